@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+)
+
+// Authorizer wraps handlers with a permission check
+type Authorizer struct {
+	audit repository.AuditRepository
+}
+
+// NewAuthorizer creates an authorizer recording its decisions in audit
+func NewAuthorizer(audit repository.AuditRepository) *Authorizer {
+	return &Authorizer{audit: audit}
+}
+
+// Require returns a handler that runs next only if the authenticated user's
+// role grants perm, and 403 otherwise. Every decision is stored in the
+// audit log before the request proceeds; if it cannot be stored the request
+// fails. It must be used behind Middleware.
+func (a *Authorizer) Require(perm Permission, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Missing API key")
+			return
+		}
+
+		entry := &models.AuditEntry{
+			UserID:     user.ID,
+			Role:       user.Role,
+			Permission: string(perm),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Allowed:    Can(user.Role, perm),
+		}
+		if err := a.audit.CreateAuditEntry(entry); err != nil {
+			logger.Error(err, "Failed to record authorization decision")
+			http.Error(w, "Failed to authorize", http.StatusInternalServerError)
+			return
+		}
+
+		if !entry.Allowed {
+			logger.LogWithFields(logger.InfoLevel, "Authorization denied", map[string]interface{}{
+				"user_id":    user.ID,
+				"role":       user.Role,
+				"permission": perm,
+				"request":    fmt.Sprintf("%s %s", r.Method, r.URL.Path),
+			})
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	})
+}
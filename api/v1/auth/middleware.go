@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"order-matching/api/v1/repository"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware authenticates requests by the API key sent as a bearer token,
+// `Authorization: Bearer <key>`, and stores the key and its owner in the
+// request context. Requests without a valid key are rejected with 401.
+func Middleware(users repository.UserRepository) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, "Missing API key")
+				return
+			}
+
+			apiKey, err := users.GetAPIKeyByHash(HashAPIKey(key))
+			if err == sql.ErrNoRows {
+				unauthorized(w, "Invalid API key")
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+				return
+			}
+
+			ctx := WithUser(r.Context(), apiKey.User)
+			ctx = context.WithValue(ctx, apiKeyKey, apiKey)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// bearerToken extracts the bearer token of the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized writes a 401 response asking for a bearer token
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="order-matching"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
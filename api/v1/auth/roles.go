@@ -0,0 +1,50 @@
+package auth
+
+import "order-matching/api/v1/models"
+
+// Permission is a class of operations a route belongs to
+type Permission string
+
+const (
+	// PermRead allows reading one's own orders and trades
+	PermRead Permission = "READ"
+
+	// PermTrade allows placing and cancelling one's own orders
+	PermTrade Permission = "TRADE"
+
+	// PermQuote allows answering requests for quote as a market maker
+	PermQuote Permission = "QUOTE"
+
+	// PermOperate allows operational actions such as instrument edits,
+	// halts, trade busts and mass cancels, and acting on any user's orders
+	PermOperate Permission = "OPERATE"
+
+	// PermAdminister allows administrative access such as reading the
+	// audit log
+	PermAdminister Permission = "ADMINISTER"
+)
+
+// rolePermissions lists the permissions granted to each role
+var rolePermissions = map[models.UserRole][]Permission{
+	models.UserRoleReadOnly:    {PermRead},
+	models.UserRoleTrader:      {PermRead, PermTrade},
+	models.UserRoleMarketMaker: {PermRead, PermTrade, PermQuote},
+	models.UserRoleOperator:    {PermRead, PermOperate},
+	models.UserRoleAdmin:       {PermRead, PermTrade, PermQuote, PermOperate, PermAdminister},
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role models.UserRole) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether role grants perm
+func Can(role models.UserRole, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
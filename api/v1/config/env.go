@@ -8,11 +8,17 @@ import (
 )
 
 type DBConfig struct {
+	Driver   string // mysql, sqlite or postgres
+	Path     string // database file for sqlite
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
+	SSLMode  string // postgres only
+
+	// AutoMigrate applies pending migrations on startup
+	AutoMigrate bool
 }
 
 var (
@@ -25,20 +31,32 @@ func LoadConfig() error {
 	// Load .env file if it exists
 	godotenv.Load()
 
+	driver := getEnv("DB_DRIVER", "mysql")
+	defaultPort := "3306"
+	if driver == "postgres" {
+		defaultPort = "5432"
+	}
+
 	Config = &DBConfig{
+		Driver:   driver,
+		Path:     getEnv("DB_PATH", "order_matching.db"),
 		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "3306"),
+		Port:     getEnv("DB_PORT", defaultPort),
 		User:     getEnv("DB_USER", "root"),
 		Password: getEnv("DB_PASSWORD", ""),
 		DBName:   getEnv("DB_NAME", "order_matching"),
+		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+		AutoMigrate: getEnv("DB_AUTO_MIGRATE", "true") != "false",
 	}
 
 	return nil
 }
 
-// GetDSN returns the MySQL data source name
+// GetDSN returns the MySQL data source name. Sessions run in UTC so that
+// CURRENT_TIMESTAMP and the times written by the server agree.
 func (c *DBConfig) GetDSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
 		c.User,
 		c.Password,
 		c.Host,
@@ -47,6 +65,23 @@ func (c *DBConfig) GetDSN() string {
 	)
 }
 
+// GetSQLiteDSN returns the SQLite data source name with foreign keys enabled
+func (c *DBConfig) GetSQLiteDSN() string {
+	return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", c.Path)
+}
+
+// GetPostgresDSN returns the PostgreSQL data source name
+func (c *DBConfig) GetPostgresDSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host,
+		c.Port,
+		c.User,
+		c.Password,
+		c.DBName,
+		c.SSLMode,
+	)
+}
+
 // getEnv gets environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
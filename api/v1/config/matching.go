@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Post-only actions, taken when a post-only order would match on arrival
+const (
+	PostOnlyReject  = "reject"
+	PostOnlyReprice = "reprice"
+)
+
+// MatchingConfig configures the matching engine
+type MatchingConfig struct {
+	// PostOnlyAction is PostOnlyReject to cancel a post-only order that
+	// would match on arrival, or PostOnlyReprice to move it one tick behind
+	// the opposite best price
+	PostOnlyAction string
+
+	// TickSize is the price increment of all stocks
+	TickSize float64
+
+	// HiddenMinQuantity is the smallest quantity of a hidden order
+	HiddenMinQuantity uint
+
+	// HiddenMatchLit lets arriving lit orders take from the dark book at
+	// the midpoint before they match the lit book
+	HiddenMatchLit bool
+}
+
+// LoadMatchingConfig loads the matching engine configuration from the
+// environment
+func LoadMatchingConfig() (*MatchingConfig, error) {
+	cfg := &MatchingConfig{
+		PostOnlyAction: getEnv("POST_ONLY_ACTION", PostOnlyReject),
+	}
+	if cfg.PostOnlyAction != PostOnlyReject && cfg.PostOnlyAction != PostOnlyReprice {
+		return nil, fmt.Errorf("invalid POST_ONLY_ACTION %q (expected reject or reprice)", cfg.PostOnlyAction)
+	}
+
+	var err error
+	if cfg.TickSize, err = strconv.ParseFloat(getEnv("TICK_SIZE", "0.01"), 64); err != nil || cfg.TickSize <= 0 {
+		return nil, fmt.Errorf("invalid TICK_SIZE %q", getEnv("TICK_SIZE", "0.01"))
+	}
+
+	minQuantity, err := strconv.ParseUint(getEnv("HIDDEN_MIN_QUANTITY", "100"), 10, 32)
+	if err != nil || minQuantity == 0 {
+		return nil, fmt.Errorf("invalid HIDDEN_MIN_QUANTITY %q", getEnv("HIDDEN_MIN_QUANTITY", "100"))
+	}
+	cfg.HiddenMinQuantity = uint(minQuantity)
+
+	if cfg.HiddenMatchLit, err = strconv.ParseBool(getEnv("HIDDEN_MATCH_LIT", "false")); err != nil {
+		return nil, fmt.Errorf("invalid HIDDEN_MATCH_LIT %q", getEnv("HIDDEN_MATCH_LIT", "false"))
+	}
+
+	return cfg, nil
+}
+
+// DefaultMatchingConfig returns the configuration used when none is loaded
+func DefaultMatchingConfig() *MatchingConfig {
+	return &MatchingConfig{PostOnlyAction: PostOnlyReject, TickSize: 0.01, HiddenMinQuantity: 100}
+}
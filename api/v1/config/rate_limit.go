@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"order-matching/api/v1/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limited request classes
+const (
+	ClassOrders  = "orders"
+	ClassCancels = "cancels"
+	ClassReads   = "reads"
+)
+
+// Limit is a token bucket refilled at Rate requests per second up to Burst
+// requests. A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig configures the per-client rate limits and the
+// message-to-trade ratio monitor
+type RateLimitConfig struct {
+	Enabled bool
+
+	// Limits holds the limit of each request class per role. It applies to
+	// every API key and, across all their keys, to every user.
+	Limits map[models.UserRole]map[string]Limit
+
+	// A user whose orders and cancels outnumber their fills by more than
+	// MaxMessageRatio to one over a window of MessageWindow, having sent at
+	// least MinMessages, is penalized for PenaltyDuration: requests cost
+	// ThrottleCost tokens when PenaltyAction is "throttle" and are rejected
+	// when it is "block"
+	MaxMessageRatio float64
+	MinMessages     int
+	MessageWindow   time.Duration
+	PenaltyAction   string
+	PenaltyDuration time.Duration
+	ThrottleCost    float64
+}
+
+// defaultLimits are the limits of each role unless overridden by
+// RATE_LIMIT_<ROLE>_<CLASS>=<rate>/<burst>
+var defaultLimits = map[models.UserRole]map[string]Limit{
+	models.UserRoleReadOnly: {
+		ClassOrders: {1, 1}, ClassCancels: {1, 1}, ClassReads: {10, 20},
+	},
+	models.UserRoleTrader: {
+		ClassOrders: {10, 20}, ClassCancels: {10, 20}, ClassReads: {20, 40},
+	},
+	models.UserRoleMarketMaker: {
+		ClassOrders: {100, 200}, ClassCancels: {100, 200}, ClassReads: {50, 100},
+	},
+	models.UserRoleOperator: {
+		ClassOrders: {10, 20}, ClassCancels: {50, 100}, ClassReads: {50, 100},
+	},
+	models.UserRoleAdmin: {
+		ClassOrders: {10, 20}, ClassCancels: {50, 100}, ClassReads: {50, 100},
+	},
+}
+
+// LoadRateLimitConfig loads the rate limit configuration from the
+// environment
+func LoadRateLimitConfig() (*RateLimitConfig, error) {
+	cfg := &RateLimitConfig{
+		Enabled:       getEnv("RATE_LIMIT_ENABLED", "true") != "false",
+		Limits:        make(map[models.UserRole]map[string]Limit),
+		PenaltyAction: getEnv("MTR_PENALTY_ACTION", "throttle"),
+	}
+
+	for role, classes := range defaultLimits {
+		cfg.Limits[role] = make(map[string]Limit)
+		for class, limit := range classes {
+			key := "RATE_LIMIT_" + string(role) + "_" + strings.ToUpper(class)
+			if v, ok := os.LookupEnv(key); ok {
+				var err error
+				if limit, err = parseLimit(v); err != nil {
+					return nil, fmt.Errorf("invalid %s: %v", key, err)
+				}
+			}
+			cfg.Limits[role][class] = limit
+		}
+	}
+
+	var err error
+	if cfg.MaxMessageRatio, err = strconv.ParseFloat(getEnv("MTR_MAX_RATIO", "100"), 64); err != nil {
+		return nil, fmt.Errorf("invalid MTR_MAX_RATIO: %v", err)
+	}
+	if cfg.MinMessages, err = strconv.Atoi(getEnv("MTR_MIN_MESSAGES", "500")); err != nil {
+		return nil, fmt.Errorf("invalid MTR_MIN_MESSAGES: %v", err)
+	}
+	if cfg.MessageWindow, err = time.ParseDuration(getEnv("MTR_WINDOW", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid MTR_WINDOW: %v", err)
+	}
+	if cfg.PenaltyDuration, err = time.ParseDuration(getEnv("MTR_PENALTY_DURATION", "5m")); err != nil {
+		return nil, fmt.Errorf("invalid MTR_PENALTY_DURATION: %v", err)
+	}
+	if cfg.ThrottleCost, err = strconv.ParseFloat(getEnv("MTR_THROTTLE_COST", "10"), 64); err != nil {
+		return nil, fmt.Errorf("invalid MTR_THROTTLE_COST: %v", err)
+	}
+	if cfg.PenaltyAction != "throttle" && cfg.PenaltyAction != "block" {
+		return nil, fmt.Errorf("invalid MTR_PENALTY_ACTION %q (expected throttle or block)", cfg.PenaltyAction)
+	}
+
+	return cfg, nil
+}
+
+// parseLimit parses a limit written as <rate>/<burst>
+func parseLimit(v string) (Limit, error) {
+	rate, burst, found := strings.Cut(v, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("expected <rate>/<burst>, got %q", v)
+	}
+	r, err := strconv.ParseFloat(rate, 64)
+	if err != nil || r < 0 {
+		return Limit{}, fmt.Errorf("invalid rate %q", rate)
+	}
+	b, err := strconv.Atoi(burst)
+	if err != nil || b < 1 {
+		return Limit{}, fmt.Errorf("invalid burst %q", burst)
+	}
+	return Limit{Rate: r, Burst: b}, nil
+}
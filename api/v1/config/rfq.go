@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// RFQConfig configures requests for quote
+type RFQConfig struct {
+	// Timeout is how long an RFQ takes quotes and may be accepted
+	Timeout time.Duration
+}
+
+// LoadRFQConfig loads the RFQ configuration from the environment
+func LoadRFQConfig() (*RFQConfig, error) {
+	cfg := &RFQConfig{}
+
+	var err error
+	if cfg.Timeout, err = time.ParseDuration(getEnv("RFQ_TIMEOUT", "30s")); err != nil || cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid RFQ_TIMEOUT %q", getEnv("RFQ_TIMEOUT", "30s"))
+	}
+
+	return cfg, nil
+}
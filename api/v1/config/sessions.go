@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SessionConfig configures cancel-on-disconnect and the dead-man's switch
+type SessionConfig struct {
+	// CancelGrace is how long a disconnected session may reconnect before
+	// its orders are cancelled
+	CancelGrace time.Duration
+
+	// MaxHeartbeatTimeout bounds the timeout of a dead-man's switch
+	MaxHeartbeatTimeout time.Duration
+}
+
+// LoadSessionConfig loads the session configuration from the environment
+func LoadSessionConfig() (*SessionConfig, error) {
+	cfg := &SessionConfig{}
+
+	var err error
+	if cfg.CancelGrace, err = time.ParseDuration(getEnv("SESSION_CANCEL_GRACE", "5s")); err != nil {
+		return nil, fmt.Errorf("invalid SESSION_CANCEL_GRACE: %v", err)
+	}
+	if cfg.MaxHeartbeatTimeout, err = time.ParseDuration(getEnv("HEARTBEAT_MAX_TIMEOUT", "10m")); err != nil {
+		return nil, fmt.Errorf("invalid HEARTBEAT_MAX_TIMEOUT: %v", err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils"
+)
+
+// Handler serves the audit log endpoints
+type Handler struct {
+	repo repository.Repository
+}
+
+// NewHandler creates an audit log handler backed by repo
+func NewHandler(repo repository.Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// GetAuditLog retrieves the latest authorization decisions matching the
+// query filters
+func (h *Handler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := utils.ParseAuditFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.repo.ListAuditEntries(filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch audit log", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
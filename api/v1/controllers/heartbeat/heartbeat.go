@@ -0,0 +1,73 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/sessions"
+	"time"
+)
+
+// HeartbeatRequest represents the request body for a heartbeat
+type HeartbeatRequest struct {
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// HeartbeatResponse tells when the dead-man's switch fires without a new
+// heartbeat
+type HeartbeatResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Handler serves the dead-man's switch endpoints
+type Handler struct {
+	sessions *sessions.Manager
+}
+
+// NewHandler creates a heartbeat handler arming switches on manager
+func NewHandler(manager *sessions.Manager) *Handler {
+	return &Handler{sessions: manager}
+}
+
+// Heartbeat arms or refreshes the authenticated user's dead-man's switch.
+// If no heartbeat follows within the timeout, all the user's open orders
+// are cancelled.
+func (h *Handler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req HeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if limit := h.sessions.MaxHeartbeatTimeout(); timeout <= 0 || timeout > limit {
+		http.Error(w, fmt.Sprintf("timeout_seconds must be between 1 and %d", int(limit.Seconds())), http.StatusBadRequest)
+		return
+	}
+
+	expiresAt := h.sessions.Heartbeat(user.ID, timeout)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HeartbeatResponse{ExpiresAt: expiresAt})
+}
+
+// Disarm removes the authenticated user's dead-man's switch
+func (h *Handler) Disarm(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !h.sessions.Disarm(user.ID) {
+		http.Error(w, "No dead-man's switch is armed", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,185 @@
+package orders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/models"
+	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/utils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxBatchOrders is the largest number of orders accepted in one batch
+const MaxBatchOrders = 100
+
+// maxBatchBytes bounds the body of a batch request
+const maxBatchBytes = 1 << 20
+
+// BatchRequest represents the request body for submitting several orders
+type BatchRequest struct {
+	Orders []OrderRequest `json:"orders"`
+}
+
+// BatchResult is the outcome of one order of a batch: the order, or the
+// reason it was rejected
+type BatchResult struct {
+	Order *models.Order `json:"order,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// BatchResponse holds the results of a batch in request order
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// CancelAllRequest selects the open orders to cancel. Empty fields match
+// any order.
+type CancelAllRequest struct {
+	StockSymbol models.StockSymbol `json:"stock_symbol"`
+	Side        models.OrderType   `json:"side"`
+}
+
+// CancelAllResponse lists the orders cancelled by a mass cancel
+type CancelAllResponse struct {
+	Cancelled []models.Order `json:"cancelled"`
+}
+
+// BatchSize returns the number of orders in a batch request without
+// consuming its body, for rate limiting. A body over maxBatchBytes is
+// truncated, so that the handler rejects it.
+func BatchSize(r *http.Request) int {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBatchBytes))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 1
+	}
+
+	var req BatchRequest
+	if json.Unmarshal(body, &req) != nil {
+		return 1
+	}
+	return min(len(req.Orders), MaxBatchOrders)
+}
+
+// CreateOrders handles the submission of a batch of orders. Valid orders
+// are processed in sequence by the matching engine; each order gets its
+// own result.
+func (h *Handler) CreateOrders(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req BatchRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Orders) == 0 || len(req.Orders) > MaxBatchOrders {
+		http.Error(w, fmt.Sprintf("A batch must hold between 1 and %d orders", MaxBatchOrders), http.StatusBadRequest)
+		return
+	}
+
+	// Validate the orders, answering re-sent ones with the original order
+	results := make([]BatchResult, len(req.Orders))
+	var orders []*models.Order
+	var indexes []int
+	clientIDs := make(map[string]bool)
+	for i := range req.Orders {
+		order := req.Orders[i].order(user.ID)
+		if err := h.validate(order); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if order.ClientOrderID != "" {
+			if clientIDs[order.ClientOrderID] {
+				results[i].Error = "duplicate client order id in batch"
+				continue
+			}
+			clientIDs[order.ClientOrderID] = true
+			if original, err := h.repo.GetOrderByClientID(user.ID, order.ClientOrderID); err == nil {
+				results[i].Order = original
+				continue
+			}
+		}
+		orders = append(orders, order)
+		indexes = append(indexes, i)
+	}
+
+	// Process the valid orders through the matching engine
+	errs := h.matcher.ProcessOrders(orders)
+	for j, order := range orders {
+		i := indexes[j]
+		if errs[j] == order_matcher.ErrPostOnlyRejected {
+			results[i].Error = errs[j].Error()
+		} else if errs[j] != nil {
+			results[i].Error = "failed to process order"
+			continue
+		}
+
+		// Reload order with stock data
+		if reloaded, err := h.repo.GetOrderByID(order.ID); err == nil {
+			order = reloaded
+		}
+		results[i].Order = order
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BatchResponse{Results: results})
+}
+
+// CancelAllOrders cancels the authenticated user's open orders matching the
+// request's symbol and side
+func (h *Handler) CancelAllOrders(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.cancelAll(w, r, user.ID)
+}
+
+// CancelUserOrders cancels the open orders of the user in the path matching
+// the request's symbol and side, for operators
+func (h *Handler) CancelUserOrders(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(mux.Vars(r)["user_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	h.cancelAll(w, r, uint(userID))
+}
+
+// cancelAll cancels the open orders of a user matching the request
+func (h *Handler) cancelAll(w http.ResponseWriter, r *http.Request, userID uint) {
+	// The body is optional: no filter cancels all the user's orders
+	var req CancelAllRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Side != "" && req.Side != models.OrderTypeBuy && req.Side != models.OrderTypeSell {
+		http.Error(w, utils.ErrInvalidOrderType.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cancelled, err := h.matcher.CancelOrders(models.OrderFilter{Symbol: req.StockSymbol, Type: req.Side, UserID: userID})
+	if err != nil {
+		http.Error(w, "Failed to cancel orders", http.StatusInternalServerError)
+		return
+	}
+	if cancelled == nil {
+		cancelled = []models.Order{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CancelAllResponse{Cancelled: cancelled})
+}
@@ -0,0 +1,146 @@
+package orders
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/utils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GroupRequest represents the request body for creating an order group:
+// the two legs of an OCO group, or the entry and exit prices of a bracket
+type GroupRequest struct {
+	Type            models.OrderGroupType `json:"type"`
+	Orders          []OrderRequest        `json:"orders"`
+	Entry           *OrderRequest         `json:"entry"`
+	TakeProfitPrice float64               `json:"take_profit_price"`
+	StopPrice       float64               `json:"stop_price"`
+}
+
+// CreateOrderGroup handles the creation of an OCO or bracket order group.
+// Its orders are created and matched together.
+func (h *Handler) CreateOrderGroup(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	requests := req.Orders
+	if req.Type == models.OrderGroupBracket {
+		requests = nil
+		if req.Entry != nil {
+			requests = []OrderRequest{*req.Entry}
+		}
+	}
+
+	// Validate the orders, then the group
+	var orders []*models.Order
+	for i := range requests {
+		order := requests[i].order(user.ID)
+		if err := h.validate(order); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if order.ClientOrderID != "" {
+			if _, err := h.repo.GetOrderByClientID(user.ID, order.ClientOrderID); err == nil {
+				http.Error(w, "Client order id already used", http.StatusConflict)
+				return
+			}
+		}
+		orders = append(orders, order)
+	}
+
+	group := &models.OrderGroup{
+		Type:            req.Type,
+		UserID:          user.ID,
+		TakeProfitPrice: req.TakeProfitPrice,
+		StopPrice:       req.StopPrice,
+	}
+	if err := utils.ValidateOrderGroup(group, orders); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	group.StockSymbol = orders[0].StockSymbol
+
+	// Process the group through the matching engine
+	if err := h.matcher.ProcessGroup(group, orders); err != nil {
+		http.Error(w, "Failed to process order group", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeGroup(w, group.ID)
+}
+
+// GetOrderGroup retrieves an order group of the authenticated user with its
+// orders
+func (h *Handler) GetOrderGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.loadOwnGroup(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// CancelOrderGroup cancels an order group of the authenticated user and
+// its open orders
+func (h *Handler) CancelOrderGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.loadOwnGroup(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.matcher.CancelGroup(group); err != nil {
+		http.Error(w, "Failed to cancel order group", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeGroup(w, group.ID)
+}
+
+// loadOwnGroup loads the order group identified by the request path, with
+// the same access rules as loadOwnOrder
+func (h *Handler) loadOwnGroup(w http.ResponseWriter, r *http.Request) (*models.OrderGroup, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid order group ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	group, err := h.repo.GetOrderGroupByID(uint(id))
+	if err == sql.ErrNoRows || (err == nil && (user == nil || (group.UserID != user.ID && !auth.Can(user.Role, auth.PermOperate)))) {
+		http.Error(w, "Order group not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch order group", http.StatusInternalServerError)
+		return nil, false
+	}
+	return group, true
+}
+
+// writeGroup responds with the current state of an order group
+func (h *Handler) writeGroup(w http.ResponseWriter, id uint) {
+	group, err := h.repo.GetOrderGroupByID(id)
+	if err != nil {
+		http.Error(w, "Failed to load order group", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
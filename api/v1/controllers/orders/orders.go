@@ -1,11 +1,15 @@
 package orders
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"order-matching/api/v1/database"
+	"order-matching/api/v1/auth"
 	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
 	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/utils"
 	"strconv"
 
 	"github.com/gorilla/mux"
@@ -18,7 +22,60 @@ type OrderRequest struct {
 	StockSymbol models.StockSymbol   `json:"stock_symbol"`
 	Quantity    uint                 `json:"quantity"`
 	Price       float64              `json:"price"`
-	UserID      uint                 `json:"user_id"`
+
+	// ClientOrderID optionally identifies the order for the client. An
+	// order re-sent with the same id returns the original order.
+	ClientOrderID string `json:"client_order_id"`
+
+	// PostOnly orders never match on arrival; see the POST_ONLY_ACTION
+	// setting for what happens when they would
+	PostOnly bool `json:"post_only"`
+
+	// PegType, PegOffset and PegCap describe the peg of PEGGED orders,
+	// whose price follows the book; Price is ignored for them
+	PegType   models.PegType `json:"peg_type"`
+	PegOffset float64        `json:"peg_offset"`
+	PegCap    float64        `json:"peg_cap"`
+
+	// StopPrice triggers a STOP order; TRAILING_STOP orders trail the last
+	// price by TrailAmount or TrailPercent instead. A triggered stop enters
+	// a limit order at Price, or a market order without one.
+	StopPrice    float64 `json:"stop_price"`
+	TrailAmount  float64 `json:"trail_amount"`
+	TrailPercent float64 `json:"trail_percent"`
+
+	// MinQuantity is the smallest quantity any execution of the order may
+	// fill; AllOrNone orders only execute for their whole open quantity
+	MinQuantity uint `json:"min_quantity"`
+	AllOrNone   bool `json:"all_or_none"`
+
+	// Hidden limit orders rest in the dark book and only trade at the
+	// midpoint of the lit book
+	Hidden bool `json:"hidden"`
+}
+
+// order returns the new order of a user described by the request
+func (req *OrderRequest) order(userID uint) *models.Order {
+	return &models.Order{
+		Type:          req.Type,
+		Category:      req.Category,
+		StockSymbol:   req.StockSymbol,
+		Quantity:      req.Quantity,
+		Price:         req.Price,
+		Status:        models.OrderStatusPending,
+		UserID:        userID,
+		ClientOrderID: req.ClientOrderID,
+		PostOnly:      req.PostOnly,
+		PegType:       req.PegType,
+		PegOffset:     req.PegOffset,
+		PegCap:        req.PegCap,
+		StopPrice:     req.StopPrice,
+		TrailAmount:   req.TrailAmount,
+		TrailPercent:  req.TrailPercent,
+		MinQuantity:   req.MinQuantity,
+		AllOrNone:     req.AllOrNone,
+		Hidden:        req.Hidden,
+	}
 }
 
 // OrderResponse represents the response for order-related endpoints
@@ -27,46 +84,128 @@ type OrderResponse struct {
 	SellOrders []models.Order `json:"sell_orders"`
 }
 
+// Handler serves the order endpoints
+type Handler struct {
+	repo    repository.Repository
+	matcher *order_matcher.OrderMatcher
+}
+
+// NewHandler creates an order handler backed by repo and matcher
+func NewHandler(repo repository.Repository, matcher *order_matcher.OrderMatcher) *Handler {
+	return &Handler{repo: repo, matcher: matcher}
+}
+
+// validate validates an order against the listed stocks and the matching
+// configuration
+func (h *Handler) validate(order *models.Order) error {
+	if err := utils.ValidateOrder(order, h.repo); err != nil {
+		return err
+	}
+	if order.Hidden && order.Quantity < h.matcher.Config().HiddenMinQuantity {
+		return utils.ErrHiddenTooSmall
+	}
+	return nil
+}
+
+// loadOwnOrder loads the order identified by the request path, responding
+// with an error unless it belongs to the authenticated user or the user may
+// operate on any order. Orders of other users are reported as not found so
+// their ids cannot be probed.
+func (h *Handler) loadOwnOrder(w http.ResponseWriter, r *http.Request) (*models.Order, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	order, err := h.repo.GetOrderByID(uint(id))
+	if err != nil || user == nil || (order.UserID != user.ID && !auth.Can(user.Role, auth.PermOperate)) {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return nil, false
+	}
+	return order, true
+}
+
+// loadClientOrder loads the authenticated user's order identified by the
+// client order id in the request path
+func (h *Handler) loadClientOrder(w http.ResponseWriter, r *http.Request) (*models.Order, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	order, err := h.repo.GetOrderByClientID(user.ID, mux.Vars(r)["client_order_id"])
+	if err == sql.ErrNoRows {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+		return nil, false
+	}
+	return order, true
+}
+
 // CreateOrder handles the creation of a new order
-func CreateOrder(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req OrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Validate stock exists
-	if _, err := models.GetStockBySymbol(database.GetDB(), req.StockSymbol); err != nil {
-		http.Error(w, "Invalid stock symbol", http.StatusBadRequest)
+	// Create order
+	order := req.order(user.ID)
+
+	// Validate order against the listed stocks
+	if err := h.validate(order); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Create order
-	order := &models.Order{
-		Type:        req.Type,
-		Category:    req.Category,
-		StockSymbol: req.StockSymbol,
-		Quantity:    req.Quantity,
-		Price:       req.Price,
-		Status:      models.OrderStatusPending,
-		UserID:      req.UserID, // TODO: Get from auth context
+	// A re-sent order returns the original instead of entering a duplicate
+	if order.ClientOrderID != "" {
+		if original, err := h.repo.GetOrderByClientID(user.ID, order.ClientOrderID); err == nil {
+			writeOrder(w, original)
+			return
+		}
 	}
 
 	// Save order to database
-	if err := models.CreateOrder(database.GetDB(), order); err != nil {
+	if err := h.repo.CreateOrder(order); err != nil {
+		// The same order may have been entered concurrently
+		if order.ClientOrderID != "" {
+			if original, err := h.repo.GetOrderByClientID(user.ID, order.ClientOrderID); err == nil {
+				writeOrder(w, original)
+				return
+			}
+		}
 		http.Error(w, "Failed to create order", http.StatusInternalServerError)
 		return
 	}
 
 	// Process order through matching engine
-	matcher := order_matcher.GetOrderMatcher()
-	if err := matcher.ProcessOrder(order); err != nil {
+	err := h.matcher.ProcessOrder(order)
+	if err == order_matcher.ErrPostOnlyRejected {
+		http.Error(w, "Post-only order would match immediately", http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to process order", http.StatusInternalServerError)
 		return
 	}
 
 	// Reload order with stock data
-	order, err := models.GetOrderByID(database.GetDB(), order.ID)
+	order, err = h.repo.GetOrderByID(order.ID)
 	if err != nil {
 		http.Error(w, "Failed to load order details", http.StatusInternalServerError)
 		return
@@ -77,27 +216,30 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetOrdersByStock retrieves all orders for a specific stock
-func GetOrdersByStock(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetOrdersByStock(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	symbol := models.StockSymbol(vars["symbol"])
 
 	// Validate stock exists
-	_, err := models.GetStockBySymbol(database.GetDB(), symbol)
+	_, err := h.repo.GetStockBySymbol(symbol)
 	if err != nil {
 		http.Error(w, "Invalid stock symbol", http.StatusBadRequest)
 		return
 	}
 
 	// Get all orders for the stock
-	orders, err := models.GetOrdersByStock(database.GetDB(), symbol)
+	orders, err := h.repo.GetOrdersByStock(symbol)
 	if err != nil {
 		http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
 		return
 	}
 
-	// Split into buy and sell orders
+	// Split into buy and sell orders, leaving out the dark book
 	var buyOrders, sellOrders []models.Order
 	for _, order := range orders {
+		if order.Hidden {
+			continue
+		}
 		if order.Type == models.OrderTypeBuy {
 			buyOrders = append(buyOrders, order)
 		} else {
@@ -114,30 +256,60 @@ func GetOrdersByStock(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetAllOrders retrieves all orders
-func GetAllOrders(w http.ResponseWriter, r *http.Request) {
-	orders, err := models.GetAllOrders(database.GetDB())
+// GetAllOrders retrieves a page of the authenticated user's orders, or of
+// any orders for operators, matching the query filters
+func (h *Handler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := utils.ParseOrderFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Users may only list their own orders unless they may operate on any
+	if !auth.Can(user.Role, auth.PermOperate) {
+		if filter.UserID != 0 && filter.UserID != user.ID {
+			http.Error(w, "Cannot list orders of other users", http.StatusForbidden)
+			return
+		}
+		filter.UserID = user.ID
+	}
+
+	page, err := h.repo.ListOrders(filter)
+	if err == models.ErrInvalidCursor {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+	json.NewEncoder(w).Encode(page)
 }
 
-// GetOrder retrieves a specific order by ID
-func GetOrder(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.ParseUint(vars["id"], 10, 32)
-	if err != nil {
-		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+// GetOrder retrieves a specific order of the authenticated user by ID
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.loadOwnOrder(w, r)
+	if !ok {
 		return
 	}
 
-	order, err := models.GetOrderByID(database.GetDB(), uint(id))
-	if err != nil {
-		http.Error(w, "Order not found", http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetOrderByClientID retrieves an order of the authenticated user by its
+// client order id
+func (h *Handler) GetOrderByClientID(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.loadClientOrder(w, r)
+	if !ok {
 		return
 	}
 
@@ -145,24 +317,34 @@ func GetOrder(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(order)
 }
 
-// CancelOrder cancels a specific order
-func CancelOrder(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.ParseUint(vars["id"], 10, 32)
-	if err != nil {
-		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+// CancelOrder cancels a specific order of the authenticated user
+func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.loadOwnOrder(w, r)
+	if !ok {
 		return
 	}
+	h.cancel(w, order)
+}
 
-	order, err := models.GetOrderByID(database.GetDB(), uint(id))
-	if err != nil {
-		http.Error(w, "Order not found", http.StatusNotFound)
+// CancelOrderByClientID cancels an order of the authenticated user
+// identified by its client order id
+func (h *Handler) CancelOrderByClientID(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.loadClientOrder(w, r)
+	if !ok {
 		return
 	}
+	h.cancel(w, order)
+}
 
+// cancel cancels an order and responds with its new state
+func (h *Handler) cancel(w http.ResponseWriter, order *models.Order) {
 	// Cancel order through matching engine
-	matcher := order_matcher.GetOrderMatcher()
-	if err := matcher.CancelOrder(order); err != nil {
+	err := h.matcher.CancelOrder(order)
+	if errors.Is(err, order_matcher.ErrOrderNotOpen) {
+		http.Error(w, "Order is no longer open", http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to cancel order", http.StatusInternalServerError)
 		return
 	}
@@ -170,3 +352,9 @@ func CancelOrder(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
+
+// writeOrder responds with an order
+func writeOrder(w http.ResponseWriter, order *models.Order) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
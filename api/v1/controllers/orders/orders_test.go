@@ -0,0 +1,175 @@
+package orders
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	order_matcher "order-matching/api/v1/services"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestRouter serves the order endpoints over an in-memory repository
+// listing COGNT
+func newTestRouter() (*mux.Router, *repository.MemoryRepository) {
+	repo := repository.NewMemory(models.Stock{Symbol: "COGNT", CurrentPrice: 100})
+	h := NewHandler(repo, order_matcher.NewOrderMatcher(repo))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/orders", h.CreateOrder).Methods("POST")
+	r.HandleFunc("/orders/{id:[0-9]+}", h.GetOrder).Methods("GET")
+	r.HandleFunc("/orders/{id:[0-9]+}/cancel", h.CancelOrder).Methods("POST")
+	r.HandleFunc("/orders/cancel-all", h.CancelAllOrders).Methods("POST")
+	r.HandleFunc("/users/{user_id:[0-9]+}/orders/cancel-all", h.CancelUserOrders).Methods("POST")
+	return r, repo
+}
+
+// serve sends a request as user and returns the recorded response
+func serve(r http.Handler, user *models.User, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if user != nil {
+		req = req.WithContext(auth.WithUser(req.Context(), user))
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+var (
+	alice = &models.User{ID: 1, Username: "alice", Role: models.UserRoleTrader}
+	bob   = &models.User{ID: 2, Username: "bob", Role: models.UserRoleTrader}
+	ops   = &models.User{ID: 3, Username: "ops", Role: models.UserRoleOperator}
+)
+
+func TestCreateOrderMatches(t *testing.T) {
+	r, repo := newTestRouter()
+
+	rec := serve(r, alice, "POST", "/orders", `{"type":"SELL","category":"LIMIT","stock_symbol":"COGNT","quantity":10,"price":100}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sell: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(r, bob, "POST", "/orders", `{"type":"BUY","category":"LIMIT","stock_symbol":"COGNT","quantity":10,"price":101}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("buy: %d %s", rec.Code, rec.Body)
+	}
+
+	var buy models.Order
+	if err := json.NewDecoder(rec.Body).Decode(&buy); err != nil {
+		t.Fatal(err)
+	}
+	if buy.UserID != bob.ID || buy.Status != models.OrderStatusMatched || buy.FilledQuantity != 10 {
+		t.Fatalf("buy = user %d %s filled %d, want bob's MATCHED order filled 10", buy.UserID, buy.Status, buy.FilledQuantity)
+	}
+
+	trades, err := repo.GetAllTrades()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].Price != 100 {
+		t.Fatalf("trades = %+v, want one at 100", trades)
+	}
+}
+
+func TestCreateOrderValidates(t *testing.T) {
+	r, _ := newTestRouter()
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"malformed", `{`, http.StatusBadRequest},
+		{"unlisted stock", `{"type":"BUY","category":"LIMIT","stock_symbol":"NOPE","quantity":10,"price":1}`, http.StatusBadRequest},
+		{"zero quantity", `{"type":"BUY","category":"LIMIT","stock_symbol":"COGNT","quantity":0,"price":1}`, http.StatusBadRequest},
+		{"limit without price", `{"type":"BUY","category":"LIMIT","stock_symbol":"COGNT","quantity":10}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(r, alice, "POST", "/orders", tt.body); rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+		})
+	}
+
+	if rec := serve(r, nil, "POST", "/orders", `{}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous status = %d, want 401", rec.Code)
+	}
+}
+
+func TestOrdersOfOtherUsersAreHidden(t *testing.T) {
+	r, repo := newTestRouter()
+
+	rec := serve(r, alice, "POST", "/orders", `{"type":"BUY","category":"LIMIT","stock_symbol":"COGNT","quantity":10,"price":99}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(r, bob, "GET", "/orders/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get as other user = %d, want 404", rec.Code)
+	}
+	if rec := serve(r, bob, "POST", "/orders/1/cancel", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("cancel as other user = %d, want 404", rec.Code)
+	}
+	if rec := serve(r, alice, "GET", "/orders/1", ""); rec.Code != http.StatusOK {
+		t.Errorf("get as owner = %d, want 200", rec.Code)
+	}
+
+	if rec := serve(r, alice, "POST", "/orders/1/cancel", ""); rec.Code != http.StatusOK {
+		t.Fatalf("cancel as owner = %d %s", rec.Code, rec.Body)
+	}
+	order, err := repo.GetOrderByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.OrderStatusCancelled {
+		t.Fatalf("status = %s, want CANCELLED", order.Status)
+	}
+
+	if rec := serve(r, alice, "POST", "/orders/1/cancel", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("second cancel = %d, want 409", rec.Code)
+	}
+}
+
+func TestCancelAllOrders(t *testing.T) {
+	r, repo := newTestRouter()
+	for _, user := range []*models.User{alice, alice, bob} {
+		rec := serve(r, user, "POST", "/orders", `{"type":"BUY","category":"LIMIT","stock_symbol":"COGNT","quantity":10,"price":99}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("create: %d %s", rec.Code, rec.Body)
+		}
+	}
+
+	// A user's mass cancel only reaches their own orders
+	rec := serve(r, bob, "POST", "/orders/cancel-all", "")
+	var resp CancelAllResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Cancelled) != 1 || resp.Cancelled[0].UserID != bob.ID {
+		t.Fatalf("bob cancelled %+v, want his one order", resp.Cancelled)
+	}
+
+	// Operators cancel the orders of another user by path
+	rec = serve(r, ops, "POST", "/users/1/orders/cancel-all", `{"stock_symbol":"COGNT","side":"BUY"}`)
+	resp = CancelAllResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Cancelled) != 2 {
+		t.Fatalf("operator cancelled %d orders, want alice's 2", len(resp.Cancelled))
+	}
+	orders, err := repo.GetAllOrders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, order := range orders {
+		if order.Status != models.OrderStatusCancelled {
+			t.Fatalf("order %d is %s, want CANCELLED", order.ID, order.Status)
+		}
+	}
+}
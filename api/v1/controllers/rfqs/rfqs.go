@@ -0,0 +1,331 @@
+package rfqs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/rfq"
+	"order-matching/api/v1/utils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// RFQRequest represents the request body for opening an RFQ
+type RFQRequest struct {
+	StockSymbol models.StockSymbol `json:"stock_symbol"`
+	Quantity    uint               `json:"quantity"`
+}
+
+// QuoteRequest represents the request body for quoting on an RFQ. A side
+// left at 0 is not quoted.
+type QuoteRequest struct {
+	BidPrice float64 `json:"bid_price"`
+	AskPrice float64 `json:"ask_price"`
+}
+
+// AcceptRequest represents the request body for accepting a quote. Type is
+// the side the requester takes.
+type AcceptRequest struct {
+	QuoteID uint             `json:"quote_id"`
+	Type    models.OrderType `json:"type"`
+}
+
+// Handler serves the RFQ endpoints
+type Handler struct {
+	repo repository.Repository
+	desk *rfq.Desk
+}
+
+// NewHandler creates an RFQ handler backed by repo and desk
+func NewHandler(repo repository.Repository, desk *rfq.Desk) *Handler {
+	return &Handler{repo: repo, desk: desk}
+}
+
+// CreateRFQ opens an RFQ of the authenticated user
+func (h *Handler) CreateRFQ(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RFQRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request := &models.RFQ{UserID: user.ID, StockSymbol: req.StockSymbol, Quantity: req.Quantity}
+	if err := utils.ValidateRFQ(request, h.repo); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.desk.Create(request); err != nil {
+		http.Error(w, "Failed to create RFQ", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRFQ(w, request.ID)
+}
+
+// GetRFQs retrieves the RFQs of the authenticated user, newest first
+func (h *Handler) GetRFQs(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rfqs, err := h.repo.GetRFQsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch RFQs", http.StatusInternalServerError)
+		return
+	}
+	writeRFQs(w, rfqs)
+}
+
+// GetOpenRFQs retrieves the open RFQs of other users for market makers to
+// quote on, oldest first
+func (h *Handler) GetOpenRFQs(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	open, err := h.repo.GetOpenRFQs()
+	if err != nil {
+		http.Error(w, "Failed to fetch RFQs", http.StatusInternalServerError)
+		return
+	}
+
+	var rfqs []models.RFQ
+	for _, rfq := range open {
+		if rfq.UserID != user.ID {
+			rfqs = append(rfqs, rfq)
+		}
+	}
+	writeRFQs(w, rfqs)
+}
+
+// GetRFQ retrieves an RFQ. The requester and operators see every quote,
+// market makers only their own.
+func (h *Handler) GetRFQ(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	request, ok := h.loadRFQ(w, r)
+	if !ok {
+		return
+	}
+
+	switch {
+	case request.UserID == user.ID || auth.Can(user.Role, auth.PermOperate):
+		// Every quote is visible
+	case auth.Can(user.Role, auth.PermQuote):
+		var own []models.RFQQuote
+		for _, q := range request.Quotes {
+			if q.UserID == user.ID {
+				own = append(own, q)
+			}
+		}
+		request.Quotes = own
+	default:
+		http.Error(w, "RFQ not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// QuoteRFQ adds the firm quote of the authenticated market maker to an RFQ
+func (h *Handler) QuoteRFQ(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	request, ok := h.loadRFQ(w, r)
+	if !ok {
+		return
+	}
+
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	quote := &models.RFQQuote{UserID: user.ID, BidPrice: req.BidPrice, AskPrice: req.AskPrice}
+	if err := utils.ValidateRFQQuote(quote); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch err := h.desk.Quote(request.ID, quote); err {
+	case nil:
+	case rfq.ErrNotOpen, rfq.ErrAlreadyQuoted, rfq.ErrOwnRFQ:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Failed to quote RFQ", http.StatusInternalServerError)
+		return
+	}
+
+	// Reload the quote for its timestamps
+	request, err := h.repo.GetRFQByID(request.ID)
+	if err != nil {
+		http.Error(w, "Failed to load quote", http.StatusInternalServerError)
+		return
+	}
+	for i := range request.Quotes {
+		if request.Quotes[i].ID == quote.ID {
+			quote = &request.Quotes[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+// AcceptRFQ accepts a quote on an RFQ of the authenticated user, executing
+// the negotiated trade
+func (h *Handler) AcceptRFQ(w http.ResponseWriter, r *http.Request) {
+	request, ok := h.loadOwnRFQ(w, r)
+	if !ok {
+		return
+	}
+
+	var req AcceptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Type != models.OrderTypeBuy && req.Type != models.OrderTypeSell {
+		http.Error(w, utils.ErrInvalidOrderType.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch err := h.desk.Accept(request.ID, req.QuoteID, req.Type); err {
+	case nil:
+	case rfq.ErrQuoteNotFound, rfq.ErrSideNotQuoted:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case rfq.ErrNotOpen:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Failed to accept quote", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRFQ(w, request.ID)
+}
+
+// CancelRFQ cancels an open RFQ of the authenticated user
+func (h *Handler) CancelRFQ(w http.ResponseWriter, r *http.Request) {
+	request, ok := h.loadOwnRFQ(w, r)
+	if !ok {
+		return
+	}
+
+	switch err := h.desk.Cancel(request.ID); err {
+	case nil:
+	case rfq.ErrNotOpen:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Failed to cancel RFQ", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRFQ(w, request.ID)
+}
+
+// GetRFQHistory retrieves the history of an RFQ of the authenticated user,
+// oldest first
+func (h *Handler) GetRFQHistory(w http.ResponseWriter, r *http.Request) {
+	request, ok := h.loadOwnRFQ(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := h.repo.GetRFQEvents(request.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch RFQ history", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []models.RFQEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// loadRFQ loads the RFQ identified by the request path
+func (h *Handler) loadRFQ(w http.ResponseWriter, r *http.Request) (*models.RFQ, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid RFQ ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	request, err := h.repo.GetRFQByID(uint(id))
+	if err == sql.ErrNoRows {
+		http.Error(w, "RFQ not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch RFQ", http.StatusInternalServerError)
+		return nil, false
+	}
+	return request, true
+}
+
+// loadOwnRFQ loads the RFQ identified by the request path, responding with
+// an error unless it belongs to the authenticated user or the user may
+// operate on any order
+func (h *Handler) loadOwnRFQ(w http.ResponseWriter, r *http.Request) (*models.RFQ, bool) {
+	request, ok := h.loadRFQ(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if user == nil || (request.UserID != user.ID && !auth.Can(user.Role, auth.PermOperate)) {
+		http.Error(w, "RFQ not found", http.StatusNotFound)
+		return nil, false
+	}
+	return request, true
+}
+
+// writeRFQ responds with the current state of an RFQ
+func (h *Handler) writeRFQ(w http.ResponseWriter, id uint) {
+	request, err := h.repo.GetRFQByID(id)
+	if err != nil {
+		http.Error(w, "Failed to load RFQ", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// writeRFQs responds with a list of RFQs
+func writeRFQs(w http.ResponseWriter, rfqs []models.RFQ) {
+	if rfqs == nil {
+		rfqs = []models.RFQ{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rfqs)
+}
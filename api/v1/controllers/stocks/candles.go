@@ -0,0 +1,40 @@
+package stocks
+
+import (
+	"encoding/json"
+	"net/http"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// GetCandles retrieves the OHLCV candles of a stock for the requested
+// interval and time range
+func (h *Handler) GetCandles(w http.ResponseWriter, r *http.Request) {
+	symbol := models.StockSymbol(mux.Vars(r)["symbol"])
+
+	filter, err := utils.ParseCandleFilter(symbol, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Validate stock exists
+	if _, err := h.repo.GetStockBySymbol(symbol); err != nil {
+		http.Error(w, "Stock not found", http.StatusNotFound)
+		return
+	}
+
+	candles, err := h.repo.ListCandles(filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch candles", http.StatusInternalServerError)
+		return
+	}
+	if candles == nil {
+		candles = []models.Candle{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candles)
+}
@@ -0,0 +1,221 @@
+package stocks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// StockRequest represents the request body for creating or updating a stock
+type StockRequest struct {
+	Symbol       models.StockSymbol `json:"symbol"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	CurrentPrice float64            `json:"current_price"`
+	DayHigh      float64            `json:"day_high"`
+	DayLow       float64            `json:"day_low"`
+	Volume       int64              `json:"volume"`
+	MarketCap    float64            `json:"market_cap"`
+	Sector       string             `json:"sector"`
+
+	// Matching rules, left unchanged when omitted
+	Allocation    models.AllocationAlgorithm `json:"allocation"`
+	MinAllocation *uint                      `json:"min_allocation"`
+}
+
+// Handler serves the stock endpoints
+type Handler struct {
+	repo    repository.Repository
+	matcher *order_matcher.OrderMatcher
+}
+
+// NewHandler creates a stock handler backed by repo and matcher
+func NewHandler(repo repository.Repository, matcher *order_matcher.OrderMatcher) *Handler {
+	return &Handler{repo: repo, matcher: matcher}
+}
+
+// apply copies the request fields onto stock, leaving symbol and status alone
+func (req *StockRequest) apply(stock *models.Stock) {
+	stock.Name = req.Name
+	stock.Description = req.Description
+	stock.CurrentPrice = req.CurrentPrice
+	stock.DayHigh = req.DayHigh
+	stock.DayLow = req.DayLow
+	stock.Volume = req.Volume
+	stock.MarketCap = req.MarketCap
+	stock.Sector = req.Sector
+	if req.Allocation != "" {
+		stock.Allocation = req.Allocation
+	}
+	if req.MinAllocation != nil {
+		stock.MinAllocation = *req.MinAllocation
+	}
+}
+
+// GetAllStocks retrieves all stocks, including delisted ones
+func (h *Handler) GetAllStocks(w http.ResponseWriter, r *http.Request) {
+	stocks, err := h.repo.GetAllStocks()
+	if err != nil {
+		http.Error(w, "Failed to fetch stocks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocks)
+}
+
+// GetStock retrieves a specific stock by symbol
+func (h *Handler) GetStock(w http.ResponseWriter, r *http.Request) {
+	symbol := models.StockSymbol(mux.Vars(r)["symbol"])
+
+	stock, err := h.repo.GetStockBySymbol(symbol)
+	if err != nil {
+		http.Error(w, "Stock not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stock)
+}
+
+// CreateStock lists a new stock
+func (h *Handler) CreateStock(w http.ResponseWriter, r *http.Request) {
+	var req StockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	stock := &models.Stock{
+		Symbol:     req.Symbol,
+		Status:     models.StockStatusActive,
+		Allocation: models.AllocationFIFO,
+	}
+	req.apply(stock)
+	if err := utils.ValidateStock(stock); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Reject duplicates, including delisted symbols
+	_, err := h.repo.GetStockBySymbol(stock.Symbol)
+	if err == nil {
+		http.Error(w, "Stock already exists", http.StatusConflict)
+		return
+	}
+	if err != sql.ErrNoRows {
+		http.Error(w, "Failed to create stock", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.repo.CreateStock(stock); err != nil {
+		http.Error(w, "Failed to create stock", http.StatusInternalServerError)
+		return
+	}
+
+	// Reload stock with its update time
+	stock, err = h.repo.GetStockBySymbol(stock.Symbol)
+	if err != nil {
+		http.Error(w, "Failed to load stock details", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stock)
+}
+
+// UpdateStock replaces the reference data of a stock. The symbol is taken
+// from the path and the listing status is left unchanged.
+func (h *Handler) UpdateStock(w http.ResponseWriter, r *http.Request) {
+	symbol := models.StockSymbol(mux.Vars(r)["symbol"])
+
+	var req StockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Symbol != "" && req.Symbol != symbol {
+		http.Error(w, "Symbol cannot be changed", http.StatusBadRequest)
+		return
+	}
+
+	stock, err := h.repo.GetStockBySymbol(symbol)
+	if err != nil {
+		http.Error(w, "Stock not found", http.StatusNotFound)
+		return
+	}
+
+	req.apply(stock)
+	if err := utils.ValidateStock(stock); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.UpdateStock(stock); err != nil {
+		http.Error(w, "Failed to update stock", http.StatusInternalServerError)
+		return
+	}
+
+	// Reload stock with its update time
+	stock, err = h.repo.GetStockBySymbol(symbol)
+	if err != nil {
+		http.Error(w, "Failed to load stock details", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stock)
+}
+
+// DelistStock stops trading in a stock and cancels its open orders. The
+// stock is kept so that its orders and trades stay queryable.
+func (h *Handler) DelistStock(w http.ResponseWriter, r *http.Request) {
+	symbol := models.StockSymbol(mux.Vars(r)["symbol"])
+
+	stock, err := h.repo.GetStockBySymbol(symbol)
+	if err != nil {
+		http.Error(w, "Stock not found", http.StatusNotFound)
+		return
+	}
+	if stock.Status == models.StockStatusDelisted {
+		http.Error(w, "Stock is already delisted", http.StatusConflict)
+		return
+	}
+
+	// Delist first so that no new orders are accepted while the open ones
+	// are cancelled
+	stock.Status = models.StockStatusDelisted
+	if err := h.repo.UpdateStock(stock); err != nil {
+		http.Error(w, "Failed to delist stock", http.StatusInternalServerError)
+		return
+	}
+
+	orders, err := h.repo.GetOrdersByStock(symbol)
+	if err != nil {
+		http.Error(w, "Failed to fetch open orders", http.StatusInternalServerError)
+		return
+	}
+	for i := range orders {
+		order := &orders[i]
+		if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusPartiallyFilled {
+			continue
+		}
+		// Orders filled or cancelled meanwhile are skipped
+		err := h.matcher.CancelOrder(order)
+		if err != nil && !errors.Is(err, order_matcher.ErrOrderNotOpen) {
+			http.Error(w, "Failed to cancel open orders", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stock)
+}
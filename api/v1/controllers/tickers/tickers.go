@@ -0,0 +1,59 @@
+package tickers
+
+import (
+	"encoding/json"
+	"net/http"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	order_matcher "order-matching/api/v1/services"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Handler serves the ticker endpoints
+type Handler struct {
+	repo    repository.Repository
+	tickers *order_matcher.Tickers
+}
+
+// NewHandler creates a ticker handler serving tickers kept by the matcher.
+// repo is only used to look up the listed stocks.
+func NewHandler(repo repository.Repository, matcher *order_matcher.OrderMatcher) *Handler {
+	return &Handler{repo: repo, tickers: matcher.Tickers()}
+}
+
+// GetAllTickers retrieves the tickers of all active stocks
+func (h *Handler) GetAllTickers(w http.ResponseWriter, r *http.Request) {
+	stocks, err := h.repo.GetAllStocks()
+	if err != nil {
+		http.Error(w, "Failed to fetch stocks", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	tickers := make([]models.Ticker, 0, len(stocks))
+	for _, stock := range stocks {
+		if stock.Status != models.StockStatusActive {
+			continue
+		}
+		tickers = append(tickers, h.tickers.Get(stock.Symbol, now))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tickers)
+}
+
+// GetTicker retrieves the ticker of a specific stock
+func (h *Handler) GetTicker(w http.ResponseWriter, r *http.Request) {
+	symbol := models.StockSymbol(mux.Vars(r)["symbol"])
+
+	// Validate stock exists
+	if _, err := h.repo.GetStockBySymbol(symbol); err != nil {
+		http.Error(w, "Stock not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.tickers.Get(symbol, time.Now()))
+}
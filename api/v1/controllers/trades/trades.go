@@ -1,30 +1,63 @@
 package trades
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
-	"order-matching/api/v1/database"
+	"order-matching/api/v1/auth"
 	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/utils"
 	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
-// GetAllTrades retrieves all trades
-func GetAllTrades(w http.ResponseWriter, r *http.Request) {
+// CorrectionRequest represents the request body for busting or correcting a
+// trade. Price and quantity are only used by corrections.
+type CorrectionRequest struct {
+	Price    float64 `json:"price"`
+	Quantity uint    `json:"quantity"`
+	Reason   string  `json:"reason"`
+}
+
+// Handler serves the trade endpoints
+type Handler struct {
+	repo    repository.Repository
+	matcher *order_matcher.OrderMatcher
+}
+
+// NewHandler creates a trade handler backed by repo and matcher
+func NewHandler(repo repository.Repository, matcher *order_matcher.OrderMatcher) *Handler {
+	return &Handler{repo: repo, matcher: matcher}
+}
+
+// GetAllTrades retrieves a page of trades matching the query filters
+func (h *Handler) GetAllTrades(w http.ResponseWriter, r *http.Request) {
+	filter, err := utils.ParseTradeFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get trades from database
-	trades, err := models.GetAllTrades(database.GetDB())
+	page, err := h.repo.ListTrades(filter)
+	if err == models.ErrInvalidCursor {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch trades", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(trades)
+	json.NewEncoder(w).Encode(page)
 }
 
 // GetTradeByID retrieves a specific trade by ID
-func GetTradeByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetTradeByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
@@ -32,7 +65,7 @@ func GetTradeByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	trade, err := models.GetTradeByID(database.GetDB(), uint(id))
+	trade, err := h.repo.GetTradeByID(uint(id))
 	if err != nil {
 		http.Error(w, "Trade not found", http.StatusNotFound)
 		return
@@ -41,3 +74,96 @@ func GetTradeByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(trade)
 }
+
+// BustTrade cancels a trade, restoring the fills of its orders
+func (h *Handler) BustTrade(w http.ResponseWriter, r *http.Request) {
+	h.correctTrade(w, r, models.TradeBust)
+}
+
+// CorrectTrade replaces a trade with one at a corrected price and quantity
+func (h *Handler) CorrectTrade(w http.ResponseWriter, r *http.Request) {
+	h.correctTrade(w, r, models.TradeCorrect)
+}
+
+// GetTradeCorrection retrieves the bust or correction of a trade
+func (h *Handler) GetTradeCorrection(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+
+	correction, err := h.repo.GetTradeCorrection(uint(id))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Trade correction not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch trade correction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(correction)
+}
+
+// correctTrade busts or corrects the trade identified by the request path on
+// behalf of the authenticated operator
+func (h *Handler) correctTrade(w http.ResponseWriter, r *http.Request, correctionType models.TradeCorrectionType) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+	trade, err := h.repo.GetTradeByID(uint(id))
+	if err != nil {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
+
+	var req CorrectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	correction := &models.TradeCorrection{
+		TradeID: trade.ID,
+		Type:    correctionType,
+		Reason:  req.Reason,
+		UserID:  user.ID,
+	}
+	if correctionType == models.TradeCorrect {
+		correction.Price = req.Price
+		correction.Quantity = req.Quantity
+	}
+	if err := utils.ValidateTradeCorrection(correction, trade); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch err := h.matcher.CorrectTrade(correction); err {
+	case nil:
+	case order_matcher.ErrTradeCorrected, order_matcher.ErrCorrectionOverfills:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Failed to correct trade", http.StatusInternalServerError)
+		return
+	}
+
+	// Reload the correction for its timestamp
+	if correction, err = h.repo.GetTradeCorrection(trade.ID); err != nil {
+		http.Error(w, "Failed to load trade correction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(correction)
+}
@@ -0,0 +1,78 @@
+package trades
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	order_matcher "order-matching/api/v1/services"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestRouter serves the trade endpoints over an in-memory repository in
+// which a sell of 10 COGNT at 100 was matched by a buy
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	repo := repository.NewMemory(models.Stock{Symbol: "COGNT", CurrentPrice: 100})
+	matcher := order_matcher.NewOrderMatcher(repo)
+	orders := []*models.Order{
+		{Type: models.OrderTypeSell, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 10, Price: 100, Status: models.OrderStatusPending, UserID: 1},
+		{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 10, Price: 100, Status: models.OrderStatusPending, UserID: 2},
+	}
+	for _, err := range matcher.ProcessOrders(orders) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewHandler(repo, matcher)
+	r := mux.NewRouter()
+	r.HandleFunc("/trades", h.GetAllTrades).Methods("GET")
+	r.HandleFunc("/trades/{id:[0-9]+}", h.GetTradeByID).Methods("GET")
+	return r
+}
+
+func TestGetAllTrades(t *testing.T) {
+	r := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/trades?symbol=COGNT", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var page models.TradePage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Trades) != 1 {
+		t.Fatalf("trades = %d, want 1", len(page.Trades))
+	}
+	trade := page.Trades[0]
+	if trade.Quantity != 10 || trade.Price != 100 || trade.BuyOrder == nil || trade.BuyOrder.UserID != 2 {
+		t.Fatalf("trade = %+v, want 10 @ 100 bought by user 2", trade)
+	}
+}
+
+func TestGetTradeByID(t *testing.T) {
+	r := newTestRouter(t)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/trades/1", http.StatusOK},
+		{"/trades/2", http.StatusNotFound},
+		{"/trades/99999999999", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.code)
+		}
+	}
+}
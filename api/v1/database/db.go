@@ -4,30 +4,59 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"order-matching/api/v1/config"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// Supported database drivers, selected with DB_DRIVER
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
 )
 
 var DB *sql.DB
 
-// Initialize sets up the database connection
+// driver is the driver DB was opened with
+var driver string
+
+// Initialize sets up the database connection and, unless DB_AUTO_MIGRATE
+// is false, applies pending schema migrations
 func Initialize() error {
-	err := godotenv.Load()
-	if err != nil {
-		log.Printf("Error loading .env file: %v", err)
+	if err := Connect(); err != nil {
+		return err
+	}
+
+	if config.Config.AutoMigrate {
+		if err := Migrate(); err != nil {
+			return err
+		}
 	}
 
-	// Database connection
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_NAME"))
+	return nil
+}
+
+// Connect opens and verifies the database connection without migrating
+func Connect() error {
+	if err := config.LoadConfig(); err != nil {
+		return fmt.Errorf("error loading config: %v", err)
+	}
 
-	DB, err = sql.Open("mysql", dsn)
+	var err error
+	driver = config.Config.Driver
+	switch driver {
+	case DriverMySQL:
+		DB, err = sql.Open("mysql", config.Config.GetDSN())
+	case DriverSQLite:
+		DB, err = sql.Open("sqlite", config.Config.GetSQLiteDSN())
+	case DriverPostgres:
+		DB, err = sql.Open("postgres", config.Config.GetPostgresDSN())
+	default:
+		return fmt.Errorf("unsupported database driver %q", driver)
+	}
 	if err != nil {
 		return fmt.Errorf("error connecting to database: %v", err)
 	}
@@ -38,6 +67,29 @@ func Initialize() error {
 		return fmt.Errorf("error pinging database: %v", err)
 	}
 
+	// SQLite allows a single writer; sharing one connection avoids
+	// SQLITE_BUSY between the matcher's transactions and other queries
+	if driver == DriverSQLite {
+		DB.SetMaxOpenConns(1)
+	}
+
+	return nil
+}
+
+// Migrate applies all pending schema migrations
+func Migrate() error {
+	migrator, err := NewMigrator(DB, driver)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		return fmt.Errorf("error migrating database: %v", err)
+	}
+	if applied > 0 {
+		log.Printf("Applied %d database migration(s)", applied)
+	}
 	return nil
 }
 
@@ -46,6 +98,11 @@ func GetDB() *sql.DB {
 	return DB
 }
 
+// GetDriver returns the driver the database connection was opened with
+func GetDriver() string {
+	return driver
+}
+
 // Close closes the database connection
 func Close() {
 	if DB != nil {
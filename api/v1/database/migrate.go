@@ -0,0 +1,415 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"order-matching/api/v1/models"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations live in migrations/<driver>/NNNN_name.up.sql with an optional
+// matching NNNN_name.down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+const (
+	// migrationLockName identifies the MySQL named lock held while migrating
+	migrationLockName = "order_matching_migrations"
+
+	// migrationLockKey identifies the PostgreSQL advisory lock held while migrating
+	migrationLockKey = 724513907
+
+	// migrationLockTimeout is how long to wait for another migration run, in seconds
+	migrationLockTimeout = 60
+)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator applies the embedded migrations of a driver to a database
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the given connection and driver
+func NewMigrator(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// loadMigrations reads the embedded migrations of a driver sorted by version
+func loadMigrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q: %v", driver, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		// Parse NNNN_name.<direction>.sql
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %v", file, err)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	if err := m.verify(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.apply(migration, migration.Up, true); err != nil {
+			return count, fmt.Errorf("migration %d_%s failed: %v", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down rolls back the most recent steps applied migrations and returns how
+// many were rolled back
+func (m *Migrator) Down(steps int) (int, error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	if err := m.verify(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %d_%s cannot be rolled back", migration.Version, migration.Name)
+		}
+		if err := m.apply(migration, migration.Down, false); err != nil {
+			return count, fmt.Errorf("rollback of %d_%s failed: %v", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if a, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = a.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// verify checks that applied migrations have not been edited since they
+// ran and that the database is not ahead of this build
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, a := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("database has migration %d_%s which is unknown to this build", version, a.Name)
+		}
+		if migration.Checksum != a.Checksum {
+			return fmt.Errorf("migration %d_%s has been modified since it was applied", version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// apply runs a migration script and records the result in schema_migrations.
+// MySQL commits DDL implicitly, so a failed MySQL migration may be partially
+// applied; SQLite and PostgreSQL roll back completely.
+func (m *Migrator) apply(migration Migration, script string, up bool) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.statements(script) {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	db := m.bind(tx)
+	if up {
+		_, err = db.Exec(`
+			INSERT INTO schema_migrations (version, name, checksum, applied_at)
+			VALUES (?, ?, ?, CURRENT_TIMESTAMP)`,
+			migration.Version, migration.Name, migration.Checksum)
+	} else {
+		_, err = db.Exec(`DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// statements splits a script into the statements to execute. The MySQL
+// driver runs one statement per call, so its scripts are split on lines
+// ending with a semicolon; the other drivers accept the whole script.
+func (m *Migrator) statements(script string) []string {
+	if m.driver != DriverMySQL {
+		return []string{script}
+	}
+
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, current.String())
+			current.Reset()
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		stmts = append(stmts, current.String())
+	}
+	return stmts
+}
+
+// bind adapts db to the placeholder style of the driver
+func (m *Migrator) bind(db models.DBTX) models.DBTX {
+	if m.driver == DriverPostgres {
+		return models.PostgresDB{DB: db}
+	}
+	return db
+}
+
+// ensureTable creates the schema_migrations table if needed
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// applied returns the applied migrations keyed by version
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	rows, err := m.db.Query(`
+		SELECT version, name, checksum, applied_at
+		FROM schema_migrations
+		ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.Version] = a
+	}
+	return applied, rows.Err()
+}
+
+// lock prevents concurrent migration runs. MySQL and PostgreSQL use
+// session-level advisory locks that are released automatically if the
+// process dies; SQLite uses a lock row that must be removed by hand if a
+// run is interrupted.
+func (m *Migrator) lock() (func(), error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	switch m.driver {
+	case DriverMySQL, DriverPostgres:
+		ctx := context.Background()
+		conn, err := m.db.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %v", err)
+		}
+
+		if m.driver == DriverMySQL {
+			var acquired sql.NullInt64
+			err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`,
+				migrationLockName, migrationLockTimeout).Scan(&acquired)
+			if err == nil && acquired.Int64 != 1 {
+				err = fmt.Errorf("timed out after %ds", migrationLockTimeout)
+			}
+		} else {
+			_, err = conn.ExecContext(ctx, fmt.Sprintf(`SET lock_timeout = '%ds'`, migrationLockTimeout))
+			if err == nil {
+				_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey)
+			}
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to acquire migration lock: %v", err)
+		}
+
+		return func() {
+			if m.driver == DriverMySQL {
+				conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, migrationLockName)
+			} else {
+				conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+				conn.ExecContext(ctx, `RESET lock_timeout`)
+			}
+			conn.Close()
+		}, nil
+
+	default:
+		_, err := m.db.Exec(`
+			CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+				id INTEGER PRIMARY KEY,
+				locked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create schema_migrations_lock: %v", err)
+		}
+		if _, err := m.db.Exec(`INSERT INTO schema_migrations_lock (id) VALUES (1)`); err != nil {
+			return nil, fmt.Errorf("migrations are locked by another run; delete the row from schema_migrations_lock if none is in progress: %v", err)
+		}
+
+		return func() {
+			m.db.Exec(`DELETE FROM schema_migrations_lock WHERE id = 1`)
+		}, nil
+	}
+}
@@ -0,0 +1,54 @@
+-- Create stocks table
+CREATE TABLE IF NOT EXISTS stocks (
+    symbol VARCHAR(10) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(500),
+    current_price DECIMAL(10,2) NOT NULL,
+    day_high DECIMAL(10,2) NOT NULL,
+    day_low DECIMAL(10,2) NOT NULL,
+    volume BIGINT NOT NULL,
+    market_cap DECIMAL(15,2) NOT NULL,
+    sector VARCHAR(50) NOT NULL,
+    last_updated TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_sector (sector),
+    INDEX idx_price (current_price)
+);
+
+-- Create orders table
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    type ENUM('BUY', 'SELL') NOT NULL,
+    category ENUM('LIMIT', 'MARKET') NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    quantity INT UNSIGNED NOT NULL,
+    filled_quantity INT UNSIGNED DEFAULT 0,
+    price DECIMAL(10,2) NOT NULL,
+    status ENUM('PENDING', 'PARTIALLY_FILLED', 'MATCHED', 'CANCELLED') DEFAULT 'PENDING',
+    user_id BIGINT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol),
+    INDEX idx_type_status (type, status),
+    INDEX idx_stock_status (stock_symbol, status),
+    INDEX idx_user (user_id)
+);
+
+-- Create trades table
+CREATE TABLE IF NOT EXISTS trades (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    buy_order_id BIGINT UNSIGNED NOT NULL,
+    sell_order_id BIGINT UNSIGNED NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    quantity INT UNSIGNED NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    executed_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (buy_order_id) REFERENCES orders(id),
+    FOREIGN KEY (sell_order_id) REFERENCES orders(id),
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol),
+    INDEX idx_stock_time (stock_symbol, executed_at),
+    INDEX idx_orders (buy_order_id, sell_order_id)
+);
@@ -0,0 +1,12 @@
+-- Insert initial stock data
+INSERT IGNORE INTO stocks (symbol, name, description, current_price, day_high, day_low, volume, market_cap, sector, last_updated) VALUES
+('NXTECH', 'Nexus Technologies', 'Advanced technology solutions provider', 150.00, 155.00, 145.00, 1000000, 15000000000.00, 'Technology', NOW()),
+('QNTUM', 'Quantum Dynamics', 'Quantum computing research and development', 200.00, 210.00, 195.00, 800000, 20000000000.00, 'Technology', NOW()),
+('CYBEX', 'Cyber Matrix Systems', 'Cybersecurity solutions provider', 175.00, 180.00, 170.00, 1200000, 17500000000.00, 'Technology', NOW()),
+('SOLRX', 'Solar Matrix Energy', 'Renewable energy solutions', 125.00, 130.00, 120.00, 1500000, 12500000000.00, 'Energy', NOW()),
+('FUSON', 'Fusion Power Corp', 'Nuclear fusion research and development', 300.00, 310.00, 290.00, 600000, 30000000000.00, 'Energy', NOW()),
+('GENUM', 'Genome Solutions', 'Genetic research and biotechnology', 250.00, 260.00, 240.00, 700000, 25000000000.00, 'Healthcare', NOW()),
+('MEDIX', 'Medical Innovations X', 'Medical device manufacturer', 180.00, 185.00, 175.00, 900000, 18000000000.00, 'Healthcare', NOW()),
+('AITHN', 'AI Think Networks', 'Artificial intelligence solutions', 220.00, 225.00, 215.00, 1100000, 22000000000.00, 'Technology', NOW()),
+('NRLNK', 'Neural Link Systems', 'Brain-computer interface technology', 275.00, 280.00, 270.00, 500000, 27500000000.00, 'Technology', NOW()),
+('COGNT', 'Cognitive Tech Labs', 'Cognitive computing solutions', 190.00, 195.00, 185.00, 1000000, 19000000000.00, 'Technology', NOW());
@@ -0,0 +1,9 @@
+DROP INDEX idx_orders_user_id ON orders;
+DROP INDEX idx_orders_stock_id ON orders;
+DROP INDEX idx_orders_stock_price ON orders;
+DROP INDEX idx_orders_status_id ON orders;
+DROP INDEX idx_orders_price ON orders;
+DROP INDEX idx_orders_created ON orders;
+DROP INDEX idx_trades_stock_id ON trades;
+DROP INDEX idx_trades_price ON trades;
+DROP INDEX idx_trades_executed ON trades;
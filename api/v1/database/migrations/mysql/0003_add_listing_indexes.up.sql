@@ -0,0 +1,10 @@
+-- Indexes backing the filtered, keyset-paginated order and trade listings
+CREATE INDEX idx_orders_user_id ON orders (user_id, id);
+CREATE INDEX idx_orders_stock_id ON orders (stock_symbol, id);
+CREATE INDEX idx_orders_stock_price ON orders (stock_symbol, price, id);
+CREATE INDEX idx_orders_status_id ON orders (status, id);
+CREATE INDEX idx_orders_price ON orders (price, id);
+CREATE INDEX idx_orders_created ON orders (created_at);
+CREATE INDEX idx_trades_stock_id ON trades (stock_symbol, id);
+CREATE INDEX idx_trades_price ON trades (price, id);
+CREATE INDEX idx_trades_executed ON trades (executed_at);
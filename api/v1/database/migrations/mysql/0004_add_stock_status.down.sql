@@ -0,0 +1,2 @@
+DROP INDEX idx_stocks_status ON stocks;
+ALTER TABLE stocks DROP COLUMN status;
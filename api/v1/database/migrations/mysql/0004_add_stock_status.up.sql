@@ -0,0 +1,3 @@
+-- Stocks are delisted rather than deleted so their trade history is kept
+ALTER TABLE stocks ADD COLUMN status ENUM('ACTIVE', 'DELISTED') NOT NULL DEFAULT 'ACTIVE' AFTER sector;
+CREATE INDEX idx_stocks_status ON stocks (status);
@@ -0,0 +1,5 @@
+ALTER TABLE stocks
+    DROP COLUMN session_date,
+    DROP COLUMN trade_count,
+    DROP COLUMN vwap,
+    DROP COLUMN previous_close;
@@ -0,0 +1,8 @@
+-- Trading statistics maintained by the matching engine. session_date is the
+-- trading day the intraday fields belong to; the default makes every stock
+-- roll into a fresh session on its first trade.
+ALTER TABLE stocks
+    ADD COLUMN previous_close DECIMAL(10,2) NOT NULL DEFAULT 0 AFTER current_price,
+    ADD COLUMN vwap DECIMAL(14,4) NOT NULL DEFAULT 0 AFTER volume,
+    ADD COLUMN trade_count BIGINT NOT NULL DEFAULT 0 AFTER vwap,
+    ADD COLUMN session_date DATE NOT NULL DEFAULT '1970-01-01' AFTER status;
@@ -0,0 +1,15 @@
+-- Every user has one role, which grants the permissions checked on each
+-- route; every check is recorded in audit_log
+ALTER TABLE users ADD COLUMN role ENUM('TRADER', 'READ_ONLY', 'MARKET_MAKER', 'OPERATOR', 'ADMIN') NOT NULL DEFAULT 'TRADER' AFTER username;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    permission VARCHAR(20) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_audit_log_user (user_id)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS idempotency_keys;
+DROP INDEX idx_orders_client_order_id ON orders;
+ALTER TABLE orders DROP COLUMN client_order_id;
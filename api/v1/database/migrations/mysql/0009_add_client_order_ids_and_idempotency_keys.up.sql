@@ -0,0 +1,19 @@
+-- Clients may tag orders with their own id, unique per user, so that a
+-- re-sent order is detected instead of entered twice
+ALTER TABLE orders ADD COLUMN client_order_id VARCHAR(64) NULL AFTER user_id;
+CREATE UNIQUE INDEX idx_orders_client_order_id ON orders (user_id, client_order_id);
+
+-- Responses to mutating requests sent with an Idempotency-Key header,
+-- replayed when the same key is sent again. A status code of 0 marks a
+-- request still in progress.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id BIGINT UNSIGNED NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    response_body MEDIUMTEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, idempotency_key),
+    INDEX idx_idempotency_keys_created (created_at)
+);
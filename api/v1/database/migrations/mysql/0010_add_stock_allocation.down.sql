@@ -0,0 +1,2 @@
+ALTER TABLE stocks DROP COLUMN min_allocation;
+ALTER TABLE stocks DROP COLUMN allocation;
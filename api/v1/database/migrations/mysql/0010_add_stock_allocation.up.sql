@@ -0,0 +1,5 @@
+-- How an incoming order is split among the resting orders of a price level:
+-- FIFO, PRO_RATA or TOP_ORDER_PRO_RATA. Pro-rata shares below
+-- min_allocation are dropped and allocated in time priority instead.
+ALTER TABLE stocks ADD COLUMN allocation ENUM('FIFO', 'PRO_RATA', 'TOP_ORDER_PRO_RATA') NOT NULL DEFAULT 'FIFO' AFTER status;
+ALTER TABLE stocks ADD COLUMN min_allocation INT UNSIGNED NOT NULL DEFAULT 0 AFTER allocation;
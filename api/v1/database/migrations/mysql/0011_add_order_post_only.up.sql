@@ -0,0 +1,3 @@
+-- Post-only orders never take liquidity: an order that would match on
+-- arrival is rejected or repriced one tick behind the opposite best
+ALTER TABLE orders ADD COLUMN post_only BOOLEAN NOT NULL DEFAULT FALSE AFTER client_order_id;
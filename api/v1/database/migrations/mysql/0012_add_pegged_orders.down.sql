@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN peg_cap;
+ALTER TABLE orders DROP COLUMN peg_offset;
+ALTER TABLE orders DROP COLUMN peg_type;
+ALTER TABLE orders MODIFY COLUMN category ENUM('LIMIT', 'MARKET') NOT NULL;
@@ -0,0 +1,8 @@
+-- Pegged orders float with the book: their price follows the same-side best
+-- (PRIMARY), the midpoint (MIDPOINT) or the opposite best (MARKET) plus
+-- peg_offset, limited by peg_cap when it is not 0. A pegged order that
+-- cannot be priced is held at price 0 until it can.
+ALTER TABLE orders MODIFY COLUMN category ENUM('LIMIT', 'MARKET', 'PEGGED') NOT NULL;
+ALTER TABLE orders ADD COLUMN peg_type ENUM('PRIMARY', 'MIDPOINT', 'MARKET') NULL AFTER post_only;
+ALTER TABLE orders ADD COLUMN peg_offset DECIMAL(10,2) NOT NULL DEFAULT 0 AFTER peg_type;
+ALTER TABLE orders ADD COLUMN peg_cap DECIMAL(10,2) NOT NULL DEFAULT 0 AFTER peg_offset;
@@ -0,0 +1,7 @@
+ALTER TABLE orders DROP FOREIGN KEY fk_orders_parent;
+ALTER TABLE orders DROP COLUMN parent_order_id;
+ALTER TABLE orders DROP COLUMN trail_percent;
+ALTER TABLE orders DROP COLUMN trail_amount;
+ALTER TABLE orders DROP COLUMN stop_price;
+ALTER TABLE orders MODIFY COLUMN status ENUM('PENDING', 'PARTIALLY_FILLED', 'MATCHED', 'CANCELLED') DEFAULT 'PENDING';
+ALTER TABLE orders MODIFY COLUMN category ENUM('LIMIT', 'MARKET', 'PEGGED') NOT NULL;
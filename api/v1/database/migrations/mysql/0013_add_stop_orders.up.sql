@@ -0,0 +1,11 @@
+-- Stop orders rest outside the book until a trade prints at or through
+-- stop_price, then enter a child order (parent_order_id) and become
+-- TRIGGERED. The stop_price of a trailing stop ratchets behind the last
+-- trade price by trail_amount or trail_percent.
+ALTER TABLE orders MODIFY COLUMN category ENUM('LIMIT', 'MARKET', 'PEGGED', 'STOP', 'TRAILING_STOP') NOT NULL;
+ALTER TABLE orders MODIFY COLUMN status ENUM('PENDING', 'PARTIALLY_FILLED', 'MATCHED', 'CANCELLED', 'TRIGGERED') DEFAULT 'PENDING';
+ALTER TABLE orders ADD COLUMN stop_price DECIMAL(10,2) NOT NULL DEFAULT 0 AFTER peg_cap;
+ALTER TABLE orders ADD COLUMN trail_amount DECIMAL(10,2) NOT NULL DEFAULT 0 AFTER stop_price;
+ALTER TABLE orders ADD COLUMN trail_percent DECIMAL(5,2) NOT NULL DEFAULT 0 AFTER trail_amount;
+ALTER TABLE orders ADD COLUMN parent_order_id BIGINT UNSIGNED NULL AFTER trail_percent;
+ALTER TABLE orders ADD CONSTRAINT fk_orders_parent FOREIGN KEY (parent_order_id) REFERENCES orders(id);
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP FOREIGN KEY fk_orders_group;
+ALTER TABLE orders DROP COLUMN group_id;
+DROP TABLE IF EXISTS order_groups;
@@ -0,0 +1,20 @@
+-- Order groups link orders: an OCO group holds a take-profit limit order
+-- and a stop order, where a fill on one cancels the other, and a bracket
+-- group holds an entry order whose fills activate an OCO exit pair at
+-- take_profit_price and stop_price
+CREATE TABLE IF NOT EXISTS order_groups (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    type ENUM('OCO', 'BRACKET') NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    status ENUM('ACTIVE', 'COMPLETED', 'CANCELLED') NOT NULL DEFAULT 'ACTIVE',
+    take_profit_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    stop_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol),
+    INDEX idx_order_groups_user (user_id, id)
+);
+
+ALTER TABLE orders ADD COLUMN group_id BIGINT UNSIGNED NULL AFTER parent_order_id;
+ALTER TABLE orders ADD CONSTRAINT fk_orders_group FOREIGN KEY (group_id) REFERENCES order_groups(id);
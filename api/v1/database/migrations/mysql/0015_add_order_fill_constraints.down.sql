@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN all_or_none;
+ALTER TABLE orders DROP COLUMN min_quantity;
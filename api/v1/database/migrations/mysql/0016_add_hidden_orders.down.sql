@@ -0,0 +1,2 @@
+ALTER TABLE trades DROP COLUMN hidden;
+ALTER TABLE orders DROP COLUMN hidden;
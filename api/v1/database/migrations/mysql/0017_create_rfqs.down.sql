@@ -0,0 +1,5 @@
+ALTER TABLE trades DROP COLUMN negotiated;
+DROP TABLE IF EXISTS rfq_events;
+ALTER TABLE rfqs DROP FOREIGN KEY fk_rfqs_quote;
+DROP TABLE IF EXISTS rfq_quotes;
+DROP TABLE IF EXISTS rfqs;
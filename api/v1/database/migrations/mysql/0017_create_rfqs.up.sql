@@ -0,0 +1,48 @@
+-- Requests for quote: a requester asks the market makers for two-way
+-- quotes on a block of a stock and may accept one of them until
+-- expires_at. The execution is written to trades as an off-book trade
+-- flagged negotiated, and rfq_events keeps the history of every RFQ.
+CREATE TABLE IF NOT EXISTS rfqs (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    quantity INT UNSIGNED NOT NULL,
+    status ENUM('OPEN', 'ACCEPTED', 'EXPIRED', 'CANCELLED') NOT NULL DEFAULT 'OPEN',
+    expires_at TIMESTAMP NOT NULL,
+    accepted_quote_id BIGINT UNSIGNED NULL,
+    trade_id BIGINT UNSIGNED NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol),
+    FOREIGN KEY (trade_id) REFERENCES trades(id),
+    INDEX idx_rfqs_user (user_id, id),
+    INDEX idx_rfqs_status (status, id)
+);
+
+CREATE TABLE IF NOT EXISTS rfq_quotes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    rfq_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    bid_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ask_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (rfq_id) REFERENCES rfqs(id),
+    UNIQUE (rfq_id, user_id)
+);
+ALTER TABLE rfqs ADD CONSTRAINT fk_rfqs_quote FOREIGN KEY (accepted_quote_id) REFERENCES rfq_quotes(id);
+
+CREATE TABLE IF NOT EXISTS rfq_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    rfq_id BIGINT UNSIGNED NOT NULL,
+    type ENUM('CREATED', 'QUOTED', 'ACCEPTED', 'EXPIRED', 'CANCELLED') NOT NULL,
+    user_id BIGINT UNSIGNED NULL,
+    quote_id BIGINT UNSIGNED NULL,
+    trade_id BIGINT UNSIGNED NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (rfq_id) REFERENCES rfqs(id),
+    FOREIGN KEY (quote_id) REFERENCES rfq_quotes(id),
+    FOREIGN KEY (trade_id) REFERENCES trades(id),
+    INDEX idx_rfq_events_rfq (rfq_id, id)
+);
+
+ALTER TABLE trades ADD COLUMN negotiated BOOLEAN NOT NULL DEFAULT FALSE AFTER hidden;
@@ -0,0 +1,19 @@
+-- Trade busts and corrections. Trades are never changed once written: a
+-- bust links the trade to a correction record, and a correction does the
+-- same and also books a replacement trade at the corrected price and
+-- quantity, linked as new_trade_id. A trade can be corrected once; later
+-- corrections apply to its replacement.
+CREATE TABLE IF NOT EXISTS trade_corrections (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    trade_id BIGINT UNSIGNED NOT NULL,
+    type ENUM('BUST', 'CORRECT') NOT NULL,
+    new_trade_id BIGINT UNSIGNED NULL,
+    price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    quantity INT UNSIGNED NOT NULL DEFAULT 0,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    user_id BIGINT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (trade_id) REFERENCES trades(id),
+    FOREIGN KEY (new_trade_id) REFERENCES trades(id),
+    UNIQUE (trade_id)
+);
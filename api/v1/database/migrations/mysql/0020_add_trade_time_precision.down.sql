@@ -0,0 +1 @@
+ALTER TABLE trades MODIFY executed_at TIMESTAMP NOT NULL;
@@ -0,0 +1,3 @@
+-- Trades keep the microseconds of their execution time, which candles are
+-- bucketed by; the other databases already store them
+ALTER TABLE trades MODIFY executed_at TIMESTAMP(6) NOT NULL;
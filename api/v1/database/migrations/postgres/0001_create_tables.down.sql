@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS trades;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS stocks;
+DROP FUNCTION IF EXISTS set_updated_at();
+DROP TYPE IF EXISTS order_status;
+DROP TYPE IF EXISTS order_category;
+DROP TYPE IF EXISTS order_type;
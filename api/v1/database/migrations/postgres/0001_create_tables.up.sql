@@ -0,0 +1,92 @@
+-- PostgreSQL translation of the MySQL schema
+--
+-- ENUM columns become enum types, ON UPDATE CURRENT_TIMESTAMP is emulated
+-- with a trigger, and the stored procedures are dropped: status updates and
+-- market order matching are performed by the OrderMatcher.
+
+-- Create enum types
+DO $$ BEGIN
+    CREATE TYPE order_type AS ENUM ('BUY', 'SELL');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$ BEGIN
+    CREATE TYPE order_category AS ENUM ('LIMIT', 'MARKET');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$ BEGIN
+    CREATE TYPE order_status AS ENUM ('PENDING', 'PARTIALLY_FILLED', 'MATCHED', 'CANCELLED');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+-- Maintain updated_at on every row update
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create stocks table
+CREATE TABLE IF NOT EXISTS stocks (
+    symbol VARCHAR(10) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(500),
+    current_price NUMERIC(10,2) NOT NULL,
+    day_high NUMERIC(10,2) NOT NULL,
+    day_low NUMERIC(10,2) NOT NULL,
+    volume BIGINT NOT NULL,
+    market_cap NUMERIC(15,2) NOT NULL,
+    sector VARCHAR(50) NOT NULL,
+    last_updated TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_sector ON stocks (sector);
+CREATE INDEX IF NOT EXISTS idx_price ON stocks (current_price);
+
+DROP TRIGGER IF EXISTS stocks_updated_at ON stocks;
+CREATE TRIGGER stocks_updated_at BEFORE UPDATE ON stocks
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Create orders table
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGSERIAL PRIMARY KEY,
+    type order_type NOT NULL,
+    category order_category NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    filled_quantity INTEGER DEFAULT 0 CHECK (filled_quantity >= 0),
+    price NUMERIC(10,2) NOT NULL,
+    status order_status DEFAULT 'PENDING',
+    user_id BIGINT NOT NULL CHECK (user_id >= 0),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_type_status ON orders (type, status);
+CREATE INDEX IF NOT EXISTS idx_stock_status ON orders (stock_symbol, status);
+CREATE INDEX IF NOT EXISTS idx_user ON orders (user_id);
+
+DROP TRIGGER IF EXISTS orders_updated_at ON orders;
+CREATE TRIGGER orders_updated_at BEFORE UPDATE ON orders
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Create trades table
+CREATE TABLE IF NOT EXISTS trades (
+    id BIGSERIAL PRIMARY KEY,
+    buy_order_id BIGINT NOT NULL REFERENCES orders(id),
+    sell_order_id BIGINT NOT NULL REFERENCES orders(id),
+    stock_symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    price NUMERIC(10,2) NOT NULL,
+    executed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_stock_time ON trades (stock_symbol, executed_at);
+CREATE INDEX IF NOT EXISTS idx_orders ON trades (buy_order_id, sell_order_id);
+
+DROP TRIGGER IF EXISTS trades_updated_at ON trades;
+CREATE TRIGGER trades_updated_at BEFORE UPDATE ON trades
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1 @@
+DELETE FROM stocks WHERE symbol IN ('NXTECH', 'QNTUM', 'CYBEX', 'SOLRX', 'FUSON', 'GENUM', 'MEDIX', 'AITHN', 'NRLNK', 'COGNT');
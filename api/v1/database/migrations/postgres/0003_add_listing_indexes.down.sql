@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_orders_user_id;
+DROP INDEX IF EXISTS idx_orders_stock_id;
+DROP INDEX IF EXISTS idx_orders_stock_price;
+DROP INDEX IF EXISTS idx_orders_status_id;
+DROP INDEX IF EXISTS idx_orders_price;
+DROP INDEX IF EXISTS idx_orders_created;
+DROP INDEX IF EXISTS idx_trades_stock_id;
+DROP INDEX IF EXISTS idx_trades_price;
+DROP INDEX IF EXISTS idx_trades_executed;
+DROP INDEX IF EXISTS idx_trades_sell_order;
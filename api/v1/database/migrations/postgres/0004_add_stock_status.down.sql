@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_stocks_status;
+ALTER TABLE stocks DROP COLUMN status;
+DROP TYPE IF EXISTS stock_status;
@@ -0,0 +1,4 @@
+-- Stocks are delisted rather than deleted so their trade history is kept
+CREATE TYPE stock_status AS ENUM ('ACTIVE', 'DELISTED');
+ALTER TABLE stocks ADD COLUMN status stock_status NOT NULL DEFAULT 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_stocks_status ON stocks (status);
@@ -0,0 +1,4 @@
+ALTER TABLE stocks DROP COLUMN session_date;
+ALTER TABLE stocks DROP COLUMN trade_count;
+ALTER TABLE stocks DROP COLUMN vwap;
+ALTER TABLE stocks DROP COLUMN previous_close;
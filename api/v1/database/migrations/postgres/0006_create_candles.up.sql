@@ -0,0 +1,14 @@
+-- OHLCV bars aggregated from trades, one row per symbol, interval and bar
+-- open time
+CREATE TABLE IF NOT EXISTS candles (
+    stock_symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    period VARCHAR(3) NOT NULL,
+    open_time TIMESTAMPTZ NOT NULL,
+    open_price NUMERIC(10,2) NOT NULL,
+    high_price NUMERIC(10,2) NOT NULL,
+    low_price NUMERIC(10,2) NOT NULL,
+    close_price NUMERIC(10,2) NOT NULL,
+    volume BIGINT NOT NULL,
+    trade_count BIGINT NOT NULL,
+    PRIMARY KEY (stock_symbol, period, open_time)
+);
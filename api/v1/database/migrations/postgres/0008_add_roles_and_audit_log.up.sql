@@ -0,0 +1,16 @@
+-- Every user has one role, which grants the permissions checked on each
+-- route; every check is recorded in audit_log
+CREATE TYPE user_role AS ENUM ('TRADER', 'READ_ONLY', 'MARKET_MAKER', 'OPERATOR', 'ADMIN');
+ALTER TABLE users ADD COLUMN role user_role NOT NULL DEFAULT 'TRADER';
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    permission VARCHAR(20) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log (user_id);
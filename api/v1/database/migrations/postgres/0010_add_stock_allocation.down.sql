@@ -0,0 +1,3 @@
+ALTER TABLE stocks DROP COLUMN min_allocation;
+ALTER TABLE stocks DROP COLUMN allocation;
+DROP TYPE IF EXISTS allocation_algorithm;
@@ -0,0 +1,6 @@
+-- How an incoming order is split among the resting orders of a price level:
+-- FIFO, PRO_RATA or TOP_ORDER_PRO_RATA. Pro-rata shares below
+-- min_allocation are dropped and allocated in time priority instead.
+CREATE TYPE allocation_algorithm AS ENUM ('FIFO', 'PRO_RATA', 'TOP_ORDER_PRO_RATA');
+ALTER TABLE stocks ADD COLUMN allocation allocation_algorithm NOT NULL DEFAULT 'FIFO';
+ALTER TABLE stocks ADD COLUMN min_allocation INTEGER NOT NULL DEFAULT 0 CHECK (min_allocation >= 0);
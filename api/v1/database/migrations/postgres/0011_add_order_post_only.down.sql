@@ -0,0 +1 @@
+ALTER TABLE orders DROP COLUMN post_only;
@@ -0,0 +1,6 @@
+-- PostgreSQL cannot drop a value from an enum type, so PEGGED stays in
+-- order_category
+ALTER TABLE orders DROP COLUMN peg_cap;
+ALTER TABLE orders DROP COLUMN peg_offset;
+ALTER TABLE orders DROP COLUMN peg_type;
+DROP TYPE IF EXISTS peg_type;
@@ -0,0 +1,9 @@
+-- Pegged orders float with the book: their price follows the same-side best
+-- (PRIMARY), the midpoint (MIDPOINT) or the opposite best (MARKET) plus
+-- peg_offset, limited by peg_cap when it is not 0. A pegged order that
+-- cannot be priced is held at price 0 until it can.
+ALTER TYPE order_category ADD VALUE IF NOT EXISTS 'PEGGED';
+CREATE TYPE peg_type AS ENUM ('PRIMARY', 'MIDPOINT', 'MARKET');
+ALTER TABLE orders ADD COLUMN peg_type peg_type NULL;
+ALTER TABLE orders ADD COLUMN peg_offset NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN peg_cap NUMERIC(10,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- PostgreSQL cannot drop a value from an enum type, so STOP, TRAILING_STOP
+-- and TRIGGERED stay in order_category and order_status
+ALTER TABLE orders DROP COLUMN parent_order_id;
+ALTER TABLE orders DROP COLUMN trail_percent;
+ALTER TABLE orders DROP COLUMN trail_amount;
+ALTER TABLE orders DROP COLUMN stop_price;
@@ -0,0 +1,11 @@
+-- Stop orders rest outside the book until a trade prints at or through
+-- stop_price, then enter a child order (parent_order_id) and become
+-- TRIGGERED. The stop_price of a trailing stop ratchets behind the last
+-- trade price by trail_amount or trail_percent.
+ALTER TYPE order_category ADD VALUE IF NOT EXISTS 'STOP';
+ALTER TYPE order_category ADD VALUE IF NOT EXISTS 'TRAILING_STOP';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'TRIGGERED';
+ALTER TABLE orders ADD COLUMN stop_price NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN trail_amount NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN trail_percent NUMERIC(5,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN parent_order_id BIGINT NULL REFERENCES orders(id);
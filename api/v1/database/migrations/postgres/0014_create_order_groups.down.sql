@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN group_id;
+DROP TABLE IF EXISTS order_groups;
+DROP TYPE IF EXISTS order_group_status;
+DROP TYPE IF EXISTS order_group_type;
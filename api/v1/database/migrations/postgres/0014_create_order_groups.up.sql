@@ -0,0 +1,22 @@
+-- Order groups link orders: an OCO group holds a take-profit limit order
+-- and a stop order, where a fill on one cancels the other, and a bracket
+-- group holds an entry order whose fills activate an OCO exit pair at
+-- take_profit_price and stop_price
+CREATE TYPE order_group_type AS ENUM ('OCO', 'BRACKET');
+CREATE TYPE order_group_status AS ENUM ('ACTIVE', 'COMPLETED', 'CANCELLED');
+
+CREATE TABLE IF NOT EXISTS order_groups (
+    id BIGSERIAL PRIMARY KEY,
+    type order_group_type NOT NULL,
+    user_id BIGINT NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    status order_group_status NOT NULL DEFAULT 'ACTIVE',
+    take_profit_price NUMERIC(10,2) NOT NULL DEFAULT 0,
+    stop_price NUMERIC(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_order_groups_user ON order_groups (user_id, id);
+
+ALTER TABLE orders ADD COLUMN group_id BIGINT NULL REFERENCES order_groups(id);
+CREATE INDEX IF NOT EXISTS idx_orders_group ON orders (group_id);
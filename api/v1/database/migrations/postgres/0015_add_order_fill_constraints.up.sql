@@ -0,0 +1,5 @@
+-- Fill constraints: every execution of an order fills at least
+-- min_quantity, or what remains of it if less, and an all-or-none order
+-- only executes for its whole remaining quantity at once
+ALTER TABLE orders ADD COLUMN min_quantity INTEGER NOT NULL DEFAULT 0 CHECK (min_quantity >= 0);
+ALTER TABLE orders ADD COLUMN all_or_none BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- Hidden orders rest in a non-displayed book per stock and only execute
+-- at the midpoint of the lit best bid and offer; their trades are flagged
+-- hidden
+ALTER TABLE orders ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE trades ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
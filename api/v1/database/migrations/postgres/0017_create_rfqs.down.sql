@@ -0,0 +1,7 @@
+ALTER TABLE trades DROP COLUMN negotiated;
+DROP TABLE IF EXISTS rfq_events;
+ALTER TABLE rfqs DROP CONSTRAINT IF EXISTS fk_rfqs_quote;
+DROP TABLE IF EXISTS rfq_quotes;
+DROP TABLE IF EXISTS rfqs;
+DROP TYPE IF EXISTS rfq_event_type;
+DROP TYPE IF EXISTS rfq_status;
@@ -0,0 +1,45 @@
+-- Requests for quote: a requester asks the market makers for two-way
+-- quotes on a block of a stock and may accept one of them until
+-- expires_at. The execution is written to trades as an off-book trade
+-- flagged negotiated, and rfq_events keeps the history of every RFQ.
+CREATE TYPE rfq_status AS ENUM ('OPEN', 'ACCEPTED', 'EXPIRED', 'CANCELLED');
+CREATE TYPE rfq_event_type AS ENUM ('CREATED', 'QUOTED', 'ACCEPTED', 'EXPIRED', 'CANCELLED');
+
+CREATE TABLE IF NOT EXISTS rfqs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status rfq_status NOT NULL DEFAULT 'OPEN',
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_quote_id BIGINT NULL,
+    trade_id BIGINT NULL REFERENCES trades(id),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_rfqs_user ON rfqs (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_rfqs_status ON rfqs (status, id);
+
+CREATE TABLE IF NOT EXISTS rfq_quotes (
+    id BIGSERIAL PRIMARY KEY,
+    rfq_id BIGINT NOT NULL REFERENCES rfqs(id),
+    user_id BIGINT NOT NULL,
+    bid_price NUMERIC(10,2) NOT NULL DEFAULT 0,
+    ask_price NUMERIC(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (rfq_id, user_id)
+);
+ALTER TABLE rfqs ADD CONSTRAINT fk_rfqs_quote FOREIGN KEY (accepted_quote_id) REFERENCES rfq_quotes(id);
+
+CREATE TABLE IF NOT EXISTS rfq_events (
+    id BIGSERIAL PRIMARY KEY,
+    rfq_id BIGINT NOT NULL REFERENCES rfqs(id),
+    type rfq_event_type NOT NULL,
+    user_id BIGINT NULL,
+    quote_id BIGINT NULL REFERENCES rfq_quotes(id),
+    trade_id BIGINT NULL REFERENCES trades(id),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_rfq_events_rfq ON rfq_events (rfq_id, id);
+
+ALTER TABLE trades ADD COLUMN negotiated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS trade_corrections;
+DROP TYPE IF EXISTS trade_correction_type;
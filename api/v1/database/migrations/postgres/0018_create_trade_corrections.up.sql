@@ -0,0 +1,18 @@
+-- Trade busts and corrections. Trades are never changed once written: a
+-- bust links the trade to a correction record, and a correction does the
+-- same and also books a replacement trade at the corrected price and
+-- quantity, linked as new_trade_id. A trade can be corrected once; later
+-- corrections apply to its replacement.
+CREATE TYPE trade_correction_type AS ENUM ('BUST', 'CORRECT');
+
+CREATE TABLE IF NOT EXISTS trade_corrections (
+    id BIGSERIAL PRIMARY KEY,
+    trade_id BIGINT NOT NULL UNIQUE REFERENCES trades(id),
+    type trade_correction_type NOT NULL,
+    new_trade_id BIGINT NULL REFERENCES trades(id),
+    price NUMERIC(10,2) NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
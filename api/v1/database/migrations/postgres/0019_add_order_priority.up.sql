@@ -0,0 +1,4 @@
+-- Orders rank within a price level by their priority time, which is their
+-- creation time until a pegged order is repriced
+ALTER TABLE orders ADD COLUMN priority_at TIMESTAMP NULL;
+UPDATE orders SET priority_at = created_at;
@@ -0,0 +1,78 @@
+-- SQLite translation of the MySQL schema
+--
+-- ENUM columns become TEXT with CHECK constraints, ON UPDATE CURRENT_TIMESTAMP
+-- is emulated with triggers, and the stored procedures are dropped: status
+-- updates and market order matching are performed by the OrderMatcher.
+
+-- Create stocks table
+CREATE TABLE IF NOT EXISTS stocks (
+    symbol TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    current_price REAL NOT NULL,
+    day_high REAL NOT NULL,
+    day_low REAL NOT NULL,
+    volume INTEGER NOT NULL,
+    market_cap REAL NOT NULL,
+    sector TEXT NOT NULL,
+    last_updated TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_sector ON stocks (sector);
+CREATE INDEX IF NOT EXISTS idx_price ON stocks (current_price);
+
+CREATE TRIGGER IF NOT EXISTS stocks_updated_at AFTER UPDATE ON stocks
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE stocks SET updated_at = CURRENT_TIMESTAMP WHERE symbol = NEW.symbol;
+END;
+
+-- Create orders table
+CREATE TABLE IF NOT EXISTS orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL CHECK (type IN ('BUY', 'SELL')),
+    category TEXT NOT NULL CHECK (category IN ('LIMIT', 'MARKET')),
+    stock_symbol TEXT NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    filled_quantity INTEGER DEFAULT 0 CHECK (filled_quantity >= 0),
+    price REAL NOT NULL,
+    status TEXT DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'PARTIALLY_FILLED', 'MATCHED', 'CANCELLED')),
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol)
+);
+CREATE INDEX IF NOT EXISTS idx_type_status ON orders (type, status);
+CREATE INDEX IF NOT EXISTS idx_stock_status ON orders (stock_symbol, status);
+CREATE INDEX IF NOT EXISTS idx_user ON orders (user_id);
+
+CREATE TRIGGER IF NOT EXISTS orders_updated_at AFTER UPDATE ON orders
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE orders SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+-- Create trades table
+CREATE TABLE IF NOT EXISTS trades (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    buy_order_id INTEGER NOT NULL,
+    sell_order_id INTEGER NOT NULL,
+    stock_symbol TEXT NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    price REAL NOT NULL,
+    executed_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (buy_order_id) REFERENCES orders(id),
+    FOREIGN KEY (sell_order_id) REFERENCES orders(id),
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol)
+);
+CREATE INDEX IF NOT EXISTS idx_stock_time ON trades (stock_symbol, executed_at);
+CREATE INDEX IF NOT EXISTS idx_orders ON trades (buy_order_id, sell_order_id);
+
+CREATE TRIGGER IF NOT EXISTS trades_updated_at AFTER UPDATE ON trades
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE trades SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
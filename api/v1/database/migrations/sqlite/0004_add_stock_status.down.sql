@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_stocks_status;
+ALTER TABLE stocks DROP COLUMN status;
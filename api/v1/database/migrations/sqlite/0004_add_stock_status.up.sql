@@ -0,0 +1,3 @@
+-- Stocks are delisted rather than deleted so their trade history is kept
+ALTER TABLE stocks ADD COLUMN status TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'DELISTED'));
+CREATE INDEX IF NOT EXISTS idx_stocks_status ON stocks (status);
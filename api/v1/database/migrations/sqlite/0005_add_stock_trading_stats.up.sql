@@ -0,0 +1,7 @@
+-- Trading statistics maintained by the matching engine. session_date is the
+-- trading day the intraday fields belong to; the default makes every stock
+-- roll into a fresh session on its first trade.
+ALTER TABLE stocks ADD COLUMN previous_close REAL NOT NULL DEFAULT 0;
+ALTER TABLE stocks ADD COLUMN vwap REAL NOT NULL DEFAULT 0;
+ALTER TABLE stocks ADD COLUMN trade_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE stocks ADD COLUMN session_date DATE NOT NULL DEFAULT '1970-01-01';
@@ -0,0 +1,14 @@
+-- OHLCV bars aggregated from trades, one row per symbol, interval and bar
+-- open time
+CREATE TABLE IF NOT EXISTS candles (
+    stock_symbol TEXT NOT NULL REFERENCES stocks(symbol),
+    period TEXT NOT NULL,
+    open_time TIMESTAMP NOT NULL,
+    open_price REAL NOT NULL,
+    high_price REAL NOT NULL,
+    low_price REAL NOT NULL,
+    close_price REAL NOT NULL,
+    volume INTEGER NOT NULL,
+    trade_count INTEGER NOT NULL,
+    PRIMARY KEY (stock_symbol, period, open_time)
+);
@@ -0,0 +1,15 @@
+-- Every user has one role, which grants the permissions checked on each
+-- route; every check is recorded in audit_log
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'TRADER' CHECK (role IN ('TRADER', 'READ_ONLY', 'MARKET_MAKER', 'OPERATOR', 'ADMIN'));
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log (user_id);
@@ -0,0 +1,19 @@
+-- Clients may tag orders with their own id, unique per user, so that a
+-- re-sent order is detected instead of entered twice
+ALTER TABLE orders ADD COLUMN client_order_id TEXT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_client_order_id ON orders (user_id, client_order_id);
+
+-- Responses to mutating requests sent with an Idempotency-Key header,
+-- replayed when the same key is sent again. A status code of 0 marks a
+-- request still in progress.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id INTEGER NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL DEFAULT '',
+    response_body TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys (created_at);
@@ -0,0 +1,5 @@
+-- How an incoming order is split among the resting orders of a price level:
+-- FIFO, PRO_RATA or TOP_ORDER_PRO_RATA. Pro-rata shares below
+-- min_allocation are dropped and allocated in time priority instead.
+ALTER TABLE stocks ADD COLUMN allocation TEXT NOT NULL DEFAULT 'FIFO' CHECK (allocation IN ('FIFO', 'PRO_RATA', 'TOP_ORDER_PRO_RATA'));
+ALTER TABLE stocks ADD COLUMN min_allocation INTEGER NOT NULL DEFAULT 0 CHECK (min_allocation >= 0);
@@ -0,0 +1,80 @@
+-- Rebuild the orders table without stop orders; see the up migration
+CREATE TABLE orders_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL CHECK (type IN ('BUY', 'SELL')),
+    category TEXT NOT NULL CHECK (category IN ('LIMIT', 'MARKET', 'PEGGED')),
+    stock_symbol TEXT NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    filled_quantity INTEGER DEFAULT 0 CHECK (filled_quantity >= 0),
+    price REAL NOT NULL,
+    status TEXT DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'PARTIALLY_FILLED', 'MATCHED', 'CANCELLED')),
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    client_order_id TEXT NULL,
+    post_only BOOLEAN NOT NULL DEFAULT FALSE,
+    peg_type TEXT NULL CHECK (peg_type IN ('PRIMARY', 'MIDPOINT', 'MARKET')),
+    peg_offset REAL NOT NULL DEFAULT 0,
+    peg_cap REAL NOT NULL DEFAULT 0,
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol)
+);
+
+CREATE TABLE trades_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    buy_order_id INTEGER NOT NULL,
+    sell_order_id INTEGER NOT NULL,
+    stock_symbol TEXT NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    price REAL NOT NULL,
+    executed_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (buy_order_id) REFERENCES orders_new(id),
+    FOREIGN KEY (sell_order_id) REFERENCES orders_new(id),
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol)
+);
+
+INSERT INTO orders_new (id, type, category, stock_symbol, quantity, filled_quantity,
+       price, status, user_id, created_at, updated_at, client_order_id,
+       post_only, peg_type, peg_offset, peg_cap)
+SELECT id, type, category, stock_symbol, quantity, filled_quantity,
+       price, status, user_id, created_at, updated_at, client_order_id,
+       post_only, peg_type, peg_offset, peg_cap
+FROM orders;
+
+INSERT INTO trades_new SELECT * FROM trades;
+
+DROP TABLE trades;
+DROP TABLE orders;
+ALTER TABLE orders_new RENAME TO orders;
+ALTER TABLE trades_new RENAME TO trades;
+
+CREATE INDEX IF NOT EXISTS idx_type_status ON orders (type, status);
+CREATE INDEX IF NOT EXISTS idx_stock_status ON orders (stock_symbol, status);
+CREATE INDEX IF NOT EXISTS idx_user ON orders (user_id);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_orders_stock_id ON orders (stock_symbol, id);
+CREATE INDEX IF NOT EXISTS idx_orders_stock_price ON orders (stock_symbol, price, id);
+CREATE INDEX IF NOT EXISTS idx_orders_status_id ON orders (status, id);
+CREATE INDEX IF NOT EXISTS idx_orders_price ON orders (price, id);
+CREATE INDEX IF NOT EXISTS idx_orders_created ON orders (created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_client_order_id ON orders (user_id, client_order_id);
+
+CREATE INDEX IF NOT EXISTS idx_stock_time ON trades (stock_symbol, executed_at);
+CREATE INDEX IF NOT EXISTS idx_orders ON trades (buy_order_id, sell_order_id);
+CREATE INDEX IF NOT EXISTS idx_trades_stock_id ON trades (stock_symbol, id);
+CREATE INDEX IF NOT EXISTS idx_trades_price ON trades (price, id);
+CREATE INDEX IF NOT EXISTS idx_trades_executed ON trades (executed_at);
+CREATE INDEX IF NOT EXISTS idx_trades_sell_order ON trades (sell_order_id);
+
+CREATE TRIGGER IF NOT EXISTS orders_updated_at AFTER UPDATE ON orders
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE orders SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trades_updated_at AFTER UPDATE ON trades
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE trades SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
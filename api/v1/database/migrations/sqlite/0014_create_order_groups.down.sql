@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_orders_group;
+ALTER TABLE orders DROP COLUMN group_id;
+DROP TABLE IF EXISTS order_groups;
@@ -0,0 +1,20 @@
+-- Order groups link orders: an OCO group holds a take-profit limit order
+-- and a stop order, where a fill on one cancels the other, and a bracket
+-- group holds an entry order whose fills activate an OCO exit pair at
+-- take_profit_price and stop_price
+CREATE TABLE IF NOT EXISTS order_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL CHECK (type IN ('OCO', 'BRACKET')),
+    user_id INTEGER NOT NULL,
+    stock_symbol TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'COMPLETED', 'CANCELLED')),
+    take_profit_price REAL NOT NULL DEFAULT 0,
+    stop_price REAL NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol)
+);
+CREATE INDEX IF NOT EXISTS idx_order_groups_user ON order_groups (user_id, id);
+
+ALTER TABLE orders ADD COLUMN group_id INTEGER NULL REFERENCES order_groups(id);
+CREATE INDEX IF NOT EXISTS idx_orders_group ON orders (group_id);
@@ -0,0 +1,7 @@
+ALTER TABLE trades DROP COLUMN negotiated;
+DROP TABLE IF EXISTS rfq_events;
+
+-- rfqs and rfq_quotes reference each other
+UPDATE rfqs SET accepted_quote_id = NULL;
+DROP TABLE IF EXISTS rfq_quotes;
+DROP TABLE IF EXISTS rfqs;
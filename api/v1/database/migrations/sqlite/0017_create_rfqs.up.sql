@@ -0,0 +1,48 @@
+-- Requests for quote: a requester asks the market makers for two-way
+-- quotes on a block of a stock and may accept one of them until
+-- expires_at. The execution is written to trades as an off-book trade
+-- flagged negotiated, and rfq_events keeps the history of every RFQ.
+CREATE TABLE IF NOT EXISTS rfqs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    stock_symbol TEXT NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'ACCEPTED', 'EXPIRED', 'CANCELLED')),
+    expires_at TIMESTAMP NOT NULL,
+    accepted_quote_id INTEGER NULL,
+    trade_id INTEGER NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (stock_symbol) REFERENCES stocks(symbol),
+    FOREIGN KEY (accepted_quote_id) REFERENCES rfq_quotes(id),
+    FOREIGN KEY (trade_id) REFERENCES trades(id)
+);
+CREATE INDEX IF NOT EXISTS idx_rfqs_user ON rfqs (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_rfqs_status ON rfqs (status, id);
+
+CREATE TABLE IF NOT EXISTS rfq_quotes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rfq_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    bid_price REAL NOT NULL DEFAULT 0,
+    ask_price REAL NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (rfq_id) REFERENCES rfqs(id),
+    UNIQUE (rfq_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS rfq_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rfq_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('CREATED', 'QUOTED', 'ACCEPTED', 'EXPIRED', 'CANCELLED')),
+    user_id INTEGER NULL,
+    quote_id INTEGER NULL,
+    trade_id INTEGER NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (rfq_id) REFERENCES rfqs(id),
+    FOREIGN KEY (quote_id) REFERENCES rfq_quotes(id),
+    FOREIGN KEY (trade_id) REFERENCES trades(id)
+);
+CREATE INDEX IF NOT EXISTS idx_rfq_events_rfq ON rfq_events (rfq_id, id);
+
+ALTER TABLE trades ADD COLUMN negotiated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,18 @@
+-- Trade busts and corrections. Trades are never changed once written: a
+-- bust links the trade to a correction record, and a correction does the
+-- same and also books a replacement trade at the corrected price and
+-- quantity, linked as new_trade_id. A trade can be corrected once; later
+-- corrections apply to its replacement.
+CREATE TABLE IF NOT EXISTS trade_corrections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    trade_id INTEGER NOT NULL UNIQUE,
+    type TEXT NOT NULL CHECK (type IN ('BUST', 'CORRECT')),
+    new_trade_id INTEGER NULL,
+    price REAL NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    reason TEXT NOT NULL DEFAULT '',
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (trade_id) REFERENCES trades(id),
+    FOREIGN KEY (new_trade_id) REFERENCES trades(id)
+);
@@ -0,0 +1,175 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Header is the request header carrying the idempotency key
+	Header = "Idempotency-Key"
+
+	// keyTTL is how long a key is remembered
+	keyTTL = 24 * time.Hour
+
+	// maxKeyLength is the longest key accepted
+	maxKeyLength = 255
+)
+
+// Middleware makes mutating requests sent with an Idempotency-Key header
+// safe to retry. The first request with a key runs and its response is
+// stored; later requests of the same user with that key get the stored
+// response back without running again. Reusing a key for a different
+// request is rejected with 422, and retrying while the first request is
+// still running with 409. Server errors and panics are not stored so that
+// the request may be retried. Keys are forgotten after a day. It must be used behind
+// auth.Middleware, and behind authorization and rate limiting so that
+// their rejections are not stored.
+func Middleware(keys repository.IdempotencyRepository) mux.MiddlewareFunc {
+	var mu sync.Mutex
+	var lastPrune time.Time
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			user, ok := auth.UserFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			// Forget expired keys at most once an hour. Keys are stamped with
+			// the same clock they are expired by.
+			now := time.Now().UTC()
+			mu.Lock()
+			if now.Sub(lastPrune) >= time.Hour {
+				if err := keys.DeleteIdempotencyKeysBefore(now.Add(-keyTTL)); err != nil {
+					logger.Error(err, "Failed to delete expired idempotency keys")
+				}
+				lastPrune = now
+			}
+			mu.Unlock()
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record := &models.IdempotencyKey{
+				UserID:      user.ID,
+				Key:         key,
+				RequestHash: requestHash(r, body),
+				CreatedAt:   now,
+			}
+			if err := keys.CreateIdempotencyKey(record); err != nil {
+				replay(w, keys, record, err)
+				return
+			}
+
+			// Release the key unless the request completes, including when
+			// the handler panics
+			release := true
+			defer func() {
+				if !release {
+					return
+				}
+				if err := keys.DeleteIdempotencyKey(user.ID, key); err != nil {
+					logger.Error(err, "Failed to release idempotency key")
+				}
+			}()
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			release = false
+			record.StatusCode = rec.status
+			record.ContentType = rec.Header().Get("Content-Type")
+			record.ResponseBody = rec.body.String()
+			if err := keys.UpdateIdempotencyKey(record); err != nil {
+				logger.Error(err, "Failed to store idempotent response")
+			}
+		})
+	}
+}
+
+// replay answers a request whose key could not be recorded, normally
+// because it is already
+func replay(w http.ResponseWriter, keys repository.IdempotencyRepository, request *models.IdempotencyKey, createErr error) {
+	stored, err := keys.GetIdempotencyKey(request.UserID, request.Key)
+	if err == sql.ErrNoRows {
+		logger.Error(createErr, "Failed to record idempotency key")
+		http.Error(w, "Failed to record Idempotency-Key", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		logger.Error(err, "Failed to load idempotency key")
+		http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case stored.RequestHash != request.RequestHash:
+		http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+	case stored.StatusCode == 0:
+		http.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+	default:
+		if stored.ContentType != "" {
+			w.Header().Set("Content-Type", stored.ContentType)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.StatusCode)
+		io.WriteString(w, stored.ResponseBody)
+	}
+}
+
+// requestHash identifies a request by its method, path and body
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes a response through while keeping a copy of it
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
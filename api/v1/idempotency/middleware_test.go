@@ -0,0 +1,51 @@
+package idempotency
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"strings"
+	"testing"
+)
+
+// send serves a POST with an idempotency key for user 1
+func send(handler http.Handler, key string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(`{}`))
+	r.Header.Set(Header, key)
+	r = r.WithContext(auth.WithUser(r.Context(), &models.User{ID: 1}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestPanicReleasesKey(t *testing.T) {
+	repo := repository.NewMemory()
+	panicking := Middleware(repo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic was swallowed")
+			}
+		}()
+		send(panicking, "retry")
+	}()
+	if _, err := repo.GetIdempotencyKey(1, "retry"); err != sql.ErrNoRows {
+		t.Fatalf("key after panic: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// The retry runs instead of being told the request is in progress
+	ok := Middleware(repo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	if w := send(ok, "retry"); w.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want 201", w.Code)
+	}
+	if w := send(ok, "retry"); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replay status = %d replayed %q, want a replayed 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+}
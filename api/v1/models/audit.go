@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// AuditEntry records an authorization decision: whether a user was allowed
+// to use a permission on a route
+type AuditEntry struct {
+	ID         uint
+	UserID     uint
+	Role       UserRole
+	Permission string
+	Method     string
+	Path       string
+	Allowed    bool
+	CreatedAt  time.Time
+}
+
+// AuditFilter selects audit entries, newest first. Zero-valued fields are
+// not filtered on.
+type AuditFilter struct {
+	UserID uint
+	Denied bool
+	Limit  int
+}
+
+// CreateAuditEntry stores an authorization decision
+func CreateAuditEntry(db DBTX, entry *AuditEntry) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO audit_log (user_id, role, permission, method, path,
+		                      allowed, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		entry.UserID, entry.Role, entry.Permission, entry.Method,
+		entry.Path, entry.Allowed)
+	if err != nil {
+		return err
+	}
+	entry.ID = uint(id)
+	return nil
+}
+
+// ListAuditEntries retrieves the latest audit entries matching the filter
+func ListAuditEntries(db DBTX, filter AuditFilter) ([]AuditEntry, error) {
+	var conds []string
+	var args []interface{}
+	if filter.UserID != 0 {
+		conds = append(conds, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.Denied {
+		conds = append(conds, "allowed = ?")
+		args = append(args, false)
+	}
+
+	rows, err := db.Query(`
+		SELECT id, user_id, role, permission, method, path, allowed, created_at
+		FROM audit_log
+		`+whereClause(conds)+`
+		ORDER BY id DESC
+		LIMIT `+strconv.Itoa(filter.Limit), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Role, &e.Permission,
+			&e.Method, &e.Path, &e.Allowed, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
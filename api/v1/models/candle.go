@@ -0,0 +1,218 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// CandleInterval is the length of a candle
+type CandleInterval string
+
+const (
+	CandleInterval1s CandleInterval = "1s"
+	CandleInterval1m CandleInterval = "1m"
+	CandleInterval5m CandleInterval = "5m"
+	CandleInterval1h CandleInterval = "1h"
+	CandleInterval1d CandleInterval = "1d"
+)
+
+// CandleIntervals lists the intervals candles are aggregated for
+var CandleIntervals = []CandleInterval{
+	CandleInterval1s, CandleInterval1m, CandleInterval5m,
+	CandleInterval1h, CandleInterval1d,
+}
+
+// Candle is an OHLCV bar of the trades of a stock executed in
+// [OpenTime, OpenTime+Interval)
+type Candle struct {
+	StockSymbol StockSymbol
+	Interval    CandleInterval
+	OpenTime    time.Time
+	Open        float64
+	High        float64
+	Low         float64
+	Close       float64
+	Volume      int64
+	TradeCount  int64
+}
+
+// CandleFilter selects the candles of a stock and interval. The time range
+// applies to the open time; zero bounds are not filtered on.
+type CandleFilter struct {
+	Symbol   StockSymbol
+	Interval CandleInterval
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// Duration returns the length of the interval, or zero if it is not supported
+func (i CandleInterval) Duration() time.Duration {
+	switch i {
+	case CandleInterval1s:
+		return time.Second
+	case CandleInterval1m:
+		return time.Minute
+	case CandleInterval5m:
+		return 5 * time.Minute
+	case CandleInterval1h:
+		return time.Hour
+	case CandleInterval1d:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// OpenTime returns the open time of the candle containing t. Candles are
+// aligned to UTC, so daily candles match the trading sessions.
+func (i CandleInterval) OpenTime(t time.Time) time.Time {
+	return t.UTC().Truncate(i.Duration())
+}
+
+// NewCandle starts a candle with a single trade
+func NewCandle(symbol StockSymbol, interval CandleInterval, executedAt time.Time, price float64, quantity uint) *Candle {
+	return &Candle{
+		StockSymbol: symbol,
+		Interval:    interval,
+		OpenTime:    interval.OpenTime(executedAt),
+		Open:        price,
+		High:        price,
+		Low:         price,
+		Close:       price,
+		Volume:      int64(quantity),
+		TradeCount:  1,
+	}
+}
+
+// Add applies a later trade to the candle
+func (c *Candle) Add(price float64, quantity uint) {
+	if price > c.High {
+		c.High = price
+	}
+	if price < c.Low {
+		c.Low = price
+	}
+	c.Close = price
+	c.Volume += int64(quantity)
+	c.TradeCount++
+}
+
+// candleColumns are the candle columns selected by the query functions
+const candleColumns = `
+		stock_symbol, period, open_time, open_price, high_price, low_price,
+		close_price, volume, trade_count`
+
+// candleFields returns the scan destinations matching candleColumns
+func candleFields(c *Candle) []interface{} {
+	return []interface{}{
+		&c.StockSymbol, &c.Interval, &c.OpenTime, &c.Open, &c.High, &c.Low,
+		&c.Close, &c.Volume, &c.TradeCount,
+	}
+}
+
+// GetCandle retrieves the candle of a stock and interval opening at openTime
+func GetCandle(db DBTX, symbol StockSymbol, interval CandleInterval, openTime time.Time) (*Candle, error) {
+	c := &Candle{}
+	err := db.QueryRow(`
+		SELECT `+candleColumns+`
+		FROM candles
+		WHERE stock_symbol = ? AND period = ? AND open_time = ?`,
+		symbol, interval, openTime.UTC()).Scan(candleFields(c)...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SaveCandle inserts the candle or replaces the stored one with the same
+// stock, interval and open time
+func SaveCandle(db DBTX, c *Candle) error {
+	var exists int
+	err := db.QueryRow(`
+		SELECT 1 FROM candles
+		WHERE stock_symbol = ? AND period = ? AND open_time = ?`,
+		c.StockSymbol, c.Interval, c.OpenTime.UTC()).Scan(&exists)
+	if err == sql.ErrNoRows {
+		_, err = db.Exec(`
+			INSERT INTO candles (stock_symbol, period, open_time, open_price,
+			                    high_price, low_price, close_price, volume,
+			                    trade_count)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.StockSymbol, c.Interval, c.OpenTime.UTC(), c.Open, c.High,
+			c.Low, c.Close, c.Volume, c.TradeCount)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE candles
+		SET open_price = ?, high_price = ?, low_price = ?, close_price = ?,
+		    volume = ?, trade_count = ?
+		WHERE stock_symbol = ? AND period = ? AND open_time = ?`,
+		c.Open, c.High, c.Low, c.Close, c.Volume, c.TradeCount,
+		c.StockSymbol, c.Interval, c.OpenTime.UTC())
+	return err
+}
+
+// ListCandles retrieves the latest filter.Limit candles matching the filter
+// in ascending open time
+func ListCandles(db DBTX, filter CandleFilter) ([]Candle, error) {
+	conds := []string{"stock_symbol = ?", "period = ?"}
+	args := []interface{}{filter.Symbol, filter.Interval}
+	if !filter.From.IsZero() {
+		conds = append(conds, "open_time >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "open_time < ?")
+		args = append(args, filter.To.UTC())
+	}
+
+	rows, err := db.Query(`
+		SELECT `+candleColumns+`
+		FROM candles
+		`+whereClause(conds)+`
+		ORDER BY open_time DESC
+		LIMIT `+strconv.Itoa(filter.Limit), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candles []Candle
+	for rows.Next() {
+		var c Candle
+		if err := rows.Scan(candleFields(&c)...); err != nil {
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Rows were fetched newest first to honour the limit
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles, nil
+}
+
+// DeleteCandle deletes the candle of a stock and interval opening at
+// openTime
+func DeleteCandle(db DBTX, symbol StockSymbol, interval CandleInterval, openTime time.Time) error {
+	_, err := db.Exec(`
+		DELETE FROM candles
+		WHERE stock_symbol = ? AND period = ? AND open_time = ?`,
+		symbol, interval, openTime.UTC())
+	return err
+}
+
+// DeleteCandles deletes all candles of a stock
+func DeleteCandles(db DBTX, symbol StockSymbol) error {
+	_, err := db.Exec(`DELETE FROM candles WHERE stock_symbol = ?`, symbol)
+	return err
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Dialect identifies the SQL flavour spoken by a database connection
+type Dialect string
+
+const (
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// dialectDB is implemented by connections that report their dialect
+type dialectDB interface {
+	Dialect() Dialect
+}
+
+// dialectOf returns the dialect of db. Plain connections are assumed to
+// understand the MySQL-compatible queries written in this package, which
+// SQLite accepts as well.
+func dialectOf(db DBTX) Dialect {
+	if d, ok := db.(dialectDB); ok {
+		return d.Dialect()
+	}
+	return DialectMySQL
+}
+
+// PostgresDB adapts a connection or transaction to PostgreSQL by rewriting
+// the ? placeholders used throughout this package to $1, $2, ...
+type PostgresDB struct {
+	DB DBTX
+}
+
+// Exec executes a query without returning any rows
+func (p PostgresDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.DB.Exec(rebind(query), args...)
+}
+
+// Query executes a query that returns rows
+func (p PostgresDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.DB.Query(rebind(query), args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row
+func (p PostgresDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return p.DB.QueryRow(rebind(query), args...)
+}
+
+// Dialect reports PostgreSQL
+func (p PostgresDB) Dialect() Dialect {
+	return DialectPostgres
+}
+
+// rebind replaces ? placeholders with PostgreSQL positional parameters
+func rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// IdempotencyKey records a mutating request sent with an Idempotency-Key
+// header and, once it completes, its response
+type IdempotencyKey struct {
+	UserID       uint
+	Key          string
+	RequestHash  string // SHA-256 of the method, path and body
+	StatusCode   int    // 0 while the request is in progress
+	ContentType  string
+	ResponseBody string
+	CreatedAt    time.Time
+}
+
+// CreateIdempotencyKey records a request in progress, created now unless
+// its creation time is set. It fails if the user already used the key.
+func CreateIdempotencyKey(db DBTX, key *IdempotencyKey) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+	_, err := db.Exec(`
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash,
+		                             status_code, content_type, response_body,
+		                             created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		key.UserID, key.Key, key.RequestHash, key.StatusCode,
+		key.ContentType, key.ResponseBody, key.CreatedAt.UTC())
+	return err
+}
+
+// GetIdempotencyKey retrieves a key of a user
+func GetIdempotencyKey(db DBTX, userID uint, key string) (*IdempotencyKey, error) {
+	k := &IdempotencyKey{}
+	err := db.QueryRow(`
+		SELECT user_id, idempotency_key, request_hash, status_code,
+		       content_type, COALESCE(response_body, ''), created_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?`, userID, key).Scan(
+		&k.UserID, &k.Key, &k.RequestHash, &k.StatusCode, &k.ContentType,
+		&k.ResponseBody, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// UpdateIdempotencyKey stores the response of a completed request
+func UpdateIdempotencyKey(db DBTX, key *IdempotencyKey) error {
+	_, err := db.Exec(`
+		UPDATE idempotency_keys
+		SET status_code = ?, content_type = ?, response_body = ?
+		WHERE user_id = ? AND idempotency_key = ?`,
+		key.StatusCode, key.ContentType, key.ResponseBody, key.UserID,
+		key.Key)
+	return err
+}
+
+// DeleteIdempotencyKey deletes a key of a user so that it may be used again
+func DeleteIdempotencyKey(db DBTX, userID uint, key string) error {
+	_, err := db.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?`, userID, key)
+	return err
+}
+
+// DeleteIdempotencyKeysBefore deletes the keys created before t
+func DeleteIdempotencyKeysBefore(db DBTX, t time.Time) error {
+	_, err := db.Exec(`DELETE FROM idempotency_keys WHERE created_at < ?`, t.UTC())
+	return err
+}
@@ -0,0 +1,393 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Listing limits
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// SortOrder selects the ordering of a listing
+type SortOrder string
+
+const (
+	SortNewest    SortOrder = "newest"
+	SortOldest    SortOrder = "oldest"
+	SortPriceAsc  SortOrder = "price_asc"
+	SortPriceDesc SortOrder = "price_desc"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were issued
+// for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects a window of a listing using keyset pagination. After is the
+// NextCursor returned with the previous page.
+type Page struct {
+	Limit int
+	After string
+	Sort  SortOrder
+}
+
+// OrderFilter selects orders. Zero-valued fields are not filtered on; the
+// time range applies to the creation time.
+type OrderFilter struct {
+	Symbol   StockSymbol
+	UserID   uint
+	Type     OrderType
+	Status   OrderStatus
+	Category OrderCategory
+	From     time.Time
+	To       time.Time
+	MinPrice float64
+	MaxPrice float64
+	Page
+}
+
+// TradeFilter selects trades. Zero-valued fields are not filtered on; the
+// time range applies to the execution time. Side restricts a UserID filter
+// to trades where the user was the buyer or the seller.
+type TradeFilter struct {
+	Symbol   StockSymbol
+	UserID   uint
+	Side     OrderType
+	From     time.Time
+	To       time.Time
+	MinPrice float64
+	MaxPrice float64
+	Page
+}
+
+// OrderPage is a page of orders with the cursor of the next page, which is
+// empty on the last page
+type OrderPage struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// TradePage is a page of trades with the cursor of the next page, which is
+// empty on the last page
+type TradePage struct {
+	Trades     []Trade `json:"trades"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// cursor is the decoded form of a page cursor: the sort key of the last row
+// of the previous page
+type cursor struct {
+	Sort  SortOrder `json:"s"`
+	Price float64   `json:"p,omitempty"`
+	ID    uint      `json:"i"`
+}
+
+// Normalize applies the default sort order and limit bounds
+func (p *Page) Normalize() {
+	if p.Sort == "" {
+		p.Sort = SortNewest
+	}
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+}
+
+// ValidSortOrder reports whether s is a supported sort order
+func ValidSortOrder(s SortOrder) bool {
+	switch s {
+	case SortNewest, SortOldest, SortPriceAsc, SortPriceDesc:
+		return true
+	}
+	return false
+}
+
+// encodeCursor returns the cursor pointing after a row with the given key
+func encodeCursor(sort SortOrder, price float64, id uint) string {
+	data, _ := json.Marshal(cursor{Sort: sort, Price: price, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses the After cursor of a page, returning nil for the
+// first page
+func (p *Page) decodeCursor() (*cursor, error) {
+	if p.After == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(p.After)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != p.Sort {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// keyset returns the ORDER BY clause and, when paging past a cursor, the
+// condition selecting rows after it for a table with the given alias
+func (p *Page) keyset(alias string, c *cursor) (string, string, []interface{}) {
+	id, price := alias+".id", alias+".price"
+	var orderBy, cond string
+	var args []interface{}
+
+	switch p.Sort {
+	case SortOldest:
+		orderBy = id + " ASC"
+		if c != nil {
+			cond, args = id+" > ?", []interface{}{c.ID}
+		}
+	case SortPriceAsc:
+		orderBy = price + " ASC, " + id + " ASC"
+		if c != nil {
+			cond = "(" + price + " > ? OR (" + price + " = ? AND " + id + " > ?))"
+			args = []interface{}{c.Price, c.Price, c.ID}
+		}
+	case SortPriceDesc:
+		orderBy = price + " DESC, " + id + " DESC"
+		if c != nil {
+			cond = "(" + price + " < ? OR (" + price + " = ? AND " + id + " < ?))"
+			args = []interface{}{c.Price, c.Price, c.ID}
+		}
+	default:
+		// Ids increase with creation time, so they stand in for it and
+		// keep the cursor exact
+		orderBy = id + " DESC"
+		if c != nil {
+			cond, args = id+" < ?", []interface{}{c.ID}
+		}
+	}
+	return orderBy, cond, args
+}
+
+// after reports whether a row with the given key sorts after the cursor
+func (c *cursor) after(price float64, id uint) bool {
+	switch c.Sort {
+	case SortOldest:
+		return id > c.ID
+	case SortPriceAsc:
+		return price > c.Price || (price == c.Price && id > c.ID)
+	case SortPriceDesc:
+		return price < c.Price || (price == c.Price && id < c.ID)
+	default:
+		return id < c.ID
+	}
+}
+
+// Less reports whether the row (priceA, idA) sorts before (priceB, idB) in
+// the page's sort order
+func (p *Page) Less(priceA float64, idA uint, priceB float64, idB uint) bool {
+	switch p.Sort {
+	case SortOldest:
+		return idA < idB
+	case SortPriceAsc:
+		if priceA != priceB {
+			return priceA < priceB
+		}
+		return idA < idB
+	case SortPriceDesc:
+		if priceA != priceB {
+			return priceA > priceB
+		}
+		return idA > idB
+	default:
+		return idA > idB
+	}
+}
+
+// Follows reports whether a row with the given key belongs after the page's
+// cursor. It always holds on the first page.
+func (p *Page) Follows(price float64, id uint) (bool, error) {
+	c, err := p.decodeCursor()
+	if err != nil {
+		return false, err
+	}
+	return c == nil || c.after(price, id), nil
+}
+
+// NextCursor returns the cursor following a row with the given key
+func (p *Page) NextCursor(price float64, id uint) string {
+	return encodeCursor(p.Sort, price, id)
+}
+
+// Matches reports whether the order passes the filter, ignoring pagination
+func (f *OrderFilter) Matches(o *Order) bool {
+	return (f.Symbol == "" || o.StockSymbol == f.Symbol) &&
+		(f.UserID == 0 || o.UserID == f.UserID) &&
+		(f.Type == "" || o.Type == f.Type) &&
+		(f.Status == "" || o.Status == f.Status) &&
+		(f.Category == "" || o.Category == f.Category) &&
+		(f.From.IsZero() || !o.CreatedAt.Before(f.From)) &&
+		(f.To.IsZero() || o.CreatedAt.Before(f.To)) &&
+		(f.MinPrice == 0 || o.Price >= f.MinPrice) &&
+		(f.MaxPrice == 0 || o.Price <= f.MaxPrice)
+}
+
+// Matches reports whether the trade passes the filter, ignoring pagination.
+// The trade's orders must be loaded for user filters.
+func (f *TradeFilter) Matches(t *Trade) bool {
+	if f.UserID != 0 {
+		buyer := t.BuyOrder != nil && t.BuyOrder.UserID == f.UserID
+		seller := t.SellOrder != nil && t.SellOrder.UserID == f.UserID
+		switch f.Side {
+		case OrderTypeBuy:
+			if !buyer {
+				return false
+			}
+		case OrderTypeSell:
+			if !seller {
+				return false
+			}
+		default:
+			if !buyer && !seller {
+				return false
+			}
+		}
+	}
+	return (f.Symbol == "" || t.StockSymbol == f.Symbol) &&
+		(f.From.IsZero() || !t.ExecutedAt.Before(f.From)) &&
+		(f.To.IsZero() || t.ExecutedAt.Before(f.To)) &&
+		(f.MinPrice == 0 || t.Price >= f.MinPrice) &&
+		(f.MaxPrice == 0 || t.Price <= f.MaxPrice)
+}
+
+// ListOrders retrieves a page of orders matching the filter
+func ListOrders(db DBTX, filter OrderFilter) (*OrderPage, error) {
+	filter.Normalize()
+	c, err := filter.decodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg ...interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg...)
+	}
+	if filter.Symbol != "" {
+		add("o.stock_symbol = ?", filter.Symbol)
+	}
+	if filter.UserID != 0 {
+		add("o.user_id = ?", filter.UserID)
+	}
+	if filter.Type != "" {
+		add("o.type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		add("o.status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		add("o.category = ?", filter.Category)
+	}
+	if !filter.From.IsZero() {
+		add("o.created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		add("o.created_at < ?", filter.To.UTC())
+	}
+	if filter.MinPrice != 0 {
+		add("o.price >= ?", filter.MinPrice)
+	}
+	if filter.MaxPrice != 0 {
+		add("o.price <= ?", filter.MaxPrice)
+	}
+
+	orderBy, keyCond, keyArgs := filter.keyset("o", c)
+	if keyCond != "" {
+		add(keyCond, keyArgs...)
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	orders, err := queryOrders(db, whereClause(conds)+
+		" ORDER BY "+orderBy+" LIMIT "+strconv.Itoa(filter.Limit+1), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &OrderPage{Orders: orders}
+	if len(orders) > filter.Limit {
+		page.Orders = orders[:filter.Limit]
+		last := page.Orders[filter.Limit-1]
+		page.NextCursor = filter.NextCursor(last.Price, last.ID)
+	}
+	return page, nil
+}
+
+// ListTrades retrieves a page of trades matching the filter
+func ListTrades(db DBTX, filter TradeFilter) (*TradePage, error) {
+	filter.Normalize()
+	c, err := filter.decodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg ...interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg...)
+	}
+	if filter.Symbol != "" {
+		add("t.stock_symbol = ?", filter.Symbol)
+	}
+	if filter.UserID != 0 {
+		switch filter.Side {
+		case OrderTypeBuy:
+			add("bo.user_id = ?", filter.UserID)
+		case OrderTypeSell:
+			add("so.user_id = ?", filter.UserID)
+		default:
+			add("(bo.user_id = ? OR so.user_id = ?)", filter.UserID, filter.UserID)
+		}
+	}
+	if !filter.From.IsZero() {
+		add("t.executed_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		add("t.executed_at < ?", filter.To.UTC())
+	}
+	if filter.MinPrice != 0 {
+		add("t.price >= ?", filter.MinPrice)
+	}
+	if filter.MaxPrice != 0 {
+		add("t.price <= ?", filter.MaxPrice)
+	}
+
+	orderBy, keyCond, keyArgs := filter.keyset("t", c)
+	if keyCond != "" {
+		add(keyCond, keyArgs...)
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	trades, err := queryTrades(db, whereClause(conds)+
+		" ORDER BY "+orderBy+" LIMIT "+strconv.Itoa(filter.Limit+1), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TradePage{Trades: trades}
+	if len(trades) > filter.Limit {
+		page.Trades = trades[:filter.Limit]
+		last := page.Trades[filter.Limit-1]
+		page.NextCursor = filter.NextCursor(last.Price, last.ID)
+	}
+	return page, nil
+}
+
+// whereClause joins conditions into a WHERE clause
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
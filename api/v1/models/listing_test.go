@@ -0,0 +1,105 @@
+package models_test
+
+import (
+	"database/sql"
+	"order-matching/api/v1/database"
+	"order-matching/api/v1/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// countingDB counts the statements run through it
+type countingDB struct {
+	db      models.DBTX
+	queries int
+}
+
+func (c *countingDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	c.queries++
+	return c.db.Exec(query, args...)
+}
+
+func (c *countingDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	c.queries++
+	return c.db.Query(query, args...)
+}
+
+func (c *countingDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	c.queries++
+	return c.db.QueryRow(query, args...)
+}
+
+// seed migrates a SQLite database and books count trades of COGNT between
+// two users
+func seed(t *testing.T, count int) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(database.DriverSQLite, "file:"+filepath.Join(t.TempDir(), "listing.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	migrator, err := database.NewMigrator(db, database.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < count; i++ {
+		buy := &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 10, Price: 100 + float64(i), Status: models.OrderStatusMatched, UserID: 1}
+		sell := &models.Order{Type: models.OrderTypeSell, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 10, Price: 100 + float64(i), Status: models.OrderStatusMatched, UserID: 2}
+		for _, order := range []*models.Order{buy, sell} {
+			if err := models.CreateOrder(db, order); err != nil {
+				t.Fatal(err)
+			}
+		}
+		trade := &models.Trade{BuyOrderID: buy.ID, SellOrderID: sell.ID, StockSymbol: "COGNT", Quantity: 10, Price: buy.Price, ExecutedAt: time.Now()}
+		if err := models.CreateTrade(db, trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// The listings load a page with its stocks and orders in a single query,
+// whatever the page size
+func TestListingQueryCount(t *testing.T) {
+	db := seed(t, 250)
+
+	for _, limit := range []int{1, 10, 100, 500} {
+		counter := &countingDB{db: db}
+		orders, err := models.ListOrders(counter, models.OrderFilter{Page: models.Page{Limit: limit}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := min(limit, 500); len(orders.Orders) != want {
+			t.Fatalf("limit %d: %d orders, want %d", limit, len(orders.Orders), want)
+		}
+		if counter.queries != 1 {
+			t.Errorf("orders, limit %d: %d queries, want 1", limit, counter.queries)
+		}
+
+		counter = &countingDB{db: db}
+		trades, err := models.ListTrades(counter, models.TradeFilter{UserID: 1, Page: models.Page{Limit: limit, Sort: models.SortPriceDesc}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := min(limit, 250); len(trades.Trades) != want {
+			t.Fatalf("limit %d: %d trades, want %d", limit, len(trades.Trades), want)
+		}
+		for _, trade := range trades.Trades {
+			if trade.BuyOrder == nil || trade.SellOrder == nil || trade.Stock == nil {
+				t.Fatalf("trade %d was listed without its orders and stock", trade.ID)
+			}
+		}
+		if counter.queries != 1 {
+			t.Errorf("trades, limit %d: %d queries, want 1", limit, counter.queries)
+		}
+	}
+}
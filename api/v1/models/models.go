@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 )
 
@@ -16,7 +17,19 @@ type Stock struct {
 	Volume       int64
 	MarketCap    float64
 	Sector       string
+	Status       StockStatus
 	LastUpdated  time.Time
+
+	// Matching rules: the allocation algorithm and, for pro-rata, the
+	// smallest share an order is allocated
+	Allocation    AllocationAlgorithm
+	MinAllocation uint
+
+	// Trading statistics of the current session
+	PreviousClose float64
+	VWAP          float64
+	TradeCount    int64
+	SessionDate   time.Time
 }
 
 // Order represents a trading order
@@ -30,83 +43,253 @@ type Order struct {
 	Price          float64
 	Status         OrderStatus
 	UserID         uint
+	ClientOrderID  string // Optional id chosen by the client, unique per user
+	PostOnly       bool   // Never takes liquidity
+	PegType        PegType
+	PegOffset      float64 // Added to the peg reference price
+	PegCap         float64 // Worst price of a pegged order, 0 if none
+	StopPrice      float64 // Trigger price of a stop order
+	TrailAmount    float64 // Distance of a trailing stop from the last price
+	TrailPercent   float64 // Distance of a trailing stop in percent
+	ParentOrderID  uint    // Stop order that entered this order, 0 if none
+	GroupID        uint    // Order group of the order, 0 if none
+	MinQuantity    uint    // Smallest quantity of an execution, 0 if none
+	AllOrNone      bool    // Only executes for the whole remaining quantity
+	Hidden         bool    // Rests in the dark book and trades at midpoint
+	PriorityAt     time.Time
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	Stock          *Stock
 }
 
+// PriorityTime returns the current time as a priority timestamp, at the
+// microsecond precision the databases store
+func PriorityTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// ExecutionTime returns the current time as the execution time of trades, in
+// UTC at the microsecond precision the databases store
+func ExecutionTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
 // Trade represents a matched trade between two orders
 type Trade struct {
-	ID          uint
-	BuyOrderID  uint
-	SellOrderID uint
-	StockSymbol StockSymbol
-	Quantity    uint
-	Price       float64
-	ExecutedAt  time.Time
-	BuyOrder    *Order
-	SellOrder   *Order
-	Stock       *Stock
+	ID           uint
+	BuyOrderID   uint
+	SellOrderID  uint
+	StockSymbol  StockSymbol
+	Quantity     uint
+	Price        float64
+	Hidden       bool // Executed in the dark book
+	Negotiated   bool // Negotiated off the book through an RFQ
+	CorrectionID uint // Correction that busted or replaced the trade, 0 if none
+	ExecutedAt   time.Time
+	BuyOrder     *Order
+	SellOrder    *Order
+	Stock        *Stock
+}
+
+// DBTX is the subset of *sql.DB and *sql.Tx used by the query functions,
+// allowing them to run either standalone or inside a transaction
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// stockColumns are the stock columns selected by the query functions, for a
+// table aliased as s
+const stockColumns = `
+		s.symbol, s.name, s.description, s.current_price, s.day_high,
+		s.day_low, s.volume, s.market_cap, s.sector, s.status, s.last_updated,
+		s.previous_close, s.vwap, s.trade_count, s.session_date,
+		s.allocation, s.min_allocation`
+
+// orderColumns returns the order columns for a table with the given alias
+func orderColumns(alias string) string {
+	return strings.ReplaceAll(`
+		o.id, o.type, o.category, o.stock_symbol, o.quantity,
+		o.filled_quantity, o.price, o.status, o.user_id,
+		COALESCE(o.client_order_id, ''), o.post_only,
+		COALESCE(o.peg_type, ''), o.peg_offset, o.peg_cap, o.stop_price,
+		o.trail_amount, o.trail_percent, COALESCE(o.parent_order_id, 0),
+		COALESCE(o.group_id, 0), o.min_quantity, o.all_or_none,
+		o.hidden, o.priority_at, o.created_at, o.updated_at`, "o.", alias+".")
+}
+
+// stockFields returns the scan destinations matching stockColumns
+func stockFields(stock *Stock) []interface{} {
+	return []interface{}{
+		&stock.Symbol, &stock.Name, &stock.Description,
+		&stock.CurrentPrice, &stock.DayHigh, &stock.DayLow,
+		&stock.Volume, &stock.MarketCap, &stock.Sector, &stock.Status,
+		&stock.LastUpdated, &stock.PreviousClose, &stock.VWAP,
+		&stock.TradeCount, &stock.SessionDate, &stock.Allocation,
+		&stock.MinAllocation,
+	}
+}
+
+// orderFields returns the scan destinations matching orderColumns
+func orderFields(order *Order) []interface{} {
+	return []interface{}{
+		&order.ID, &order.Type, &order.Category, &order.StockSymbol,
+		&order.Quantity, &order.FilledQuantity, &order.Price,
+		&order.Status, &order.UserID, &order.ClientOrderID,
+		&order.PostOnly, &order.PegType, &order.PegOffset, &order.PegCap,
+		&order.StopPrice, &order.TrailAmount, &order.TrailPercent,
+		&order.ParentOrderID, &order.GroupID, &order.MinQuantity,
+		&order.AllOrNone, &order.Hidden, &order.PriorityAt,
+		&order.CreatedAt, &order.UpdatedAt,
+	}
 }
 
 // GetStockBySymbol retrieves a stock by its symbol
-func GetStockBySymbol(db *sql.DB, symbol StockSymbol) (*Stock, error) {
+func GetStockBySymbol(db DBTX, symbol StockSymbol) (*Stock, error) {
 	stock := &Stock{}
 	err := db.QueryRow(`
-		SELECT symbol, name, description, current_price, day_high, day_low, 
-		       volume, market_cap, sector, last_updated 
-		FROM stocks 
-		WHERE symbol = ?`, symbol).Scan(
-		&stock.Symbol, &stock.Name, &stock.Description,
-		&stock.CurrentPrice, &stock.DayHigh, &stock.DayLow,
-		&stock.Volume, &stock.MarketCap, &stock.Sector, &stock.LastUpdated)
+		SELECT `+stockColumns+`
+		FROM stocks s
+		WHERE s.symbol = ?`, symbol).Scan(stockFields(stock)...)
 	if err != nil {
 		return nil, err
 	}
 	return stock, nil
 }
 
-// GetOrderByID retrieves an order by its ID
-func GetOrderByID(db *sql.DB, id uint) (*Order, error) {
-	order := &Order{}
-	err := db.QueryRow(`
-		SELECT o.id, o.type, o.category, o.stock_symbol, o.quantity, 
-		       o.filled_quantity, o.price, o.status, o.user_id, 
-		       o.created_at, o.updated_at
-		FROM orders o
-		WHERE o.id = ?`, id).Scan(
-		&order.ID, &order.Type, &order.Category, &order.StockSymbol,
-		&order.Quantity, &order.FilledQuantity, &order.Price,
-		&order.Status, &order.UserID, &order.CreatedAt, &order.UpdatedAt)
+// GetAllStocks retrieves all stocks ordered by symbol
+func GetAllStocks(db DBTX) ([]Stock, error) {
+	rows, err := db.Query(`
+		SELECT ` + stockColumns + `
+		FROM stocks s
+		ORDER BY s.symbol`)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var stocks []Stock
+	for rows.Next() {
+		var stock Stock
+		if err := rows.Scan(stockFields(&stock)...); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+	return stocks, rows.Err()
+}
+
+// CreateStock lists a new stock
+func CreateStock(db DBTX, stock *Stock) error {
+	_, err := db.Exec(`
+		INSERT INTO stocks (symbol, name, description, current_price, day_high,
+		                   day_low, volume, market_cap, sector, status,
+		                   allocation, min_allocation, last_updated,
+		                   session_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)`,
+		stock.Symbol, stock.Name, stock.Description, stock.CurrentPrice,
+		stock.DayHigh, stock.DayLow, stock.Volume, stock.MarketCap,
+		stock.Sector, stock.Status, stock.Allocation, stock.MinAllocation,
+		SessionDay(time.Now()).Format(sessionDateLayout))
+	return err
+}
 
-	// Load associated stock
-	stock, err := GetStockBySymbol(db, order.StockSymbol)
+// UpdateStock updates the reference data, listing status and matching rules
+// of a stock
+func UpdateStock(db DBTX, stock *Stock) error {
+	_, err := db.Exec(`
+		UPDATE stocks
+		SET name = ?, description = ?, current_price = ?, day_high = ?,
+		    day_low = ?, volume = ?, market_cap = ?, sector = ?, status = ?,
+		    allocation = ?, min_allocation = ?,
+		    last_updated = CURRENT_TIMESTAMP
+		WHERE symbol = ?`,
+		stock.Name, stock.Description, stock.CurrentPrice, stock.DayHigh,
+		stock.DayLow, stock.Volume, stock.MarketCap, stock.Sector,
+		stock.Status, stock.Allocation, stock.MinAllocation, stock.Symbol)
+	return err
+}
+
+// UpdateStockStats writes the trading statistics of a stock, leaving its
+// reference data alone
+func UpdateStockStats(db DBTX, stock *Stock) error {
+	_, err := db.Exec(`
+		UPDATE stocks
+		SET current_price = ?, day_high = ?, day_low = ?, volume = ?,
+		    previous_close = ?, vwap = ?, trade_count = ?, session_date = ?,
+		    last_updated = CURRENT_TIMESTAMP
+		WHERE symbol = ?`,
+		stock.CurrentPrice, stock.DayHigh, stock.DayLow, stock.Volume,
+		stock.PreviousClose, stock.VWAP, stock.TradeCount,
+		stock.SessionDate.Format(sessionDateLayout), stock.Symbol)
+	return err
+}
+
+// GetOrderByID retrieves an order by its ID
+func GetOrderByID(db DBTX, id uint) (*Order, error) {
+	orders, err := queryOrders(db, `WHERE o.id = ?`, id)
 	if err != nil {
 		return nil, err
 	}
-	order.Stock = stock
+	if len(orders) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &orders[0], nil
+}
 
-	return order, nil
+// GetOrderByClientID retrieves the order of a user with the given client
+// order id
+func GetOrderByClientID(db DBTX, userID uint, clientOrderID string) (*Order, error) {
+	orders, err := queryOrders(db, `WHERE o.user_id = ? AND o.client_order_id = ?`, userID, clientOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &orders[0], nil
 }
 
-// CreateOrder creates a new order in the database
-func CreateOrder(db *sql.DB, order *Order) error {
-	result, err := db.Exec(`
+// CreateOrder creates a new order in the database. Its priority is the
+// current time unless set.
+func CreateOrder(db DBTX, order *Order) error {
+	// Orders without a client order id store NULL, which the unique index
+	// on (user_id, client_order_id) does not compare
+	var clientOrderID interface{}
+	if order.ClientOrderID != "" {
+		clientOrderID = order.ClientOrderID
+	}
+	var pegType interface{}
+	if order.PegType != "" {
+		pegType = order.PegType
+	}
+	var parentOrderID, groupID interface{}
+	if order.ParentOrderID != 0 {
+		parentOrderID = order.ParentOrderID
+	}
+	if order.GroupID != 0 {
+		groupID = order.GroupID
+	}
+	if order.PriorityAt.IsZero() {
+		order.PriorityAt = PriorityTime()
+	}
+
+	id, err := insertReturningID(db, `
 		INSERT INTO orders (type, category, stock_symbol, quantity, 
 		                   filled_quantity, price, status, user_id, 
+		                   client_order_id, post_only, peg_type,
+		                   peg_offset, peg_cap, stop_price, trail_amount,
+		                   trail_percent, parent_order_id, group_id,
+		                   min_quantity, all_or_none, hidden, priority_at,
 		                   created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`,
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
 		order.Type, order.Category, order.StockSymbol,
 		order.Quantity, order.FilledQuantity, order.Price,
-		order.Status, order.UserID)
-	if err != nil {
-		return err
-	}
-
-	id, err := result.LastInsertId()
+		order.Status, order.UserID, clientOrderID, order.PostOnly,
+		pegType, order.PegOffset, order.PegCap, order.StopPrice,
+		order.TrailAmount, order.TrailPercent, parentOrderID, groupID,
+		order.MinQuantity, order.AllOrNone, order.Hidden, order.PriorityAt)
 	if err != nil {
 		return err
 	}
@@ -114,73 +297,103 @@ func CreateOrder(db *sql.DB, order *Order) error {
 	return nil
 }
 
-// UpdateOrder updates an existing order in the database
-func UpdateOrder(db *sql.DB, order *Order) error {
+// UpdateOrder updates the prices, priority and fill state of an existing
+// order. A zero priority is left unchanged.
+func UpdateOrder(db DBTX, order *Order) error {
+	var priorityAt interface{}
+	if !order.PriorityAt.IsZero() {
+		priorityAt = order.PriorityAt
+	}
+
 	_, err := db.Exec(`
 		UPDATE orders 
-		SET filled_quantity = ?, status = ?, updated_at = NOW()
+		SET price = ?, stop_price = ?, filled_quantity = ?, status = ?,
+		    priority_at = COALESCE(?, priority_at),
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?`,
-		order.FilledQuantity, order.Status, order.ID)
+		order.Price, order.StopPrice, order.FilledQuantity, order.Status,
+		priorityAt, order.ID)
 	return err
 }
 
-// CreateTrade creates a new trade in the database
-func CreateTrade(db *sql.DB, trade *Trade) error {
-	_, err := db.Exec(`
+// CreateTrade creates a new trade in the database, executed now unless its
+// execution time is set
+func CreateTrade(db DBTX, trade *Trade) error {
+	if trade.ExecutedAt.IsZero() {
+		trade.ExecutedAt = ExecutionTime()
+	}
+	id, err := insertReturningID(db, `
 		INSERT INTO trades (buy_order_id, sell_order_id, stock_symbol,
-		                   quantity, price, executed_at)
-		VALUES (?, ?, ?, ?, ?, NOW())`,
+		                   quantity, price, hidden, negotiated,
+		                   executed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 		trade.BuyOrderID, trade.SellOrderID, trade.StockSymbol,
-		trade.Quantity, trade.Price)
-	return err
-}
-
-// GetTradeByID retrieves a trade by its ID with all associated data
-func GetTradeByID(db *sql.DB, id uint) (*Trade, error) {
-	trade := &Trade{}
-	err := db.QueryRow(`
-		SELECT id, buy_order_id, sell_order_id, stock_symbol,
-		       quantity, price, executed_at
-		FROM trades
-		WHERE id = ?`, id).Scan(
-		&trade.ID, &trade.BuyOrderID, &trade.SellOrderID,
-		&trade.StockSymbol, &trade.Quantity, &trade.Price,
-		&trade.ExecutedAt)
+		trade.Quantity, trade.Price, trade.Hidden, trade.Negotiated,
+		trade.ExecutedAt.UTC())
 	if err != nil {
-		return nil, err
+		return err
 	}
+	trade.ID = uint(id)
+	return nil
+}
 
-	// Load associated orders and stock
-	buyOrder, err := GetOrderByID(db, trade.BuyOrderID)
-	if err != nil {
-		return nil, err
+// insertReturningID executes an INSERT and returns the generated id. The
+// PostgreSQL driver does not support LastInsertId, so the id is read back
+// with RETURNING instead.
+func insertReturningID(db DBTX, query string, args ...interface{}) (int64, error) {
+	var id int64
+	if dialectOf(db) == DialectPostgres {
+		err := db.QueryRow(query+` RETURNING id`, args...).Scan(&id)
+		return id, err
 	}
-	trade.BuyOrder = buyOrder
 
-	sellOrder, err := GetOrderByID(db, trade.SellOrderID)
+	result, err := db.Exec(query, args...)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	trade.SellOrder = sellOrder
+	return result.LastInsertId()
+}
 
-	stock, err := GetStockBySymbol(db, trade.StockSymbol)
+// GetTradeByID retrieves a trade by its ID with all associated data
+func GetTradeByID(db DBTX, id uint) (*Trade, error) {
+	trades, err := queryTrades(db, `WHERE t.id = ?`, id)
 	if err != nil {
 		return nil, err
 	}
-	trade.Stock = stock
-
-	return trade, nil
+	if len(trades) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &trades[0], nil
 }
 
 // GetOrdersByUserID retrieves all orders for a specific user
-func GetOrdersByUserID(db *sql.DB, userID uint) ([]Order, error) {
+func GetOrdersByUserID(db DBTX, userID uint) ([]Order, error) {
+	return queryOrders(db, `WHERE o.user_id = ? ORDER BY o.created_at DESC, o.id DESC`, userID)
+}
+
+// GetOrdersByStock retrieves all orders for a specific stock
+func GetOrdersByStock(db DBTX, symbol StockSymbol) ([]Order, error) {
+	return queryOrders(db, `WHERE o.stock_symbol = ? ORDER BY o.created_at DESC, o.id DESC`, symbol)
+}
+
+// GetAllOrders retrieves all orders from the database
+func GetAllOrders(db DBTX) ([]Order, error) {
+	return queryOrders(db, `ORDER BY o.created_at DESC, o.id DESC`)
+}
+
+// GetAllTrades retrieves all trades from the database
+func GetAllTrades(db DBTX) ([]Trade, error) {
+	return queryTrades(db, `ORDER BY t.executed_at DESC, t.id DESC`)
+}
+
+// queryOrders loads orders together with their stocks in a single query.
+// clauses holds the WHERE, ORDER BY and LIMIT clauses of the query.
+func queryOrders(db DBTX, clauses string, args ...interface{}) ([]Order, error) {
 	rows, err := db.Query(`
-		SELECT o.id, o.type, o.category, o.stock_symbol, o.quantity, 
-		       o.filled_quantity, o.price, o.status, o.user_id, 
-		       o.created_at, o.updated_at
+		SELECT `+orderColumns("o")+`,`+stockColumns+`
 		FROM orders o
-		WHERE o.user_id = ?
-		ORDER BY o.created_at DESC`, userID)
+		JOIN stocks s ON s.symbol = o.stock_symbol
+		`+clauses, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -189,71 +402,119 @@ func GetOrdersByUserID(db *sql.DB, userID uint) ([]Order, error) {
 	var orders []Order
 	for rows.Next() {
 		var order Order
-		err := rows.Scan(
-			&order.ID, &order.Type, &order.Category, &order.StockSymbol,
-			&order.Quantity, &order.FilledQuantity, &order.Price,
-			&order.Status, &order.UserID, &order.CreatedAt, &order.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-
-		// Load associated stock
-		stock, err := GetStockBySymbol(db, order.StockSymbol)
-		if err != nil {
+		stock := &Stock{}
+		dest := append(orderFields(&order), stockFields(stock)...)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 		order.Stock = stock
-
 		orders = append(orders, order)
 	}
-	return orders, nil
+	return orders, rows.Err()
 }
 
-// GetOrdersByStock retrieves all orders for a specific stock
-func GetOrdersByStock(db *sql.DB, symbol StockSymbol) ([]Order, error) {
+// queryTrades loads trades together with both orders and the stock in a
+// single query. clauses holds the WHERE, ORDER BY and LIMIT clauses of the
+// query.
+func queryTrades(db DBTX, clauses string, args ...interface{}) ([]Trade, error) {
 	rows, err := db.Query(`
-		SELECT o.id, o.type, o.category, o.stock_symbol, o.quantity, 
-		       o.filled_quantity, o.price, o.status, o.user_id, 
-		       o.created_at, o.updated_at
-		FROM orders o
-		WHERE o.stock_symbol = ?
-		ORDER BY o.created_at DESC`, symbol)
+		SELECT t.id, t.buy_order_id, t.sell_order_id, t.stock_symbol,
+		       t.quantity, t.price, t.hidden, t.negotiated,
+		       COALESCE(tc.id, 0), t.executed_at,
+		       `+orderColumns("bo")+`,`+orderColumns("so")+`,`+stockColumns+`
+		FROM trades t
+		JOIN orders bo ON bo.id = t.buy_order_id
+		JOIN orders so ON so.id = t.sell_order_id
+		JOIN stocks s ON s.symbol = t.stock_symbol
+		LEFT JOIN trade_corrections tc ON tc.trade_id = t.id
+		`+clauses, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []Order
+	var trades []Trade
 	for rows.Next() {
-		var order Order
-		err := rows.Scan(
-			&order.ID, &order.Type, &order.Category, &order.StockSymbol,
-			&order.Quantity, &order.FilledQuantity, &order.Price,
-			&order.Status, &order.UserID, &order.CreatedAt, &order.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
+		var trade Trade
+		buyOrder := &Order{}
+		sellOrder := &Order{}
+		stock := &Stock{}
 
-		// Load associated stock
-		stock, err := GetStockBySymbol(db, order.StockSymbol)
-		if err != nil {
+		dest := []interface{}{
+			&trade.ID, &trade.BuyOrderID, &trade.SellOrderID,
+			&trade.StockSymbol, &trade.Quantity, &trade.Price,
+			&trade.Hidden, &trade.Negotiated, &trade.CorrectionID,
+			&trade.ExecutedAt,
+		}
+		dest = append(dest, orderFields(buyOrder)...)
+		dest = append(dest, orderFields(sellOrder)...)
+		dest = append(dest, stockFields(stock)...)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
-		order.Stock = stock
 
-		orders = append(orders, order)
+		// Both orders trade the same stock as the trade itself
+		buyOrder.Stock = stock
+		sellOrder.Stock = stock
+		trade.BuyOrder = buyOrder
+		trade.SellOrder = sellOrder
+		trade.Stock = stock
+		trades = append(trades, trade)
 	}
-	return orders, nil
+	return trades, rows.Err()
 }
 
-// GetAllOrders retrieves all orders from the database
-func GetAllOrders(db *sql.DB) ([]Order, error) {
-	rows, err := db.Query(`
-		SELECT o.id, o.type, o.category, o.stock_symbol, o.quantity, 
-		       o.filled_quantity, o.price, o.status, o.user_id, 
-		       o.created_at, o.updated_at
+// GetMatchingOrders retrieves the active opposite-side orders that can match
+// the given order, sorted by price-time priority
+func GetMatchingOrders(db DBTX, order *Order) ([]Order, error) {
+	var query string
+	var args []interface{}
+
+	// Base query with common conditions. Only limit and pegged orders rest
+	// in the book, and pegged orders held at price 0 until the book can
+	// price them are not matchable. Hidden orders rest in the dark book.
+	query = `
+		SELECT ` + orderColumns("o") + `
 		FROM orders o
-		ORDER BY o.created_at DESC`)
+		WHERE type = ?
+		  AND stock_symbol = ?
+		  AND status IN ('PENDING', 'PARTIALLY_FILLED')
+		  AND (category = 'LIMIT' OR (category = 'PEGGED' AND price > 0))
+		  AND hidden = FALSE`
+
+	if order.Type == OrderTypeBuy {
+		args = append(args, OrderTypeSell, order.StockSymbol)
+
+		// Add price condition for priced orders
+		if order.Category != OrderCategoryMarket {
+			query += ` AND price <= ?`
+			args = append(args, order.Price)
+		}
+
+		// Add order by clause for price-time priority
+		query += ` ORDER BY price ASC, priority_at ASC, id ASC`
+	} else {
+		args = append(args, OrderTypeBuy, order.StockSymbol)
+
+		// Add price condition for priced orders
+		if order.Category != OrderCategoryMarket {
+			query += ` AND price >= ?`
+			args = append(args, order.Price)
+		}
+
+		// Add order by clause for price-time priority
+		query += ` ORDER BY price DESC, priority_at ASC, id ASC`
+	}
+
+	// On PostgreSQL, lock the resting orders for the rest of the matching
+	// transaction. Rows already being filled by a concurrent matcher are
+	// skipped rather than waited on or filled twice.
+	if dialectOf(db) == DialectPostgres {
+		query += ` FOR UPDATE SKIP LOCKED`
+	}
+
+	// Execute query
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -262,69 +523,54 @@ func GetAllOrders(db *sql.DB) ([]Order, error) {
 	var orders []Order
 	for rows.Next() {
 		var order Order
-		err := rows.Scan(
-			&order.ID, &order.Type, &order.Category, &order.StockSymbol,
-			&order.Quantity, &order.FilledQuantity, &order.Price,
-			&order.Status, &order.UserID, &order.CreatedAt, &order.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-
-		// Load associated stock
-		stock, err := GetStockBySymbol(db, order.StockSymbol)
-		if err != nil {
+		if err := rows.Scan(orderFields(&order)...); err != nil {
 			return nil, err
 		}
-		order.Stock = stock
-
 		orders = append(orders, order)
 	}
 	return orders, nil
 }
 
-// GetAllTrades retrieves all trades from the database
-func GetAllTrades(db *sql.DB) ([]Trade, error) {
-	rows, err := db.Query(`
-		SELECT id, buy_order_id, sell_order_id, stock_symbol,
-		       quantity, price, executed_at
-		FROM trades
-		ORDER BY executed_at DESC`)
+// GetDarkOrders retrieves the active opposite-side hidden orders willing to
+// trade with the given order at the midpoint price, in time priority
+func GetDarkOrders(db DBTX, order *Order, midpoint float64) ([]Order, error) {
+	query := `
+		SELECT ` + orderColumns("o") + `
+		FROM orders o
+		WHERE type = ?
+		  AND stock_symbol = ?
+		  AND status IN ('PENDING', 'PARTIALLY_FILLED')
+		  AND category = 'LIMIT'
+		  AND hidden = TRUE`
+
+	// Hidden buys accept midpoints up to their price, sells down to it
+	args := []interface{}{OrderTypeBuy, order.StockSymbol, midpoint}
+	if order.Type == OrderTypeBuy {
+		args[0] = OrderTypeSell
+		query += ` AND price <= ?`
+	} else {
+		query += ` AND price >= ?`
+	}
+	query += ` ORDER BY priority_at ASC, id ASC`
+
+	// Lock the resting orders like GetMatchingOrders
+	if dialectOf(db) == DialectPostgres {
+		query += ` FOR UPDATE SKIP LOCKED`
+	}
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var trades []Trade
+	var orders []Order
 	for rows.Next() {
-		var trade Trade
-		err := rows.Scan(
-			&trade.ID, &trade.BuyOrderID, &trade.SellOrderID,
-			&trade.StockSymbol, &trade.Quantity, &trade.Price,
-			&trade.ExecutedAt)
-		if err != nil {
-			return nil, err
-		}
-
-		// Load associated orders and stock
-		buyOrder, err := GetOrderByID(db, trade.BuyOrderID)
-		if err != nil {
-			return nil, err
-		}
-		trade.BuyOrder = buyOrder
-
-		sellOrder, err := GetOrderByID(db, trade.SellOrderID)
-		if err != nil {
-			return nil, err
-		}
-		trade.SellOrder = sellOrder
-
-		stock, err := GetStockBySymbol(db, trade.StockSymbol)
-		if err != nil {
+		var order Order
+		if err := rows.Scan(orderFields(&order)...); err != nil {
 			return nil, err
 		}
-		trade.Stock = stock
-
-		trades = append(trades, trade)
+		orders = append(orders, order)
 	}
-	return trades, nil
+	return orders, rows.Err()
 }
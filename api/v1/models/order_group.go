@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// OrderGroupType is the kind of link between the orders of a group
+type OrderGroupType string
+
+const (
+	// OrderGroupOCO holds a take-profit limit order and a stop order; a
+	// fill on one cancels the other
+	OrderGroupOCO OrderGroupType = "OCO"
+
+	// OrderGroupBracket holds an entry order whose fills activate an OCO
+	// exit pair
+	OrderGroupBracket OrderGroupType = "BRACKET"
+)
+
+// OrderGroupStatus represents the status of an order group
+type OrderGroupStatus string
+
+const (
+	OrderGroupActive    OrderGroupStatus = "ACTIVE"
+	OrderGroupCompleted OrderGroupStatus = "COMPLETED"
+	OrderGroupCancelled OrderGroupStatus = "CANCELLED"
+)
+
+// OrderGroup links the orders of a user on one stock
+type OrderGroup struct {
+	ID              uint
+	Type            OrderGroupType
+	UserID          uint
+	StockSymbol     StockSymbol
+	Status          OrderGroupStatus
+	TakeProfitPrice float64 // Price of the take-profit exit of a bracket
+	StopPrice       float64 // Stop price of the stop exit of a bracket
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Orders          []Order // Oldest first
+}
+
+// CreateOrderGroup creates a new order group without its orders
+func CreateOrderGroup(db DBTX, group *OrderGroup) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO order_groups (type, user_id, stock_symbol, status,
+		                         take_profit_price, stop_price, created_at,
+		                         updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		group.Type, group.UserID, group.StockSymbol, group.Status,
+		group.TakeProfitPrice, group.StopPrice)
+	if err != nil {
+		return err
+	}
+	group.ID = uint(id)
+	return nil
+}
+
+// GetOrderGroupByID retrieves an order group by its ID without its orders
+func GetOrderGroupByID(db DBTX, id uint) (*OrderGroup, error) {
+	g := &OrderGroup{}
+	err := db.QueryRow(`
+		SELECT id, type, user_id, stock_symbol, status, take_profit_price,
+		       stop_price, created_at, updated_at
+		FROM order_groups
+		WHERE id = ?`, id).Scan(
+		&g.ID, &g.Type, &g.UserID, &g.StockSymbol, &g.Status,
+		&g.TakeProfitPrice, &g.StopPrice, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// UpdateOrderGroup updates the status of an order group
+func UpdateOrderGroup(db DBTX, group *OrderGroup) error {
+	_, err := db.Exec(`
+		UPDATE order_groups
+		SET status = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		group.Status, group.ID)
+	return err
+}
+
+// GetOrdersByGroupID retrieves the orders of a group, oldest first
+func GetOrdersByGroupID(db DBTX, groupID uint) ([]Order, error) {
+	return queryOrders(db, `WHERE o.group_id = ? ORDER BY o.id`, groupID)
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RFQStatus represents the status of a request for quote
+type RFQStatus string
+
+const (
+	RFQOpen      RFQStatus = "OPEN"
+	RFQAccepted  RFQStatus = "ACCEPTED"
+	RFQExpired   RFQStatus = "EXPIRED"
+	RFQCancelled RFQStatus = "CANCELLED"
+)
+
+// RFQEventType identifies a step in the history of a request for quote
+type RFQEventType string
+
+const (
+	RFQEventCreated   RFQEventType = "CREATED"
+	RFQEventQuoted    RFQEventType = "QUOTED"
+	RFQEventAccepted  RFQEventType = "ACCEPTED"
+	RFQEventExpired   RFQEventType = "EXPIRED"
+	RFQEventCancelled RFQEventType = "CANCELLED"
+)
+
+// RFQ is a request for quote: a user asks the market makers for quotes on
+// a block of a stock, too large for the book, and may accept one of them
+// until it expires
+type RFQ struct {
+	ID              uint
+	UserID          uint
+	StockSymbol     StockSymbol
+	Quantity        uint
+	Status          RFQStatus
+	ExpiresAt       time.Time
+	AcceptedQuoteID uint // Quote the requester accepted, 0 if none
+	TradeID         uint // Negotiated trade of the accepted quote, 0 if none
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Quotes          []RFQQuote // Oldest first
+}
+
+// RFQQuote is a firm two-way quote of a market maker for the whole size of
+// an RFQ. A side that is not quoted has price 0.
+type RFQQuote struct {
+	ID        uint
+	RFQID     uint
+	UserID    uint
+	BidPrice  float64
+	AskPrice  float64
+	CreatedAt time.Time
+}
+
+// RFQEvent records a step in the history of an RFQ. UserID is 0 for steps
+// taken by the system, such as expiry.
+type RFQEvent struct {
+	ID        uint
+	RFQID     uint
+	Type      RFQEventType
+	UserID    uint
+	QuoteID   uint
+	TradeID   uint
+	CreatedAt time.Time
+}
+
+// rfqColumns are the RFQ columns selected by the query functions
+const rfqColumns = `
+		id, user_id, stock_symbol, quantity, status, expires_at,
+		COALESCE(accepted_quote_id, 0), COALESCE(trade_id, 0), created_at,
+		updated_at`
+
+// rfqFields returns the scan destinations matching rfqColumns
+func rfqFields(rfq *RFQ) []interface{} {
+	return []interface{}{
+		&rfq.ID, &rfq.UserID, &rfq.StockSymbol, &rfq.Quantity, &rfq.Status,
+		&rfq.ExpiresAt, &rfq.AcceptedQuoteID, &rfq.TradeID, &rfq.CreatedAt,
+		&rfq.UpdatedAt,
+	}
+}
+
+// nullID returns nil for a zero id, stored as NULL
+func nullID(id uint) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// CreateRFQ creates a new RFQ without quotes
+func CreateRFQ(db DBTX, rfq *RFQ) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO rfqs (user_id, stock_symbol, quantity, status,
+		                  expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		rfq.UserID, rfq.StockSymbol, rfq.Quantity, rfq.Status,
+		rfq.ExpiresAt.UTC())
+	if err != nil {
+		return err
+	}
+	rfq.ID = uint(id)
+	return nil
+}
+
+// GetRFQByID retrieves an RFQ by its ID without its quotes
+func GetRFQByID(db DBTX, id uint) (*RFQ, error) {
+	rfqs, err := queryRFQs(db, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rfqs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &rfqs[0], nil
+}
+
+// GetRFQsByUserID retrieves the RFQs of a user, newest first
+func GetRFQsByUserID(db DBTX, userID uint) ([]RFQ, error) {
+	return queryRFQs(db, `WHERE user_id = ? ORDER BY id DESC`, userID)
+}
+
+// GetOpenRFQs retrieves the open RFQs, oldest first, including those past
+// their expiry that have not been expired yet
+func GetOpenRFQs(db DBTX) ([]RFQ, error) {
+	return queryRFQs(db, `WHERE status = ? ORDER BY id`, RFQOpen)
+}
+
+// UpdateRFQ updates the status, accepted quote and trade of an RFQ
+func UpdateRFQ(db DBTX, rfq *RFQ) error {
+	_, err := db.Exec(`
+		UPDATE rfqs
+		SET status = ?, accepted_quote_id = ?, trade_id = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		rfq.Status, nullID(rfq.AcceptedQuoteID), nullID(rfq.TradeID), rfq.ID)
+	return err
+}
+
+// queryRFQs loads RFQs. clauses holds the WHERE and ORDER BY clauses of the
+// query.
+func queryRFQs(db DBTX, clauses string, args ...interface{}) ([]RFQ, error) {
+	rows, err := db.Query(`SELECT `+rfqColumns+` FROM rfqs `+clauses, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rfqs []RFQ
+	for rows.Next() {
+		var rfq RFQ
+		if err := rows.Scan(rfqFields(&rfq)...); err != nil {
+			return nil, err
+		}
+		rfqs = append(rfqs, rfq)
+	}
+	return rfqs, rows.Err()
+}
+
+// CreateRFQQuote creates a new quote on an RFQ
+func CreateRFQQuote(db DBTX, quote *RFQQuote) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO rfq_quotes (rfq_id, user_id, bid_price, ask_price,
+		                        created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		quote.RFQID, quote.UserID, quote.BidPrice, quote.AskPrice)
+	if err != nil {
+		return err
+	}
+	quote.ID = uint(id)
+	return nil
+}
+
+// GetRFQQuotes retrieves the quotes of an RFQ, oldest first
+func GetRFQQuotes(db DBTX, rfqID uint) ([]RFQQuote, error) {
+	rows, err := db.Query(`
+		SELECT id, rfq_id, user_id, bid_price, ask_price, created_at
+		FROM rfq_quotes
+		WHERE rfq_id = ?
+		ORDER BY id`, rfqID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []RFQQuote
+	for rows.Next() {
+		var q RFQQuote
+		if err := rows.Scan(&q.ID, &q.RFQID, &q.UserID, &q.BidPrice,
+			&q.AskPrice, &q.CreatedAt); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
+
+// CreateRFQEvent records a step in the history of an RFQ
+func CreateRFQEvent(db DBTX, event *RFQEvent) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO rfq_events (rfq_id, type, user_id, quote_id, trade_id,
+		                        created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		event.RFQID, event.Type, nullID(event.UserID),
+		nullID(event.QuoteID), nullID(event.TradeID))
+	if err != nil {
+		return err
+	}
+	event.ID = uint(id)
+	return nil
+}
+
+// GetRFQEvents retrieves the history of an RFQ, oldest first
+func GetRFQEvents(db DBTX, rfqID uint) ([]RFQEvent, error) {
+	rows, err := db.Query(`
+		SELECT id, rfq_id, type, COALESCE(user_id, 0), COALESCE(quote_id, 0),
+		       COALESCE(trade_id, 0), created_at
+		FROM rfq_events
+		WHERE rfq_id = ?
+		ORDER BY id`, rfqID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []RFQEvent
+	for rows.Next() {
+		var e RFQEvent
+		if err := rows.Scan(&e.ID, &e.RFQID, &e.Type, &e.UserID, &e.QuoteID,
+			&e.TradeID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// sessionDateLayout formats session dates for comparison
+const sessionDateLayout = "2006-01-02"
+
+// SessionDay returns the trading day containing t. Trading days follow UTC.
+func SessionDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// RollSession moves the stock into the trading session of day when it is
+// still in an earlier one: the last price becomes the previous close and the
+// intraday statistics are reset. It reports whether the stock rolled.
+func (s *Stock) RollSession(day time.Time) bool {
+	if s.SessionDate.Format(sessionDateLayout) >= day.Format(sessionDateLayout) {
+		return false
+	}
+
+	s.PreviousClose = s.CurrentPrice
+	s.ResetSession()
+	s.SessionDate = SessionDay(day)
+	return true
+}
+
+// ResetSession clears the statistics of the current session back to the
+// previous close, so that its trades can be applied again
+func (s *Stock) ResetSession() {
+	s.CurrentPrice = s.PreviousClose
+	s.DayHigh = s.PreviousClose
+	s.DayLow = s.PreviousClose
+	s.Volume = 0
+	s.VWAP = 0
+	s.TradeCount = 0
+}
+
+// InSession reports whether t falls in the stock's current session
+func (s *Stock) InSession(t time.Time) bool {
+	return SessionDay(t).Format(sessionDateLayout) == s.SessionDate.Format(sessionDateLayout)
+}
+
+// RecordTrade applies an execution to the statistics of the current session
+func (s *Stock) RecordTrade(price float64, quantity uint) {
+	// The first trade of a session opens the day's range
+	if s.TradeCount == 0 || price > s.DayHigh {
+		s.DayHigh = price
+	}
+	if s.TradeCount == 0 || price < s.DayLow {
+		s.DayLow = price
+	}
+
+	volume := s.Volume + int64(quantity)
+	s.VWAP = (s.VWAP*float64(s.Volume) + price*float64(quantity)) / float64(volume)
+	s.Volume = volume
+	s.TradeCount++
+	s.CurrentPrice = price
+}
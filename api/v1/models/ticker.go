@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Ticker summarizes the market in a stock: the top of its book, its last
+// trade and its trading over the last 24 hours. Prices and sizes are zero
+// when there is no such order or trade.
+type Ticker struct {
+	Symbol      StockSymbol
+	BestBid     float64
+	BestBidSize uint
+	BestAsk     float64
+	BestAskSize uint
+
+	LastPrice    float64
+	LastQuantity uint
+	LastTradeAt  time.Time
+
+	// Statistics of the trades of the last 24 hours. The change is measured
+	// against the first of them.
+	Open24h          float64
+	Change24h        float64
+	ChangePercent24h float64
+	High24h          float64
+	Low24h           float64
+	Volume24h        int64
+	VWAP24h          float64
+}
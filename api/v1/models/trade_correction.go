@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// TradeCorrectionType identifies how a trade was corrected
+type TradeCorrectionType string
+
+const (
+	// TradeBust cancels a trade outright
+	TradeBust TradeCorrectionType = "BUST"
+
+	// TradeCorrect replaces a trade with one at a corrected price and
+	// quantity
+	TradeCorrect TradeCorrectionType = "CORRECT"
+)
+
+// TradeCorrection records the bust or correction of a trade by an operator.
+// The trade itself is left as executed; a correction links it to the
+// replacement trade booked at the corrected price and quantity.
+type TradeCorrection struct {
+	ID         uint
+	TradeID    uint
+	Type       TradeCorrectionType
+	NewTradeID uint    // Replacement trade, 0 for a bust
+	Price      float64 // Corrected price, 0 for a bust
+	Quantity   uint    // Corrected quantity, 0 for a bust
+	Reason     string
+	UserID     uint // Operator who made the correction
+	CreatedAt  time.Time
+}
+
+// CreateCorrectedTrade books the replacement of a corrected trade at the
+// price and quantity of trade. The replacement keeps the orders, flags and
+// execution time of the original.
+func CreateCorrectedTrade(db DBTX, trade *Trade, originalID uint) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO trades (buy_order_id, sell_order_id, stock_symbol,
+		                   quantity, price, hidden, negotiated,
+		                   executed_at)
+		SELECT buy_order_id, sell_order_id, stock_symbol, ?, ?, hidden,
+		       negotiated, executed_at
+		FROM trades
+		WHERE id = ?`,
+		trade.Quantity, trade.Price, originalID)
+	if err != nil {
+		return err
+	}
+	trade.ID = uint(id)
+	return nil
+}
+
+// CreateTradeCorrection records the correction of a trade
+func CreateTradeCorrection(db DBTX, correction *TradeCorrection) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO trade_corrections (trade_id, type, new_trade_id, price,
+		                               quantity, reason, user_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		correction.TradeID, correction.Type, nullID(correction.NewTradeID),
+		correction.Price, correction.Quantity, correction.Reason,
+		correction.UserID)
+	if err != nil {
+		return err
+	}
+	correction.ID = uint(id)
+	return nil
+}
+
+// GetTradeCorrection retrieves the correction of a trade
+func GetTradeCorrection(db DBTX, tradeID uint) (*TradeCorrection, error) {
+	var c TradeCorrection
+	err := db.QueryRow(`
+		SELECT id, trade_id, type, COALESCE(new_trade_id, 0), price, quantity,
+		       reason, user_id, created_at
+		FROM trade_corrections
+		WHERE trade_id = ?`, tradeID).Scan(
+		&c.ID, &c.TradeID, &c.Type, &c.NewTradeID, &c.Price, &c.Quantity,
+		&c.Reason, &c.UserID, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -8,12 +8,37 @@ const (
 	OrderTypeSell OrderType = "SELL"
 )
 
-// OrderCategory represents the category of order (LIMIT/MARKET)
+// OrderCategory represents the category of order
 type OrderCategory string
 
 const (
 	OrderCategoryLimit  OrderCategory = "LIMIT"
 	OrderCategoryMarket OrderCategory = "MARKET"
+
+	// OrderCategoryPegged is a limit order whose price follows the book
+	OrderCategoryPegged OrderCategory = "PEGGED"
+
+	// OrderCategoryStop enters a child order when a trade prints at or
+	// through its stop price
+	OrderCategoryStop OrderCategory = "STOP"
+
+	// OrderCategoryTrailingStop is a stop order whose stop price follows
+	// the last trade price
+	OrderCategoryTrailingStop OrderCategory = "TRAILING_STOP"
+)
+
+// PegType is the reference price of a pegged order
+type PegType string
+
+const (
+	// PegPrimary pegs to the best price on the order's own side
+	PegPrimary PegType = "PRIMARY"
+
+	// PegMidpoint pegs to the midpoint of the best bid and ask
+	PegMidpoint PegType = "MIDPOINT"
+
+	// PegMarket pegs to the best price on the opposite side
+	PegMarket PegType = "MARKET"
 )
 
 // OrderStatus represents the status of an order
@@ -24,20 +49,47 @@ const (
 	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
 	OrderStatusMatched         OrderStatus = "MATCHED"
 	OrderStatusCancelled       OrderStatus = "CANCELLED"
+
+	// OrderStatusTriggered is the final status of a stop order that
+	// entered its child order
+	OrderStatusTriggered OrderStatus = "TRIGGERED"
 )
 
-// StockSymbol represents valid stock symbols
+// StockSymbol identifies a stock. The set of tradable symbols is defined by
+// the stocks table, not by the code.
 type StockSymbol string
 
+// StockStatus represents the listing status of a stock
+type StockStatus string
+
+const (
+	StockStatusActive   StockStatus = "ACTIVE"
+	StockStatusDelisted StockStatus = "DELISTED"
+)
+
+// AllocationAlgorithm determines how an incoming order is split among the
+// resting orders of a price level
+type AllocationAlgorithm string
+
+const (
+	// AllocationFIFO fills resting orders in time priority
+	AllocationFIFO AllocationAlgorithm = "FIFO"
+
+	// AllocationProRata fills resting orders in proportion to their size
+	AllocationProRata AllocationAlgorithm = "PRO_RATA"
+
+	// AllocationTopOrderProRata fills the oldest order of the best price
+	// level first and the rest pro-rata
+	AllocationTopOrderProRata AllocationAlgorithm = "TOP_ORDER_PRO_RATA"
+)
+
+// UserRole determines what a user is allowed to do
+type UserRole string
+
 const (
-	StockNXTECH StockSymbol = "NXTECH"
-	StockQNTUM  StockSymbol = "QNTUM"
-	StockCYBEX  StockSymbol = "CYBEX"
-	StockSOLRX  StockSymbol = "SOLRX"
-	StockFUSON  StockSymbol = "FUSON"
-	StockGENUM  StockSymbol = "GENUM"
-	StockMEDIX  StockSymbol = "MEDIX"
-	StockAITHN  StockSymbol = "AITHN"
-	StockNRLNK  StockSymbol = "NRLNK"
-	StockCOGNT  StockSymbol = "COGNT"
+	UserRoleTrader      UserRole = "TRADER"
+	UserRoleReadOnly    UserRole = "READ_ONLY"
+	UserRoleMarketMaker UserRole = "MARKET_MAKER"
+	UserRoleOperator    UserRole = "OPERATOR"
+	UserRoleAdmin       UserRole = "ADMIN"
 )
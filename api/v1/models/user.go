@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// User is an account that trades through the API
+type User struct {
+	ID        uint
+	Username  string
+	Role      UserRole
+	CreatedAt time.Time
+}
+
+// APIKey is a credential of a user. Only the hash of the key is stored.
+type APIKey struct {
+	ID        uint
+	UserID    uint
+	Name      string
+	KeyHash   string
+	Revoked   bool
+	CreatedAt time.Time
+	User      *User
+}
+
+// CreateUser creates a new user
+func CreateUser(db DBTX, user *User) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO users (username, role, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)`,
+		user.Username, user.Role)
+	if err != nil {
+		return err
+	}
+	user.ID = uint(id)
+	return nil
+}
+
+// GetUserByID retrieves a user by its ID
+func GetUserByID(db DBTX, id uint) (*User, error) {
+	user := &User{}
+	err := db.QueryRow(`
+		SELECT id, username, role, created_at
+		FROM users
+		WHERE id = ?`, id).Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserByUsername retrieves a user by its username
+func GetUserByUsername(db DBTX, username string) (*User, error) {
+	user := &User{}
+	err := db.QueryRow(`
+		SELECT id, username, role, created_at
+		FROM users
+		WHERE username = ?`, username).Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateAPIKey stores a new API key
+func CreateAPIKey(db DBTX, key *APIKey) error {
+	id, err := insertReturningID(db, `
+		INSERT INTO api_keys (user_id, name, key_hash, revoked, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		key.UserID, key.Name, key.KeyHash, key.Revoked)
+	if err != nil {
+		return err
+	}
+	key.ID = uint(id)
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the unrevoked API key with the given hash
+// together with its owner
+func GetAPIKeyByHash(db DBTX, keyHash string) (*APIKey, error) {
+	key := &APIKey{User: &User{}}
+	err := db.QueryRow(`
+		SELECT k.id, k.user_id, k.name, k.key_hash, k.revoked, k.created_at,
+		       u.id, u.username, u.role, u.created_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = ? AND k.revoked = ?`, keyHash, false).Scan(
+		&key.ID, &key.UserID, &key.Name, &key.KeyHash, &key.Revoked,
+		&key.CreatedAt, &key.User.ID, &key.User.Username, &key.User.Role,
+		&key.User.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
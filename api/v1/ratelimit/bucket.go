@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"math"
+	"order-matching/api/v1/config"
+	"time"
+)
+
+// bucket is a token bucket. It starts full and is refilled lazily.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newBucket creates a full bucket for limit
+func newBucket(limit config.Limit, now time.Time) *bucket {
+	return &bucket{tokens: float64(limit.Burst), last: now}
+}
+
+// refill adds the tokens accrued since the last refill
+func (b *bucket) refill(limit config.Limit, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	}
+	b.last = now
+}
+
+// wait returns how long until the bucket holds cost tokens, zero if it
+// already does. The bucket must be refilled first.
+func (b *bucket) wait(limit config.Limit, cost float64) time.Duration {
+	if b.tokens >= cost {
+		return 0
+	}
+	return time.Duration((cost - b.tokens) / limit.Rate * float64(time.Second))
+}
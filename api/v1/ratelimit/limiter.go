@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/config"
+	"sync"
+	"time"
+)
+
+// idleTimeout is how long a client's buckets are kept without requests
+const idleTimeout = 10 * time.Minute
+
+// Limiter applies the token bucket limits of each role to every API key and
+// to every user across all their keys
+type Limiter struct {
+	cfg     *config.RateLimitConfig
+	monitor *Monitor
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastPrune time.Time
+}
+
+// bucketKey identifies the bucket of a client for a request class
+type bucketKey struct {
+	apiKey bool // Whether id is an API key ID rather than a user ID
+	id     uint
+	class  string
+}
+
+// NewLimiter creates a limiter that also feeds and enforces monitor
+func NewLimiter(cfg *config.RateLimitConfig, monitor *Monitor) *Limiter {
+	return &Limiter{cfg: cfg, monitor: monitor, buckets: make(map[bucketKey]*bucket)}
+}
+
+// Limit returns a handler that runs next only if the client has tokens left
+// for class, and 429 with a Retry-After header otherwise. Orders and
+// cancels count towards the message-to-trade ratio. It must be used behind
+// auth.Middleware.
+func (l *Limiter) Limit(class string, next http.Handler) http.Handler {
+	return l.LimitN(class, nil, next)
+}
+
+// LimitN is Limit for requests carrying several messages, such as batches.
+// count returns the number of messages of a request, each of which costs a
+// token; a nil count means one. A request costing more than the burst could
+// never pass and is rejected outright.
+func (l *Limiter) LimitN(class string, count func(r *http.Request) int, next http.Handler) http.Handler {
+	if !l.cfg.Enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.UserFromContext(r.Context())
+		key, _ := auth.APIKeyFromContext(r.Context())
+		if !ok || key == nil {
+			http.Error(w, "Missing API key", http.StatusUnauthorized)
+			return
+		}
+
+		n := 1
+		if count != nil {
+			n = max(count(r), 1)
+		}
+
+		limit := l.cfg.Limits[user.Role][class]
+		if limit.Rate != 0 && n > limit.Burst {
+			http.Error(w, fmt.Sprintf("Request holds %d messages, more than the burst limit of %d", n, limit.Burst), http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		cost := float64(n)
+		if until, penalized := l.monitor.Penalty(user.ID, now); penalized {
+			if l.cfg.PenaltyAction == "block" {
+				tooManyRequests(w, until.Sub(now), "Message-to-trade ratio exceeded")
+				return
+			}
+			cost *= l.cfg.ThrottleCost
+			if limit.Rate != 0 && cost > float64(limit.Burst) {
+				// Only passes once the penalty is over
+				tooManyRequests(w, until.Sub(now), "Message-to-trade ratio exceeded")
+				return
+			}
+		}
+
+		if wait := l.take(limit, cost, now, bucketKey{true, key.ID, class}, bucketKey{false, user.ID, class}); wait > 0 {
+			tooManyRequests(w, wait, "Rate limit exceeded")
+			return
+		}
+
+		if class == config.ClassOrders || class == config.ClassCancels {
+			l.monitor.RecordMessages(user.ID, n, now)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take removes cost tokens from every bucket of keys if all of them hold
+// enough, and otherwise returns how long to wait until they do
+func (l *Limiter) take(limit config.Limit, cost float64, now time.Time, keys ...bucketKey) time.Duration {
+	if limit.Rate == 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) >= idleTimeout {
+		l.prune(now)
+	}
+
+	var wait time.Duration
+	buckets := make([]*bucket, len(keys))
+	for i, key := range keys {
+		b, ok := l.buckets[key]
+		if !ok {
+			b = newBucket(limit, now)
+			l.buckets[key] = b
+		}
+		b.refill(limit, now)
+		if w := b.wait(limit, cost); w > wait {
+			wait = w
+		}
+		buckets[i] = b
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	for _, b := range buckets {
+		b.tokens -= cost
+	}
+	return 0
+}
+
+// prune drops the buckets and monitor state of idle clients
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.monitor.prune(now)
+	l.lastPrune = now
+}
+
+// tooManyRequests rejects a request that may be retried after wait
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", fmt.Sprint(seconds))
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"order-matching/api/v1/config"
+	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/utils/logger"
+	"sync"
+	"time"
+)
+
+// Monitor tracks the message-to-trade ratio of every user: the orders and
+// cancels they send against the fills they receive. A user exceeding the
+// configured ratio is penalized for a while.
+type Monitor struct {
+	cfg   *config.RateLimitConfig
+	mu    sync.Mutex
+	users map[uint]*activity
+}
+
+// activity is the traffic of a user over the current window
+type activity struct {
+	windowStart  time.Time
+	messages     int
+	trades       int
+	penaltyUntil time.Time
+}
+
+// NewMonitor creates a monitor enforcing the ratio of cfg
+func NewMonitor(cfg *config.RateLimitConfig) *Monitor {
+	return &Monitor{cfg: cfg, users: make(map[uint]*activity)}
+}
+
+// Apply counts the fills of a matcher event
+func (m *Monitor) Apply(event order_matcher.Event) {
+	if event.Type != order_matcher.EventTrade || event.Trade.BuyOrder == nil || event.Trade.SellOrder == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.activity(event.Trade.BuyOrder.UserID, now).trades++
+	if event.Trade.SellOrder.UserID != event.Trade.BuyOrder.UserID {
+		m.activity(event.Trade.SellOrder.UserID, now).trades++
+	}
+}
+
+// RecordMessages counts n orders or cancels sent by a user and penalizes
+// the user once the ratio is exceeded
+func (m *Monitor) RecordMessages(userID uint, n int, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.activity(userID, now)
+	a.messages += n
+	if now.Before(a.penaltyUntil) || a.messages < m.cfg.MinMessages {
+		return
+	}
+
+	trades := max(a.trades, 1)
+	if float64(a.messages) <= m.cfg.MaxMessageRatio*float64(trades) {
+		return
+	}
+
+	a.penaltyUntil = now.Add(m.cfg.PenaltyDuration)
+	logger.LogWithFields(logger.InfoLevel, "Message-to-trade ratio exceeded", map[string]interface{}{
+		"user_id":  userID,
+		"messages": a.messages,
+		"trades":   a.trades,
+		"action":   m.cfg.PenaltyAction,
+		"until":    a.penaltyUntil.Format(time.RFC3339),
+	})
+}
+
+// Penalty returns the end of the penalty of a user, if one is in force
+func (m *Monitor) Penalty(userID uint, now time.Time) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.users[userID]
+	if !ok || !now.Before(a.penaltyUntil) {
+		return time.Time{}, false
+	}
+	return a.penaltyUntil, true
+}
+
+// activity returns the activity of a user, starting a new window when the
+// current one has ended
+func (m *Monitor) activity(userID uint, now time.Time) *activity {
+	a, ok := m.users[userID]
+	if !ok {
+		a = &activity{windowStart: now}
+		m.users[userID] = a
+	}
+	if now.Sub(a.windowStart) >= m.cfg.MessageWindow {
+		a.windowStart, a.messages, a.trades = now, 0, 0
+	}
+	return a
+}
+
+// prune drops the users with neither recent traffic nor a penalty
+func (m *Monitor) prune(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, a := range m.users {
+		if now.Sub(a.windowStart) >= m.cfg.MessageWindow && !now.Before(a.penaltyUntil) {
+			delete(m.users, id)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"order-matching/api/v1/database"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The conformance suite runs every case against each backend: the in-memory
+// repository, SQLite on a temporary file, and MySQL and PostgreSQL when
+// TEST_MYSQL_DSN and TEST_POSTGRES_DSN are set. The SQL schemas are rolled
+// back and migrated again before each case, so those DSNs must point to
+// disposable databases.
+
+// backend creates an empty repository listing the seeded stocks
+type backend func(t *testing.T) repository.Repository
+
+func backends() map[string]backend {
+	b := map[string]backend{
+		"memory": func(t *testing.T) repository.Repository {
+			return repository.NewMemory(models.Stock{Symbol: "COGNT", CurrentPrice: 100})
+		},
+		"sqlite": func(t *testing.T) repository.Repository {
+			path := filepath.Join(t.TempDir(), "conformance.db")
+			return openSQL(t, database.DriverSQLite, "file:"+path+"?_pragma=foreign_keys(1)")
+		},
+	}
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		b["mysql"] = func(t *testing.T) repository.Repository {
+			return openSQL(t, database.DriverMySQL, dsn)
+		}
+	}
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		b["postgres"] = func(t *testing.T) repository.Repository {
+			return openSQL(t, database.DriverPostgres, dsn)
+		}
+	}
+	return b
+}
+
+// openSQL opens a database and migrates it from scratch
+func openSQL(t *testing.T, driver, dsn string) repository.Repository {
+	t.Helper()
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if driver == database.DriverSQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	migrator, err := database.NewMigrator(db, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(1 << 20); err != nil {
+		t.Fatalf("failed to reset schema: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return repository.NewSQL(db, models.Dialect(driver))
+}
+
+// conformance runs a case against every backend
+func conformance(t *testing.T, test func(t *testing.T, repo repository.Repository)) {
+	for name, newRepo := range backends() {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepo(t))
+		})
+	}
+}
+
+// newOrder creates a pending limit order of COGNT
+func newOrder(t *testing.T, repo repository.Repository, side models.OrderType, quantity uint, price float64, userID uint) *models.Order {
+	t.Helper()
+	order := &models.Order{
+		Type:        side,
+		Category:    models.OrderCategoryLimit,
+		StockSymbol: "COGNT",
+		Quantity:    quantity,
+		Price:       price,
+		Status:      models.OrderStatusPending,
+		UserID:      userID,
+	}
+	if err := repo.CreateOrder(order); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestConformanceStocks(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		stock, err := repo.GetStockBySymbol("COGNT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stock.Status != models.StockStatusActive {
+			t.Fatalf("status = %s, want ACTIVE", stock.Status)
+		}
+
+		stock.CurrentPrice = 123.45
+		stock.Volume = 77
+		stock.TradeCount = 3
+		if err := repo.UpdateStockStats(stock); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := repo.GetStockBySymbol("COGNT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.CurrentPrice != 123.45 || stored.Volume != 77 || stored.TradeCount != 3 {
+			t.Fatalf("stats = %v %d %d, want 123.45 77 3", stored.CurrentPrice, stored.Volume, stored.TradeCount)
+		}
+
+		if _, err := repo.GetStockBySymbol("NOPE"); err == nil {
+			t.Fatal("unknown stock found")
+		}
+	})
+}
+
+func TestConformanceOrders(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		order := newOrder(t, repo, models.OrderTypeBuy, 10, 99.5, 1)
+		if order.ID == 0 {
+			t.Fatal("order id not set")
+		}
+
+		order.FilledQuantity = 4
+		order.Status = models.OrderStatusPartiallyFilled
+		if err := repo.UpdateOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := repo.GetOrderByID(order.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.FilledQuantity != 4 || stored.Status != models.OrderStatusPartiallyFilled || stored.Price != 99.5 {
+			t.Fatalf("order = %+v", stored)
+		}
+		if stored.Stock == nil || stored.Stock.Symbol != "COGNT" {
+			t.Fatal("stock not attached")
+		}
+
+		withID := &models.Order{Type: models.OrderTypeSell, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 1, Price: 1, Status: models.OrderStatusPending, UserID: 1, ClientOrderID: "abc-1"}
+		if err := repo.CreateOrder(withID); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.GetOrderByClientID(1, "abc-1")
+		if err != nil || found.ID != withID.ID {
+			t.Fatalf("GetOrderByClientID = %v, %v", found, err)
+		}
+		if _, err := repo.GetOrderByClientID(2, "abc-1"); err != sql.ErrNoRows {
+			t.Fatalf("client order id of another user: err = %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.GetOrderByID(999999); err != sql.ErrNoRows {
+			t.Fatalf("unknown order: err = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestConformanceMatchingOrders(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		first := newOrder(t, repo, models.OrderTypeSell, 10, 101, 1)
+		best := newOrder(t, repo, models.OrderTypeSell, 10, 100, 1)
+		second := newOrder(t, repo, models.OrderTypeSell, 10, 101, 1)
+		newOrder(t, repo, models.OrderTypeSell, 10, 105, 1)
+		newOrder(t, repo, models.OrderTypeBuy, 10, 99, 1)
+
+		buy := &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 30, Price: 101}
+		matching, err := repo.GetMatchingOrders(buy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []uint
+		for _, o := range matching {
+			ids = append(ids, o.ID)
+		}
+		want := []uint{best.ID, first.ID, second.ID}
+		if len(ids) != len(want) {
+			t.Fatalf("matching = %v, want %v", ids, want)
+		}
+		for i := range want {
+			if ids[i] != want[i] {
+				t.Fatalf("matching = %v, want %v", ids, want)
+			}
+		}
+
+		// A new priority time queues an order behind its price level
+		first.PriorityAt = models.PriorityTime().Add(time.Second)
+		if err := repo.UpdateOrder(first); err != nil {
+			t.Fatal(err)
+		}
+		matching, err = repo.GetMatchingOrders(buy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matching) != 3 || matching[1].ID != second.ID || matching[2].ID != first.ID {
+			t.Fatalf("matching after priority reset = %v", matching)
+		}
+		if !matching[2].PriorityAt.Equal(first.PriorityAt) {
+			t.Fatalf("priority = %v, want %v", matching[2].PriorityAt, first.PriorityAt)
+		}
+	})
+}
+
+func TestConformanceDarkOrders(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		var hidden []*models.Order
+		for _, price := range []float64{100, 99} {
+			order := &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 100, Price: price, Status: models.OrderStatusPending, UserID: 1, Hidden: true}
+			if err := repo.CreateOrder(order); err != nil {
+				t.Fatal(err)
+			}
+			hidden = append(hidden, order)
+		}
+		newOrder(t, repo, models.OrderTypeBuy, 100, 101, 1)
+
+		// Hidden orders queue by priority time regardless of price
+		sell := &models.Order{Type: models.OrderTypeSell, StockSymbol: "COGNT"}
+		dark, err := repo.GetDarkOrders(sell, 98.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dark) != 2 || dark[0].ID != hidden[0].ID || dark[1].ID != hidden[1].ID {
+			t.Fatalf("dark orders = %v", dark)
+		}
+
+		hidden[0].PriorityAt = models.PriorityTime().Add(time.Second)
+		if err := repo.UpdateOrder(hidden[0]); err != nil {
+			t.Fatal(err)
+		}
+		dark, err = repo.GetDarkOrders(sell, 98.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dark) != 2 || dark[0].ID != hidden[1].ID || dark[1].ID != hidden[0].ID {
+			t.Fatalf("dark orders after priority reset = %v", dark)
+		}
+
+		// Only orders accepting the midpoint are returned
+		if dark, err = repo.GetDarkOrders(sell, 99.5); err != nil || len(dark) != 1 || dark[0].ID != hidden[0].ID {
+			t.Fatalf("dark orders at 99.5 = %v, %v", dark, err)
+		}
+	})
+}
+
+func TestConformanceTrades(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		sell := newOrder(t, repo, models.OrderTypeSell, 10, 100, 1)
+		buy := newOrder(t, repo, models.OrderTypeBuy, 10, 100, 2)
+		executedAt := time.Date(2026, 3, 4, 23, 59, 59, 999999000, time.UTC)
+		trade := &models.Trade{BuyOrderID: buy.ID, SellOrderID: sell.ID, StockSymbol: "COGNT", Quantity: 10, Price: 100, ExecutedAt: executedAt}
+		if err := repo.CreateTrade(trade); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := repo.GetTradeByID(trade.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.ExecutedAt.Equal(executedAt) {
+			t.Fatalf("executed at %v, want %v", stored.ExecutedAt, executedAt)
+		}
+		if stored.BuyOrder == nil || stored.BuyOrder.UserID != 2 || stored.SellOrder == nil || stored.SellOrder.UserID != 1 {
+			t.Fatal("orders not attached")
+		}
+
+		page, err := repo.ListTrades(models.TradeFilter{UserID: 2, Side: models.OrderTypeBuy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Trades) != 1 || page.Trades[0].ID != trade.ID {
+			t.Fatalf("trades bought by user 2 = %+v", page.Trades)
+		}
+		page, err = repo.ListTrades(models.TradeFilter{UserID: 1, Side: models.OrderTypeBuy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Trades) != 0 {
+			t.Fatalf("trades bought by user 1 = %d, want 0", len(page.Trades))
+		}
+
+		// A corrected trade keeps its row and links to its replacement
+		replacement := &models.Trade{Quantity: 8, Price: 99}
+		if err := repo.CreateCorrectedTrade(replacement, trade.ID); err != nil {
+			t.Fatal(err)
+		}
+		correction := &models.TradeCorrection{TradeID: trade.ID, Type: models.TradeCorrect, NewTradeID: replacement.ID, Price: 99, Quantity: 8, Reason: "test", UserID: 3}
+		if err := repo.CreateTradeCorrection(correction); err != nil {
+			t.Fatal(err)
+		}
+		original, err := repo.GetTradeByID(trade.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if original.CorrectionID != correction.ID || original.Quantity != 10 || original.Price != 100 {
+			t.Fatalf("original = %+v", original)
+		}
+		fixed, err := repo.GetTradeByID(replacement.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fixed.Quantity != 8 || fixed.Price != 99 || fixed.BuyOrderID != buy.ID || !fixed.ExecutedAt.Equal(original.ExecutedAt) {
+			t.Fatalf("replacement = %+v", fixed)
+		}
+	})
+}
+
+func TestConformanceTransact(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		failed := errors.New("failed")
+		var dropped models.Order
+		err := repo.Transact(func(tx repository.Repository) error {
+			dropped = models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 1, Price: 1, Status: models.OrderStatusPending, UserID: 1}
+			if err := tx.CreateOrder(&dropped); err != nil {
+				return err
+			}
+			return failed
+		})
+		if err != failed {
+			t.Fatalf("Transact = %v, want %v", err, failed)
+		}
+		if _, err := repo.GetOrderByID(dropped.ID); err != sql.ErrNoRows {
+			t.Fatalf("rolled back order: err = %v, want sql.ErrNoRows", err)
+		}
+
+		var kept models.Order
+		err = repo.Transact(func(tx repository.Repository) error {
+			kept = models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 1, Price: 1, Status: models.OrderStatusPending, UserID: 1}
+			return tx.CreateOrder(&kept)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetOrderByID(kept.ID); err != nil {
+			t.Fatalf("committed order: %v", err)
+		}
+	})
+}
+
+func TestConformanceCandles(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		open := models.CandleInterval1m.OpenTime(time.Now())
+		candle := &models.Candle{StockSymbol: "COGNT", Interval: models.CandleInterval1m, OpenTime: open, Open: 1, High: 3, Low: 1, Close: 2, Volume: 10, TradeCount: 2}
+		if err := repo.SaveCandle(candle); err != nil {
+			t.Fatal(err)
+		}
+		candle.Close, candle.Volume = 3, 15
+		if err := repo.SaveCandle(candle); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := repo.GetCandle("COGNT", models.CandleInterval1m, open)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Close != 3 || stored.Volume != 15 {
+			t.Fatalf("candle = %+v, want close 3 volume 15", stored)
+		}
+
+		next := &models.Candle{StockSymbol: "COGNT", Interval: models.CandleInterval1m, OpenTime: open.Add(time.Minute), Open: 2, High: 2, Low: 2, Close: 2, Volume: 5, TradeCount: 1}
+		if err := repo.SaveCandle(next); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.DeleteCandle("COGNT", models.CandleInterval1m, next.OpenTime); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetCandle("COGNT", models.CandleInterval1m, next.OpenTime); err != sql.ErrNoRows {
+			t.Fatalf("deleted candle: err = %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.GetCandle("COGNT", models.CandleInterval1m, open); err != nil {
+			t.Fatalf("other candle deleted: %v", err)
+		}
+
+		if err := repo.DeleteCandles("COGNT"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetCandle("COGNT", models.CandleInterval1m, open); err != sql.ErrNoRows {
+			t.Fatalf("deleted candle: err = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestConformanceUsers(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		user := &models.User{Username: "conformance", Role: models.UserRoleTrader}
+		if err := repo.CreateUser(user); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.CreateUser(&models.User{Username: "conformance", Role: models.UserRoleTrader}); err == nil {
+			t.Fatal("duplicate username accepted")
+		}
+
+		key := &models.APIKey{UserID: user.ID, Name: "default", KeyHash: "hash"}
+		if err := repo.CreateAPIKey(key); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := repo.GetAPIKeyByHash("hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.UserID != user.ID || stored.User == nil || stored.User.Username != "conformance" {
+			t.Fatalf("key = %+v", stored)
+		}
+	})
+}
+
+func TestConformanceIdempotencyKeys(t *testing.T) {
+	conformance(t, func(t *testing.T, repo repository.Repository) {
+		user := &models.User{Username: "conformance", Role: models.UserRoleTrader}
+		if err := repo.CreateUser(user); err != nil {
+			t.Fatal(err)
+		}
+
+		// Keys are expired by the clock they were stamped with
+		now := time.Now().UTC()
+		old := &models.IdempotencyKey{UserID: user.ID, Key: "old", RequestHash: "hash", CreatedAt: now.Add(-25 * time.Hour).Truncate(time.Second)}
+		fresh := &models.IdempotencyKey{UserID: user.ID, Key: "fresh", RequestHash: "hash"}
+		for _, key := range []*models.IdempotencyKey{old, fresh} {
+			if err := repo.CreateIdempotencyKey(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.CreateIdempotencyKey(&models.IdempotencyKey{UserID: user.ID, Key: "old", RequestHash: "hash"}); err == nil {
+			t.Fatal("duplicate key accepted")
+		}
+		stored, err := repo.GetIdempotencyKey(user.ID, "old")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.CreatedAt.Equal(old.CreatedAt) {
+			t.Fatalf("created at = %v, want %v", stored.CreatedAt, old.CreatedAt)
+		}
+
+		if err := repo.DeleteIdempotencyKeysBefore(now.Add(-24 * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetIdempotencyKey(user.ID, "old"); err != sql.ErrNoRows {
+			t.Fatalf("expired key: err = %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.GetIdempotencyKey(user.ID, "fresh"); err != nil {
+			t.Fatalf("fresh key: %v", err)
+		}
+	})
+}
@@ -0,0 +1,1152 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"order-matching/api/v1/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryData holds the records of an in-memory repository
+type memoryData struct {
+	stocks           map[models.StockSymbol]models.Stock
+	orders           map[uint]models.Order
+	groups           map[uint]models.OrderGroup
+	rfqs             map[uint]models.RFQ
+	quotes           map[uint]models.RFQQuote
+	rfqEvents        []models.RFQEvent
+	trades           map[uint]models.Trade
+	corrections      map[uint]models.TradeCorrection // By trade id
+	candles          map[candleKey]models.Candle
+	users            map[uint]models.User
+	apiKeys          map[uint]models.APIKey
+	audit            []models.AuditEntry
+	idempotency      map[idempotencyKey]models.IdempotencyKey
+	nextOrderID      uint
+	nextGroupID      uint
+	nextRFQID        uint
+	nextQuoteID      uint
+	nextEventID      uint
+	nextTradeID      uint
+	nextCorrectionID uint
+	nextUserID       uint
+	nextKeyID        uint
+}
+
+// candleKey identifies a candle
+type candleKey struct {
+	symbol   models.StockSymbol
+	interval models.CandleInterval
+	openTime int64
+}
+
+// idempotencyKey identifies an idempotency key of a user
+type idempotencyKey struct {
+	userID uint
+	key    string
+}
+
+// keyOf returns the key of a candle
+func keyOf(c *models.Candle) candleKey {
+	return candleKey{c.StockSymbol, c.Interval, c.OpenTime.Unix()}
+}
+
+// memoryState is the shared, lock-protected state of an in-memory repository
+type memoryState struct {
+	mu   sync.RWMutex // guards data; held for writing through a transaction
+	data *memoryData
+}
+
+// memoryTx is the undo log of a transaction. Writes apply to the shared data
+// directly, and the log restores the map entries they replaced if the
+// transaction fails.
+type memoryTx struct {
+	undo []func()
+}
+
+// put sets m[k] to v, logging the previous entry in tx if not nil
+func put[K comparable, V any](tx *memoryTx, m map[K]V, k K, v V) {
+	if tx != nil {
+		old, existed := m[k]
+		tx.undo = append(tx.undo, func() {
+			if existed {
+				m[k] = old
+			} else {
+				delete(m, k)
+			}
+		})
+	}
+	m[k] = v
+}
+
+// remove deletes m[k], logging the previous entry in tx if not nil
+func remove[K comparable, V any](tx *memoryTx, m map[K]V, k K) {
+	if old, existed := m[k]; existed && tx != nil {
+		tx.undo = append(tx.undo, func() { m[k] = old })
+	}
+	delete(m, k)
+}
+
+// MemoryRepository implements Repository entirely in memory. It is intended
+// for tests and offline use; nothing is persisted.
+type MemoryRepository struct {
+	state *memoryState
+	tx    *memoryTx // Set inside a transaction
+}
+
+// NewMemory creates an empty in-memory repository seeded with the given stocks
+func NewMemory(stocks ...models.Stock) *MemoryRepository {
+	data := &memoryData{
+		stocks:           make(map[models.StockSymbol]models.Stock),
+		orders:           make(map[uint]models.Order),
+		groups:           make(map[uint]models.OrderGroup),
+		rfqs:             make(map[uint]models.RFQ),
+		quotes:           make(map[uint]models.RFQQuote),
+		trades:           make(map[uint]models.Trade),
+		corrections:      make(map[uint]models.TradeCorrection),
+		candles:          make(map[candleKey]models.Candle),
+		users:            make(map[uint]models.User),
+		apiKeys:          make(map[uint]models.APIKey),
+		idempotency:      make(map[idempotencyKey]models.IdempotencyKey),
+		nextOrderID:      1,
+		nextGroupID:      1,
+		nextRFQID:        1,
+		nextQuoteID:      1,
+		nextEventID:      1,
+		nextTradeID:      1,
+		nextCorrectionID: 1,
+		nextUserID:       1,
+		nextKeyID:        1,
+	}
+	for _, stock := range stocks {
+		if stock.Status == "" {
+			stock.Status = models.StockStatusActive
+		}
+		if stock.Allocation == "" {
+			stock.Allocation = models.AllocationFIFO
+		}
+		data.stocks[stock.Symbol] = stock
+	}
+	return &MemoryRepository{state: &memoryState{data: data}}
+}
+
+// read runs fn with shared access to the data. A transaction already holds
+// exclusive access.
+func (r *MemoryRepository) read(fn func(d *memoryData) error) error {
+	if r.tx == nil {
+		r.state.mu.RLock()
+		defer r.state.mu.RUnlock()
+	}
+	return fn(r.state.data)
+}
+
+// write runs fn with exclusive access to the data. A transaction already
+// holds it.
+func (r *MemoryRepository) write(fn func(d *memoryData) error) error {
+	if r.tx == nil {
+		r.state.mu.Lock()
+		defer r.state.mu.Unlock()
+	}
+	return fn(r.state.data)
+}
+
+// loadOrder returns a copy of the order with its stock attached
+func (d *memoryData) loadOrder(id uint) (*models.Order, error) {
+	order, ok := d.orders[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stock, ok := d.stocks[order.StockSymbol]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	order.Stock = &stock
+	return &order, nil
+}
+
+// loadTrade returns a copy of the trade with its orders and stock attached
+func (d *memoryData) loadTrade(id uint) (*models.Trade, error) {
+	trade, ok := d.trades[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	buyOrder, err := d.loadOrder(trade.BuyOrderID)
+	if err != nil {
+		return nil, err
+	}
+	trade.BuyOrder = buyOrder
+
+	sellOrder, err := d.loadOrder(trade.SellOrderID)
+	if err != nil {
+		return nil, err
+	}
+	trade.SellOrder = sellOrder
+
+	stock, ok := d.stocks[trade.StockSymbol]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	trade.Stock = &stock
+
+	if c, ok := d.corrections[id]; ok {
+		trade.CorrectionID = c.ID
+	}
+	return &trade, nil
+}
+
+// listOrders returns the orders accepted by keep, newest first
+func (d *memoryData) listOrders(keep func(o *models.Order) bool) ([]models.Order, error) {
+	var orders []models.Order
+	for id, o := range d.orders {
+		if !keep(&o) {
+			continue
+		}
+		order, err := d.loadOrder(id)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].ID > orders[j].ID
+	})
+	return orders, nil
+}
+
+// GetStockBySymbol retrieves a stock by its symbol
+func (r *MemoryRepository) GetStockBySymbol(symbol models.StockSymbol) (*models.Stock, error) {
+	var stock *models.Stock
+	err := r.read(func(d *memoryData) error {
+		s, ok := d.stocks[symbol]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		stock = &s
+		return nil
+	})
+	return stock, err
+}
+
+// GetAllStocks retrieves all stocks ordered by symbol
+func (r *MemoryRepository) GetAllStocks() ([]models.Stock, error) {
+	var stocks []models.Stock
+	err := r.read(func(d *memoryData) error {
+		for _, stock := range d.stocks {
+			stocks = append(stocks, stock)
+		}
+		return nil
+	})
+	sort.Slice(stocks, func(i, j int) bool {
+		return stocks[i].Symbol < stocks[j].Symbol
+	})
+	return stocks, err
+}
+
+// CreateStock lists a new stock
+func (r *MemoryRepository) CreateStock(stock *models.Stock) error {
+	return r.write(func(d *memoryData) error {
+		if _, ok := d.stocks[stock.Symbol]; ok {
+			return fmt.Errorf("stock %s already exists", stock.Symbol)
+		}
+		stock.LastUpdated = time.Now()
+		stock.SessionDate = models.SessionDay(stock.LastUpdated)
+		put(r.tx, d.stocks, stock.Symbol, *stock)
+		return nil
+	})
+}
+
+// UpdateStock updates an existing stock
+func (r *MemoryRepository) UpdateStock(stock *models.Stock) error {
+	return r.write(func(d *memoryData) error {
+		if _, ok := d.stocks[stock.Symbol]; !ok {
+			return nil
+		}
+		stock.LastUpdated = time.Now()
+		put(r.tx, d.stocks, stock.Symbol, *stock)
+		return nil
+	})
+}
+
+// UpdateStockStats updates the trading statistics of a stock
+func (r *MemoryRepository) UpdateStockStats(stock *models.Stock) error {
+	return r.write(func(d *memoryData) error {
+		stored, ok := d.stocks[stock.Symbol]
+		if !ok {
+			return nil
+		}
+		stored.CurrentPrice = stock.CurrentPrice
+		stored.DayHigh = stock.DayHigh
+		stored.DayLow = stock.DayLow
+		stored.Volume = stock.Volume
+		stored.PreviousClose = stock.PreviousClose
+		stored.VWAP = stock.VWAP
+		stored.TradeCount = stock.TradeCount
+		stored.SessionDate = stock.SessionDate
+		stored.LastUpdated = time.Now()
+		put(r.tx, d.stocks, stock.Symbol, stored)
+		return nil
+	})
+}
+
+// GetOrderByID retrieves an order by its ID
+func (r *MemoryRepository) GetOrderByID(id uint) (*models.Order, error) {
+	var order *models.Order
+	err := r.read(func(d *memoryData) error {
+		var err error
+		order, err = d.loadOrder(id)
+		return err
+	})
+	return order, err
+}
+
+// GetOrderByClientID retrieves the order of a user by client order id
+func (r *MemoryRepository) GetOrderByClientID(userID uint, clientOrderID string) (*models.Order, error) {
+	var order *models.Order
+	err := r.read(func(d *memoryData) error {
+		for _, o := range d.orders {
+			if o.UserID == userID && o.ClientOrderID == clientOrderID && clientOrderID != "" {
+				var err error
+				order, err = d.loadOrder(o.ID)
+				return err
+			}
+		}
+		return sql.ErrNoRows
+	})
+	return order, err
+}
+
+// CreateOrder creates a new order
+func (r *MemoryRepository) CreateOrder(order *models.Order) error {
+	return r.write(func(d *memoryData) error {
+		if order.ClientOrderID != "" {
+			for _, o := range d.orders {
+				if o.UserID == order.UserID && o.ClientOrderID == order.ClientOrderID {
+					return fmt.Errorf("duplicate client order id %s", order.ClientOrderID)
+				}
+			}
+		}
+
+		now := time.Now()
+		order.ID = d.nextOrderID
+		order.CreatedAt = now
+		order.UpdatedAt = now
+		if order.PriorityAt.IsZero() {
+			order.PriorityAt = models.PriorityTime()
+		}
+		d.nextOrderID++
+
+		stored := *order
+		stored.Stock = nil
+		put(r.tx, d.orders, order.ID, stored)
+		return nil
+	})
+}
+
+// UpdateOrder updates the price, priority and fill state of an existing
+// order. A zero priority is left unchanged.
+func (r *MemoryRepository) UpdateOrder(order *models.Order) error {
+	return r.write(func(d *memoryData) error {
+		stored, ok := d.orders[order.ID]
+		if !ok {
+			return nil
+		}
+		if !order.PriorityAt.IsZero() {
+			stored.PriorityAt = order.PriorityAt
+		}
+		stored.Price = order.Price
+		stored.StopPrice = order.StopPrice
+		stored.FilledQuantity = order.FilledQuantity
+		stored.Status = order.Status
+		stored.UpdatedAt = time.Now()
+		put(r.tx, d.orders, order.ID, stored)
+		return nil
+	})
+}
+
+// CreateOrderGroup creates a new order group
+func (r *MemoryRepository) CreateOrderGroup(group *models.OrderGroup) error {
+	return r.write(func(d *memoryData) error {
+		now := time.Now()
+		group.ID = d.nextGroupID
+		group.CreatedAt = now
+		group.UpdatedAt = now
+		d.nextGroupID++
+
+		stored := *group
+		stored.Orders = nil
+		put(r.tx, d.groups, group.ID, stored)
+		return nil
+	})
+}
+
+// GetOrderGroupByID retrieves an order group with its orders
+func (r *MemoryRepository) GetOrderGroupByID(id uint) (*models.OrderGroup, error) {
+	var group models.OrderGroup
+	err := r.read(func(d *memoryData) error {
+		var ok bool
+		if group, ok = d.groups[id]; !ok {
+			return sql.ErrNoRows
+		}
+		orders, err := d.listOrders(func(o *models.Order) bool {
+			return o.GroupID == id
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+		group.Orders = orders
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// UpdateOrderGroup updates the status of an order group
+func (r *MemoryRepository) UpdateOrderGroup(group *models.OrderGroup) error {
+	return r.write(func(d *memoryData) error {
+		stored, ok := d.groups[group.ID]
+		if !ok {
+			return nil
+		}
+		stored.Status = group.Status
+		stored.UpdatedAt = time.Now()
+		put(r.tx, d.groups, group.ID, stored)
+		return nil
+	})
+}
+
+// CreateRFQ creates a new request for quote
+func (r *MemoryRepository) CreateRFQ(rfq *models.RFQ) error {
+	return r.write(func(d *memoryData) error {
+		now := time.Now()
+		rfq.ID = d.nextRFQID
+		rfq.CreatedAt = now
+		rfq.UpdatedAt = now
+		d.nextRFQID++
+
+		stored := *rfq
+		stored.Quotes = nil
+		put(r.tx, d.rfqs, rfq.ID, stored)
+		return nil
+	})
+}
+
+// GetRFQByID retrieves an RFQ with its quotes
+func (r *MemoryRepository) GetRFQByID(id uint) (*models.RFQ, error) {
+	var rfq models.RFQ
+	err := r.read(func(d *memoryData) error {
+		var ok bool
+		if rfq, ok = d.rfqs[id]; !ok {
+			return sql.ErrNoRows
+		}
+		for _, q := range d.quotes {
+			if q.RFQID == id {
+				rfq.Quotes = append(rfq.Quotes, q)
+			}
+		}
+		sort.Slice(rfq.Quotes, func(i, j int) bool { return rfq.Quotes[i].ID < rfq.Quotes[j].ID })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rfq, nil
+}
+
+// GetRFQsByUserID retrieves the RFQs of a user, newest first
+func (r *MemoryRepository) GetRFQsByUserID(userID uint) ([]models.RFQ, error) {
+	return r.listRFQs(func(rfq *models.RFQ) bool { return rfq.UserID == userID }, true)
+}
+
+// GetOpenRFQs retrieves the open RFQs, oldest first
+func (r *MemoryRepository) GetOpenRFQs() ([]models.RFQ, error) {
+	return r.listRFQs(func(rfq *models.RFQ) bool { return rfq.Status == models.RFQOpen }, false)
+}
+
+// listRFQs returns the RFQs matching keep sorted by id
+func (r *MemoryRepository) listRFQs(keep func(rfq *models.RFQ) bool, newestFirst bool) ([]models.RFQ, error) {
+	var rfqs []models.RFQ
+	err := r.read(func(d *memoryData) error {
+		for _, rfq := range d.rfqs {
+			if keep(&rfq) {
+				rfqs = append(rfqs, rfq)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rfqs, func(i, j int) bool { return (rfqs[i].ID < rfqs[j].ID) != newestFirst })
+	return rfqs, nil
+}
+
+// UpdateRFQ updates the status, accepted quote and trade of an RFQ
+func (r *MemoryRepository) UpdateRFQ(rfq *models.RFQ) error {
+	return r.write(func(d *memoryData) error {
+		stored, ok := d.rfqs[rfq.ID]
+		if !ok {
+			return nil
+		}
+		stored.Status = rfq.Status
+		stored.AcceptedQuoteID = rfq.AcceptedQuoteID
+		stored.TradeID = rfq.TradeID
+		stored.UpdatedAt = time.Now()
+		put(r.tx, d.rfqs, rfq.ID, stored)
+		return nil
+	})
+}
+
+// CreateRFQQuote creates a new quote on an RFQ, one per market maker
+func (r *MemoryRepository) CreateRFQQuote(quote *models.RFQQuote) error {
+	return r.write(func(d *memoryData) error {
+		for _, q := range d.quotes {
+			if q.RFQID == quote.RFQID && q.UserID == quote.UserID {
+				return fmt.Errorf("duplicate quote of user %d on RFQ %d", quote.UserID, quote.RFQID)
+			}
+		}
+		quote.ID = d.nextQuoteID
+		quote.CreatedAt = time.Now()
+		d.nextQuoteID++
+		put(r.tx, d.quotes, quote.ID, *quote)
+		return nil
+	})
+}
+
+// CreateRFQEvent records a step in the history of an RFQ
+func (r *MemoryRepository) CreateRFQEvent(event *models.RFQEvent) error {
+	return r.write(func(d *memoryData) error {
+		event.ID = d.nextEventID
+		event.CreatedAt = time.Now()
+		d.nextEventID++
+		d.rfqEvents = append(d.rfqEvents, *event)
+		return nil
+	})
+}
+
+// GetRFQEvents retrieves the history of an RFQ, oldest first
+func (r *MemoryRepository) GetRFQEvents(rfqID uint) ([]models.RFQEvent, error) {
+	var events []models.RFQEvent
+	err := r.read(func(d *memoryData) error {
+		for _, e := range d.rfqEvents {
+			if e.RFQID == rfqID {
+				events = append(events, e)
+			}
+		}
+		return nil
+	})
+	return events, err
+}
+
+// GetOrdersByUserID retrieves all orders for a specific user
+func (r *MemoryRepository) GetOrdersByUserID(userID uint) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(func(d *memoryData) error {
+		var err error
+		orders, err = d.listOrders(func(o *models.Order) bool {
+			return o.UserID == userID
+		})
+		return err
+	})
+	return orders, err
+}
+
+// GetOrdersByStock retrieves all orders for a specific stock
+func (r *MemoryRepository) GetOrdersByStock(symbol models.StockSymbol) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(func(d *memoryData) error {
+		var err error
+		orders, err = d.listOrders(func(o *models.Order) bool {
+			return o.StockSymbol == symbol
+		})
+		return err
+	})
+	return orders, err
+}
+
+// GetAllOrders retrieves all orders
+func (r *MemoryRepository) GetAllOrders() ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(func(d *memoryData) error {
+		var err error
+		orders, err = d.listOrders(func(o *models.Order) bool {
+			return true
+		})
+		return err
+	})
+	return orders, err
+}
+
+// ListOrders retrieves a page of orders matching the filter
+func (r *MemoryRepository) ListOrders(filter models.OrderFilter) (*models.OrderPage, error) {
+	filter.Normalize()
+
+	var orders []models.Order
+	err := r.read(func(d *memoryData) error {
+		var err error
+		orders, err = d.listOrders(func(o *models.Order) bool {
+			return filter.Matches(o)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return filter.Less(orders[i].Price, orders[i].ID, orders[j].Price, orders[j].ID)
+	})
+
+	page := &models.OrderPage{}
+	for _, order := range orders {
+		ok, err := filter.Follows(order.Price, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if len(page.Orders) == filter.Limit {
+			last := page.Orders[len(page.Orders)-1]
+			page.NextCursor = filter.NextCursor(last.Price, last.ID)
+			break
+		}
+		page.Orders = append(page.Orders, order)
+	}
+	return page, nil
+}
+
+// GetMatchingOrders retrieves the orders that can match the given order
+func (r *MemoryRepository) GetMatchingOrders(order *models.Order) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(func(d *memoryData) error {
+		for _, o := range d.orders {
+			if o.Type == order.Type || o.StockSymbol != order.StockSymbol {
+				continue
+			}
+			if o.Status != models.OrderStatusPending && o.Status != models.OrderStatusPartiallyFilled {
+				continue
+			}
+
+			// Only limit and pegged orders rest in the book, and pegged
+			// orders the book cannot price yet are held. Hidden orders
+			// rest in the dark book.
+			if o.Hidden {
+				continue
+			}
+			switch o.Category {
+			case models.OrderCategoryLimit:
+			case models.OrderCategoryPegged:
+				if o.Price <= 0 {
+					continue
+				}
+			default:
+				continue
+			}
+
+			// Price condition for priced orders
+			if order.Category != models.OrderCategoryMarket {
+				if order.Type == models.OrderTypeBuy && o.Price > order.Price {
+					continue
+				}
+				if order.Type == models.OrderTypeSell && o.Price < order.Price {
+					continue
+				}
+			}
+			orders = append(orders, o)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Price-time priority
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].Price != orders[j].Price {
+			if order.Type == models.OrderTypeBuy {
+				return orders[i].Price < orders[j].Price
+			}
+			return orders[i].Price > orders[j].Price
+		}
+		if !orders[i].PriorityAt.Equal(orders[j].PriorityAt) {
+			return orders[i].PriorityAt.Before(orders[j].PriorityAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+	return orders, nil
+}
+
+// GetDarkOrders retrieves the hidden orders that can match the given order
+// at the midpoint
+func (r *MemoryRepository) GetDarkOrders(order *models.Order, midpoint float64) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.read(func(d *memoryData) error {
+		for _, o := range d.orders {
+			if o.Type == order.Type || o.StockSymbol != order.StockSymbol || !o.Hidden {
+				continue
+			}
+			if o.Status != models.OrderStatusPending && o.Status != models.OrderStatusPartiallyFilled {
+				continue
+			}
+			if o.Category != models.OrderCategoryLimit {
+				continue
+			}
+
+			// Hidden buys accept midpoints up to their price, sells down
+			// to it
+			if (o.Type == models.OrderTypeBuy && o.Price < midpoint) || (o.Type == models.OrderTypeSell && o.Price > midpoint) {
+				continue
+			}
+			orders = append(orders, o)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Time priority
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].PriorityAt.Equal(orders[j].PriorityAt) {
+			return orders[i].PriorityAt.Before(orders[j].PriorityAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+	return orders, nil
+}
+
+// CreateTrade creates a new trade
+func (r *MemoryRepository) CreateTrade(trade *models.Trade) error {
+	return r.write(func(d *memoryData) error {
+		trade.ID = d.nextTradeID
+		if trade.ExecutedAt.IsZero() {
+			trade.ExecutedAt = models.ExecutionTime()
+		}
+		d.nextTradeID++
+
+		stored := *trade
+		stored.BuyOrder = nil
+		stored.SellOrder = nil
+		stored.Stock = nil
+		put(r.tx, d.trades, trade.ID, stored)
+		return nil
+	})
+}
+
+// GetTradeByID retrieves a trade by its ID
+func (r *MemoryRepository) GetTradeByID(id uint) (*models.Trade, error) {
+	var trade *models.Trade
+	err := r.read(func(d *memoryData) error {
+		var err error
+		trade, err = d.loadTrade(id)
+		return err
+	})
+	return trade, err
+}
+
+// GetAllTrades retrieves all trades, newest first
+func (r *MemoryRepository) GetAllTrades() ([]models.Trade, error) {
+	var trades []models.Trade
+	err := r.read(func(d *memoryData) error {
+		for id := range d.trades {
+			trade, err := d.loadTrade(id)
+			if err != nil {
+				return err
+			}
+			trades = append(trades, *trade)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(trades, func(i, j int) bool {
+		if !trades[i].ExecutedAt.Equal(trades[j].ExecutedAt) {
+			return trades[i].ExecutedAt.After(trades[j].ExecutedAt)
+		}
+		return trades[i].ID > trades[j].ID
+	})
+	return trades, nil
+}
+
+// ListTrades retrieves a page of trades matching the filter
+func (r *MemoryRepository) ListTrades(filter models.TradeFilter) (*models.TradePage, error) {
+	filter.Normalize()
+
+	trades, err := r.GetAllTrades()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(trades, func(i, j int) bool {
+		return filter.Less(trades[i].Price, trades[i].ID, trades[j].Price, trades[j].ID)
+	})
+
+	page := &models.TradePage{}
+	for _, trade := range trades {
+		if !filter.Matches(&trade) {
+			continue
+		}
+		ok, err := filter.Follows(trade.Price, trade.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if len(page.Trades) == filter.Limit {
+			last := page.Trades[len(page.Trades)-1]
+			page.NextCursor = filter.NextCursor(last.Price, last.ID)
+			break
+		}
+		page.Trades = append(page.Trades, trade)
+	}
+	return page, nil
+}
+
+// CreateCorrectedTrade books the replacement of a corrected trade
+func (r *MemoryRepository) CreateCorrectedTrade(trade *models.Trade, originalID uint) error {
+	return r.write(func(d *memoryData) error {
+		original, ok := d.trades[originalID]
+		if !ok {
+			return sql.ErrNoRows
+		}
+
+		stored := original
+		stored.ID = d.nextTradeID
+		stored.Quantity = trade.Quantity
+		stored.Price = trade.Price
+		d.nextTradeID++
+		put(r.tx, d.trades, stored.ID, stored)
+
+		trade.ID = stored.ID
+		return nil
+	})
+}
+
+// CreateTradeCorrection records the correction of a trade
+func (r *MemoryRepository) CreateTradeCorrection(correction *models.TradeCorrection) error {
+	return r.write(func(d *memoryData) error {
+		if _, ok := d.trades[correction.TradeID]; !ok {
+			return fmt.Errorf("trade %d not found", correction.TradeID)
+		}
+		if _, ok := d.corrections[correction.TradeID]; ok {
+			return fmt.Errorf("trade %d is already corrected", correction.TradeID)
+		}
+
+		correction.ID = d.nextCorrectionID
+		correction.CreatedAt = time.Now()
+		d.nextCorrectionID++
+		put(r.tx, d.corrections, correction.TradeID, *correction)
+		return nil
+	})
+}
+
+// GetTradeCorrection retrieves the correction of a trade
+func (r *MemoryRepository) GetTradeCorrection(tradeID uint) (*models.TradeCorrection, error) {
+	var correction models.TradeCorrection
+	err := r.read(func(d *memoryData) error {
+		c, ok := d.corrections[tradeID]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		correction = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &correction, nil
+}
+
+// GetCandle retrieves the candle of a stock and interval opening at openTime
+func (r *MemoryRepository) GetCandle(symbol models.StockSymbol, interval models.CandleInterval, openTime time.Time) (*models.Candle, error) {
+	var candle models.Candle
+	err := r.read(func(d *memoryData) error {
+		c, ok := d.candles[candleKey{symbol, interval, openTime.Unix()}]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		candle = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &candle, nil
+}
+
+// SaveCandle inserts or replaces a candle
+func (r *MemoryRepository) SaveCandle(candle *models.Candle) error {
+	return r.write(func(d *memoryData) error {
+		put(r.tx, d.candles, keyOf(candle), *candle)
+		return nil
+	})
+}
+
+// ListCandles retrieves the latest candles matching the filter
+func (r *MemoryRepository) ListCandles(filter models.CandleFilter) ([]models.Candle, error) {
+	var candles []models.Candle
+	err := r.read(func(d *memoryData) error {
+		for _, c := range d.candles {
+			if c.StockSymbol != filter.Symbol || c.Interval != filter.Interval ||
+				(!filter.From.IsZero() && c.OpenTime.Before(filter.From)) ||
+				(!filter.To.IsZero() && !c.OpenTime.Before(filter.To)) {
+				continue
+			}
+			candles = append(candles, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].OpenTime.Before(candles[j].OpenTime)
+	})
+	if len(candles) > filter.Limit {
+		candles = candles[len(candles)-filter.Limit:]
+	}
+	return candles, nil
+}
+
+// DeleteCandle deletes the candle of a stock and interval opening at
+// openTime
+func (r *MemoryRepository) DeleteCandle(symbol models.StockSymbol, interval models.CandleInterval, openTime time.Time) error {
+	return r.write(func(d *memoryData) error {
+		remove(r.tx, d.candles, candleKey{symbol, interval, openTime.Unix()})
+		return nil
+	})
+}
+
+// DeleteCandles deletes all candles of a stock
+func (r *MemoryRepository) DeleteCandles(symbol models.StockSymbol) error {
+	return r.write(func(d *memoryData) error {
+		for k := range d.candles {
+			if k.symbol == symbol {
+				remove(r.tx, d.candles, k)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateUser creates a new user
+func (r *MemoryRepository) CreateUser(user *models.User) error {
+	return r.write(func(d *memoryData) error {
+		for _, u := range d.users {
+			if u.Username == user.Username {
+				return fmt.Errorf("user %s already exists", user.Username)
+			}
+		}
+		user.ID = d.nextUserID
+		user.CreatedAt = time.Now()
+		d.nextUserID++
+		put(r.tx, d.users, user.ID, *user)
+		return nil
+	})
+}
+
+// GetUserByID retrieves a user by its ID
+func (r *MemoryRepository) GetUserByID(id uint) (*models.User, error) {
+	var user models.User
+	err := r.read(func(d *memoryData) error {
+		u, ok := d.users[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		user = u
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByUsername retrieves a user by its username
+func (r *MemoryRepository) GetUserByUsername(username string) (*models.User, error) {
+	var user *models.User
+	err := r.read(func(d *memoryData) error {
+		for _, u := range d.users {
+			if u.Username == username {
+				user = &u
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	})
+	return user, err
+}
+
+// CreateAPIKey stores a new API key
+func (r *MemoryRepository) CreateAPIKey(key *models.APIKey) error {
+	return r.write(func(d *memoryData) error {
+		if _, ok := d.users[key.UserID]; !ok {
+			return fmt.Errorf("user %d does not exist", key.UserID)
+		}
+		for _, k := range d.apiKeys {
+			if k.KeyHash == key.KeyHash {
+				return fmt.Errorf("duplicate API key")
+			}
+		}
+		key.ID = d.nextKeyID
+		key.CreatedAt = time.Now()
+		d.nextKeyID++
+		put(r.tx, d.apiKeys, key.ID, *key)
+		return nil
+	})
+}
+
+// GetAPIKeyByHash retrieves an unrevoked API key with its owner
+func (r *MemoryRepository) GetAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.read(func(d *memoryData) error {
+		for _, k := range d.apiKeys {
+			if k.KeyHash == keyHash && !k.Revoked {
+				user, ok := d.users[k.UserID]
+				if !ok {
+					return sql.ErrNoRows
+				}
+				key = k
+				key.User = &user
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateIdempotencyKey records a request in progress
+func (r *MemoryRepository) CreateIdempotencyKey(key *models.IdempotencyKey) error {
+	return r.write(func(d *memoryData) error {
+		k := idempotencyKey{key.UserID, key.Key}
+		if _, ok := d.idempotency[k]; ok {
+			return fmt.Errorf("duplicate idempotency key %s", key.Key)
+		}
+		if key.CreatedAt.IsZero() {
+			key.CreatedAt = time.Now().UTC()
+		}
+		put(r.tx, d.idempotency, k, *key)
+		return nil
+	})
+}
+
+// GetIdempotencyKey retrieves an idempotency key of a user
+func (r *MemoryRepository) GetIdempotencyKey(userID uint, key string) (*models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	err := r.read(func(d *memoryData) error {
+		var ok bool
+		if k, ok = d.idempotency[idempotencyKey{userID, key}]; !ok {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// UpdateIdempotencyKey stores the response of a completed request
+func (r *MemoryRepository) UpdateIdempotencyKey(key *models.IdempotencyKey) error {
+	return r.write(func(d *memoryData) error {
+		k := idempotencyKey{key.UserID, key.Key}
+		stored, ok := d.idempotency[k]
+		if !ok {
+			return nil
+		}
+		stored.StatusCode = key.StatusCode
+		stored.ContentType = key.ContentType
+		stored.ResponseBody = key.ResponseBody
+		put(r.tx, d.idempotency, k, stored)
+		return nil
+	})
+}
+
+// DeleteIdempotencyKey deletes an idempotency key of a user
+func (r *MemoryRepository) DeleteIdempotencyKey(userID uint, key string) error {
+	return r.write(func(d *memoryData) error {
+		remove(r.tx, d.idempotency, idempotencyKey{userID, key})
+		return nil
+	})
+}
+
+// DeleteIdempotencyKeysBefore deletes the idempotency keys created before t
+func (r *MemoryRepository) DeleteIdempotencyKeysBefore(t time.Time) error {
+	return r.write(func(d *memoryData) error {
+		for k, v := range d.idempotency {
+			if v.CreatedAt.Before(t) {
+				remove(r.tx, d.idempotency, k)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateAuditEntry stores an authorization decision
+func (r *MemoryRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	return r.write(func(d *memoryData) error {
+		entry.ID = uint(len(d.audit) + 1)
+		entry.CreatedAt = time.Now()
+		d.audit = append(d.audit, *entry)
+		return nil
+	})
+}
+
+// ListAuditEntries retrieves the latest audit entries matching the filter
+func (r *MemoryRepository) ListAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := r.read(func(d *memoryData) error {
+		for i := len(d.audit) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+			e := d.audit[i]
+			if (filter.UserID != 0 && e.UserID != filter.UserID) || (filter.Denied && e.Allowed) {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Transact runs fn with exclusive access to the data and undoes its writes
+// if fn fails. Only the entries fn writes are logged, so a transaction costs
+// time proportional to its own writes.
+func (r *MemoryRepository) Transact(fn func(repo Repository) error) error {
+	// Already inside a transaction
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	// Counters and appended slices are restored from a shallow copy; the
+	// maps are shared and restored from the undo log
+	saved := *r.state.data
+	tx := &MemoryRepository{state: r.state, tx: &memoryTx{}}
+	if err := fn(tx); err != nil {
+		for i := len(tx.tx.undo) - 1; i >= 0; i-- {
+			tx.tx.undo[i]()
+		}
+		*r.state.data = saved
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"order-matching/api/v1/models"
+	"testing"
+)
+
+func TestMemoryTransactRollsBackWrites(t *testing.T) {
+	repo := NewMemory(models.Stock{Symbol: "COGNT", CurrentPrice: 100})
+
+	kept := &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 10, Price: 99, Status: models.OrderStatusPending}
+	if err := repo.CreateOrder(kept); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := repo.Transact(func(tx Repository) error {
+		kept.Quantity = 20
+		if err := tx.UpdateOrder(kept); err != nil {
+			return err
+		}
+		dropped := &models.Order{Type: models.OrderTypeSell, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 5, Price: 101, Status: models.OrderStatusPending}
+		if err := tx.CreateOrder(dropped); err != nil {
+			return err
+		}
+		if err := tx.CreateAuditEntry(&models.AuditEntry{}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("Transact returned %v, want %v", err, failed)
+	}
+
+	orders, err := repo.GetAllOrders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].Quantity != 10 {
+		t.Fatalf("orders after rollback = %+v, want only the original order", orders)
+	}
+	entries, err := repo.ListAuditEntries(models.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("audit entries after rollback = %d, want 0", len(entries))
+	}
+
+	// The id counter is restored along with the data
+	next := &models.Order{Type: models.OrderTypeSell, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 5, Price: 101, Status: models.OrderStatusPending}
+	if err := repo.CreateOrder(next); err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != kept.ID+1 {
+		t.Fatalf("next order id = %d, want %d", next.ID, kept.ID+1)
+	}
+}
+
+func TestMemoryTransactCommitsWrites(t *testing.T) {
+	repo := NewMemory(models.Stock{Symbol: "COGNT", CurrentPrice: 100})
+
+	var order models.Order
+	err := repo.Transact(func(tx Repository) error {
+		order = models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: 10, Price: 99, Status: models.OrderStatusPending}
+		return tx.CreateOrder(&order)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.GetOrderByID(order.ID)
+	if err != nil {
+		t.Fatalf("committed order not found: %v", err)
+	}
+	if stored.Quantity != 10 {
+		t.Fatalf("stored quantity = %d, want 10", stored.Quantity)
+	}
+}
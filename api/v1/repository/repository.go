@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"order-matching/api/v1/models"
+	"time"
+)
+
+// StockRepository provides access to stock reference data
+type StockRepository interface {
+	GetStockBySymbol(symbol models.StockSymbol) (*models.Stock, error)
+	GetAllStocks() ([]models.Stock, error)
+	CreateStock(stock *models.Stock) error
+	UpdateStock(stock *models.Stock) error
+
+	// UpdateStockStats writes only the trading statistics of a stock
+	UpdateStockStats(stock *models.Stock) error
+}
+
+// OrderRepository provides access to orders
+type OrderRepository interface {
+	GetOrderByID(id uint) (*models.Order, error)
+
+	// GetOrderByClientID returns the order of a user with the given client
+	// order id
+	GetOrderByClientID(userID uint, clientOrderID string) (*models.Order, error)
+
+	CreateOrder(order *models.Order) error
+	UpdateOrder(order *models.Order) error
+	GetOrdersByUserID(userID uint) ([]models.Order, error)
+	GetOrdersByStock(symbol models.StockSymbol) ([]models.Order, error)
+	GetAllOrders() ([]models.Order, error)
+
+	// ListOrders returns a page of orders matching the filter
+	ListOrders(filter models.OrderFilter) (*models.OrderPage, error)
+
+	// GetMatchingOrders returns the active opposite-side orders that can
+	// match the given order, sorted by price-time priority
+	GetMatchingOrders(order *models.Order) ([]models.Order, error)
+
+	// GetDarkOrders returns the active opposite-side hidden orders willing
+	// to trade with the given order at the midpoint, in time priority
+	GetDarkOrders(order *models.Order, midpoint float64) ([]models.Order, error)
+}
+
+// OrderGroupRepository provides access to order groups
+type OrderGroupRepository interface {
+	CreateOrderGroup(group *models.OrderGroup) error
+
+	// GetOrderGroupByID returns a group with its orders
+	GetOrderGroupByID(id uint) (*models.OrderGroup, error)
+
+	// UpdateOrderGroup writes only the status of a group
+	UpdateOrderGroup(group *models.OrderGroup) error
+}
+
+// RFQRepository provides access to requests for quote, their quotes and
+// their history
+type RFQRepository interface {
+	CreateRFQ(rfq *models.RFQ) error
+
+	// GetRFQByID returns an RFQ with its quotes
+	GetRFQByID(id uint) (*models.RFQ, error)
+
+	// GetRFQsByUserID returns the RFQs of a user without their quotes,
+	// newest first
+	GetRFQsByUserID(userID uint) ([]models.RFQ, error)
+
+	// GetOpenRFQs returns the open RFQs without their quotes, oldest first
+	GetOpenRFQs() ([]models.RFQ, error)
+
+	// UpdateRFQ writes the status, accepted quote and trade of an RFQ
+	UpdateRFQ(rfq *models.RFQ) error
+
+	CreateRFQQuote(quote *models.RFQQuote) error
+	CreateRFQEvent(event *models.RFQEvent) error
+
+	// GetRFQEvents returns the history of an RFQ, oldest first
+	GetRFQEvents(rfqID uint) ([]models.RFQEvent, error)
+}
+
+// TradeRepository provides access to executed trades
+type TradeRepository interface {
+	// CreateTrade stores a trade at its execution time, or now if unset
+	CreateTrade(trade *models.Trade) error
+	GetTradeByID(id uint) (*models.Trade, error)
+	GetAllTrades() ([]models.Trade, error)
+
+	// ListTrades returns a page of trades matching the filter
+	ListTrades(filter models.TradeFilter) (*models.TradePage, error)
+
+	// CreateCorrectedTrade books the replacement of a corrected trade at
+	// the price and quantity of trade, keeping the original's orders, flags
+	// and execution time
+	CreateCorrectedTrade(trade *models.Trade, originalID uint) error
+	CreateTradeCorrection(correction *models.TradeCorrection) error
+	GetTradeCorrection(tradeID uint) (*models.TradeCorrection, error)
+}
+
+// CandleRepository provides access to the OHLCV candles aggregated from
+// trades
+type CandleRepository interface {
+	GetCandle(symbol models.StockSymbol, interval models.CandleInterval, openTime time.Time) (*models.Candle, error)
+
+	// SaveCandle inserts or replaces a candle
+	SaveCandle(candle *models.Candle) error
+
+	// ListCandles returns the latest candles matching the filter in
+	// ascending open time
+	ListCandles(filter models.CandleFilter) ([]models.Candle, error)
+	DeleteCandle(symbol models.StockSymbol, interval models.CandleInterval, openTime time.Time) error
+	DeleteCandles(symbol models.StockSymbol) error
+}
+
+// UserRepository provides access to users and their API keys
+type UserRepository interface {
+	CreateUser(user *models.User) error
+	GetUserByID(id uint) (*models.User, error)
+	GetUserByUsername(username string) (*models.User, error)
+	CreateAPIKey(key *models.APIKey) error
+
+	// GetAPIKeyByHash returns an unrevoked API key with its owner
+	GetAPIKeyByHash(keyHash string) (*models.APIKey, error)
+}
+
+// AuditRepository records authorization decisions
+type AuditRepository interface {
+	CreateAuditEntry(entry *models.AuditEntry) error
+
+	// ListAuditEntries returns the latest entries matching the filter,
+	// newest first
+	ListAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// IdempotencyRepository records the responses of requests sent with an
+// idempotency key
+type IdempotencyRepository interface {
+	// CreateIdempotencyKey stores the key as created now unless its creation
+	// time is set, and fails if the user already used the key
+	CreateIdempotencyKey(key *models.IdempotencyKey) error
+	GetIdempotencyKey(userID uint, key string) (*models.IdempotencyKey, error)
+	UpdateIdempotencyKey(key *models.IdempotencyKey) error
+	DeleteIdempotencyKey(userID uint, key string) error
+	DeleteIdempotencyKeysBefore(t time.Time) error
+}
+
+// Repository is the persistence layer used by the matching engine and the
+// HTTP handlers. Lookups of missing records return sql.ErrNoRows regardless
+// of the backend.
+type Repository interface {
+	StockRepository
+	OrderRepository
+	OrderGroupRepository
+	RFQRepository
+	TradeRepository
+	CandleRepository
+	UserRepository
+	AuditRepository
+	IdempotencyRepository
+
+	// Transact runs fn inside a transaction. The repository passed to fn must
+	// be used for all operations that belong to the transaction; it is
+	// committed if fn returns nil and rolled back otherwise. Calling Transact
+	// on a transactional repository runs fn in the existing transaction.
+	Transact(fn func(repo Repository) error) error
+}
@@ -0,0 +1,364 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"order-matching/api/v1/models"
+	"time"
+)
+
+// SQLRepository implements Repository on top of a SQL database. The queries
+// in the models package are shared by all dialects; PostgreSQL connections
+// are wrapped so their placeholders are rewritten.
+type SQLRepository struct {
+	conn    *sql.DB
+	db      models.DBTX
+	dialect models.Dialect
+	stocks  *stockCache
+
+	// committed holds the cache updates of a transaction, which are
+	// applied once it commits
+	committed []func()
+}
+
+// NewSQL creates a repository backed by the given database connection
+func NewSQL(db *sql.DB, dialect models.Dialect) *SQLRepository {
+	r := &SQLRepository{conn: db, dialect: dialect, stocks: newStockCache()}
+	r.db = r.bind(db)
+	return r
+}
+
+// bind adapts db to the repository's dialect
+func (r *SQLRepository) bind(db models.DBTX) models.DBTX {
+	if r.dialect == models.DialectPostgres {
+		return models.PostgresDB{DB: db}
+	}
+	return db
+}
+
+// GetStockBySymbol retrieves a stock by its symbol. Outside transactions
+// stocks are served from the in-process cache.
+func (r *SQLRepository) GetStockBySymbol(symbol models.StockSymbol) (*models.Stock, error) {
+	if r.conn == nil {
+		return models.GetStockBySymbol(r.db, symbol)
+	}
+	return r.stocks.get(r.db, symbol)
+}
+
+// GetAllStocks retrieves all stocks, including delisted ones
+func (r *SQLRepository) GetAllStocks() ([]models.Stock, error) {
+	return models.GetAllStocks(r.db)
+}
+
+// CreateStock lists a new stock
+func (r *SQLRepository) CreateStock(stock *models.Stock) error {
+	if err := models.CreateStock(r.db, stock); err != nil {
+		return err
+	}
+	r.onCommit(r.stocks.invalidate)
+	return nil
+}
+
+// UpdateStock updates an existing stock
+func (r *SQLRepository) UpdateStock(stock *models.Stock) error {
+	if err := models.UpdateStock(r.db, stock); err != nil {
+		return err
+	}
+	r.onCommit(r.stocks.invalidate)
+	return nil
+}
+
+// UpdateStockStats updates the trading statistics of a stock. They change
+// on every trade, so the cached stock is replaced rather than the whole
+// cache invalidated.
+func (r *SQLRepository) UpdateStockStats(stock *models.Stock) error {
+	if err := models.UpdateStockStats(r.db, stock); err != nil {
+		return err
+	}
+	updated := *stock
+	r.onCommit(func() { r.stocks.put(updated) })
+	return nil
+}
+
+// onCommit runs fn once the repository's writes are committed: at once
+// outside a transaction and after the commit inside one, so that the cache
+// never holds uncommitted data
+func (r *SQLRepository) onCommit(fn func()) {
+	if r.conn == nil {
+		r.committed = append(r.committed, fn)
+		return
+	}
+	fn()
+}
+
+// GetOrderByID retrieves an order by its ID
+func (r *SQLRepository) GetOrderByID(id uint) (*models.Order, error) {
+	return models.GetOrderByID(r.db, id)
+}
+
+// GetOrderByClientID retrieves the order of a user by client order id
+func (r *SQLRepository) GetOrderByClientID(userID uint, clientOrderID string) (*models.Order, error) {
+	return models.GetOrderByClientID(r.db, userID, clientOrderID)
+}
+
+// CreateOrder creates a new order
+func (r *SQLRepository) CreateOrder(order *models.Order) error {
+	return models.CreateOrder(r.db, order)
+}
+
+// UpdateOrder updates the price, priority and fill state of an existing
+// order
+func (r *SQLRepository) UpdateOrder(order *models.Order) error {
+	return models.UpdateOrder(r.db, order)
+}
+
+// GetOrdersByUserID retrieves all orders for a specific user
+func (r *SQLRepository) GetOrdersByUserID(userID uint) ([]models.Order, error) {
+	return models.GetOrdersByUserID(r.db, userID)
+}
+
+// GetOrdersByStock retrieves all orders for a specific stock
+func (r *SQLRepository) GetOrdersByStock(symbol models.StockSymbol) ([]models.Order, error) {
+	return models.GetOrdersByStock(r.db, symbol)
+}
+
+// GetAllOrders retrieves all orders
+func (r *SQLRepository) GetAllOrders() ([]models.Order, error) {
+	return models.GetAllOrders(r.db)
+}
+
+// ListOrders retrieves a page of orders matching the filter
+func (r *SQLRepository) ListOrders(filter models.OrderFilter) (*models.OrderPage, error) {
+	return models.ListOrders(r.db, filter)
+}
+
+// GetMatchingOrders retrieves the orders that can match the given order
+func (r *SQLRepository) GetMatchingOrders(order *models.Order) ([]models.Order, error) {
+	return models.GetMatchingOrders(r.db, order)
+}
+
+// GetDarkOrders retrieves the hidden orders that can match the given order
+// at the midpoint
+func (r *SQLRepository) GetDarkOrders(order *models.Order, midpoint float64) ([]models.Order, error) {
+	return models.GetDarkOrders(r.db, order, midpoint)
+}
+
+// CreateTrade creates a new trade
+func (r *SQLRepository) CreateTrade(trade *models.Trade) error {
+	return models.CreateTrade(r.db, trade)
+}
+
+// GetTradeByID retrieves a trade by its ID
+func (r *SQLRepository) GetTradeByID(id uint) (*models.Trade, error) {
+	return models.GetTradeByID(r.db, id)
+}
+
+// GetAllTrades retrieves all trades
+func (r *SQLRepository) GetAllTrades() ([]models.Trade, error) {
+	return models.GetAllTrades(r.db)
+}
+
+// ListTrades retrieves a page of trades matching the filter
+func (r *SQLRepository) ListTrades(filter models.TradeFilter) (*models.TradePage, error) {
+	return models.ListTrades(r.db, filter)
+}
+
+// CreateCorrectedTrade books the replacement of a corrected trade
+func (r *SQLRepository) CreateCorrectedTrade(trade *models.Trade, originalID uint) error {
+	return models.CreateCorrectedTrade(r.db, trade, originalID)
+}
+
+// CreateTradeCorrection records the correction of a trade
+func (r *SQLRepository) CreateTradeCorrection(correction *models.TradeCorrection) error {
+	return models.CreateTradeCorrection(r.db, correction)
+}
+
+// GetTradeCorrection retrieves the correction of a trade
+func (r *SQLRepository) GetTradeCorrection(tradeID uint) (*models.TradeCorrection, error) {
+	return models.GetTradeCorrection(r.db, tradeID)
+}
+
+// GetCandle retrieves the candle of a stock and interval opening at openTime
+func (r *SQLRepository) GetCandle(symbol models.StockSymbol, interval models.CandleInterval, openTime time.Time) (*models.Candle, error) {
+	return models.GetCandle(r.db, symbol, interval, openTime)
+}
+
+// SaveCandle inserts or replaces a candle
+func (r *SQLRepository) SaveCandle(candle *models.Candle) error {
+	return models.SaveCandle(r.db, candle)
+}
+
+// ListCandles retrieves the latest candles matching the filter
+func (r *SQLRepository) ListCandles(filter models.CandleFilter) ([]models.Candle, error) {
+	return models.ListCandles(r.db, filter)
+}
+
+// DeleteCandle deletes the candle of a stock and interval opening at
+// openTime
+func (r *SQLRepository) DeleteCandle(symbol models.StockSymbol, interval models.CandleInterval, openTime time.Time) error {
+	return models.DeleteCandle(r.db, symbol, interval, openTime)
+}
+
+// DeleteCandles deletes all candles of a stock
+func (r *SQLRepository) DeleteCandles(symbol models.StockSymbol) error {
+	return models.DeleteCandles(r.db, symbol)
+}
+
+// CreateUser creates a new user
+func (r *SQLRepository) CreateUser(user *models.User) error {
+	return models.CreateUser(r.db, user)
+}
+
+// GetUserByID retrieves a user by its ID
+func (r *SQLRepository) GetUserByID(id uint) (*models.User, error) {
+	return models.GetUserByID(r.db, id)
+}
+
+// GetUserByUsername retrieves a user by its username
+func (r *SQLRepository) GetUserByUsername(username string) (*models.User, error) {
+	return models.GetUserByUsername(r.db, username)
+}
+
+// CreateAPIKey stores a new API key
+func (r *SQLRepository) CreateAPIKey(key *models.APIKey) error {
+	return models.CreateAPIKey(r.db, key)
+}
+
+// GetAPIKeyByHash retrieves an unrevoked API key with its owner
+func (r *SQLRepository) GetAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	return models.GetAPIKeyByHash(r.db, keyHash)
+}
+
+// CreateOrderGroup creates a new order group
+func (r *SQLRepository) CreateOrderGroup(group *models.OrderGroup) error {
+	return models.CreateOrderGroup(r.db, group)
+}
+
+// GetOrderGroupByID retrieves an order group with its orders
+func (r *SQLRepository) GetOrderGroupByID(id uint) (*models.OrderGroup, error) {
+	group, err := models.GetOrderGroupByID(r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if group.Orders, err = models.GetOrdersByGroupID(r.db, id); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateOrderGroup updates the status of an order group
+func (r *SQLRepository) UpdateOrderGroup(group *models.OrderGroup) error {
+	return models.UpdateOrderGroup(r.db, group)
+}
+
+// CreateRFQ creates a new request for quote
+func (r *SQLRepository) CreateRFQ(rfq *models.RFQ) error {
+	return models.CreateRFQ(r.db, rfq)
+}
+
+// GetRFQByID retrieves an RFQ with its quotes
+func (r *SQLRepository) GetRFQByID(id uint) (*models.RFQ, error) {
+	rfq, err := models.GetRFQByID(r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if rfq.Quotes, err = models.GetRFQQuotes(r.db, id); err != nil {
+		return nil, err
+	}
+	return rfq, nil
+}
+
+// GetRFQsByUserID retrieves the RFQs of a user
+func (r *SQLRepository) GetRFQsByUserID(userID uint) ([]models.RFQ, error) {
+	return models.GetRFQsByUserID(r.db, userID)
+}
+
+// GetOpenRFQs retrieves the open RFQs
+func (r *SQLRepository) GetOpenRFQs() ([]models.RFQ, error) {
+	return models.GetOpenRFQs(r.db)
+}
+
+// UpdateRFQ updates the status, accepted quote and trade of an RFQ
+func (r *SQLRepository) UpdateRFQ(rfq *models.RFQ) error {
+	return models.UpdateRFQ(r.db, rfq)
+}
+
+// CreateRFQQuote creates a new quote on an RFQ
+func (r *SQLRepository) CreateRFQQuote(quote *models.RFQQuote) error {
+	return models.CreateRFQQuote(r.db, quote)
+}
+
+// CreateRFQEvent records a step in the history of an RFQ
+func (r *SQLRepository) CreateRFQEvent(event *models.RFQEvent) error {
+	return models.CreateRFQEvent(r.db, event)
+}
+
+// GetRFQEvents retrieves the history of an RFQ
+func (r *SQLRepository) GetRFQEvents(rfqID uint) ([]models.RFQEvent, error) {
+	return models.GetRFQEvents(r.db, rfqID)
+}
+
+// CreateIdempotencyKey records a request in progress
+func (r *SQLRepository) CreateIdempotencyKey(key *models.IdempotencyKey) error {
+	return models.CreateIdempotencyKey(r.db, key)
+}
+
+// GetIdempotencyKey retrieves an idempotency key of a user
+func (r *SQLRepository) GetIdempotencyKey(userID uint, key string) (*models.IdempotencyKey, error) {
+	return models.GetIdempotencyKey(r.db, userID, key)
+}
+
+// UpdateIdempotencyKey stores the response of a completed request
+func (r *SQLRepository) UpdateIdempotencyKey(key *models.IdempotencyKey) error {
+	return models.UpdateIdempotencyKey(r.db, key)
+}
+
+// DeleteIdempotencyKey deletes an idempotency key of a user
+func (r *SQLRepository) DeleteIdempotencyKey(userID uint, key string) error {
+	return models.DeleteIdempotencyKey(r.db, userID, key)
+}
+
+// DeleteIdempotencyKeysBefore deletes the idempotency keys created before t
+func (r *SQLRepository) DeleteIdempotencyKeysBefore(t time.Time) error {
+	return models.DeleteIdempotencyKeysBefore(r.db, t)
+}
+
+// CreateAuditEntry stores an authorization decision
+func (r *SQLRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	return models.CreateAuditEntry(r.db, entry)
+}
+
+// ListAuditEntries retrieves the latest audit entries matching the filter
+func (r *SQLRepository) ListAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	return models.ListAuditEntries(r.db, filter)
+}
+
+// Transact runs fn inside a database transaction
+func (r *SQLRepository) Transact(fn func(repo Repository) error) error {
+	// Already inside a transaction
+	if r.conn == nil {
+		return fn(r)
+	}
+
+	// Begin transaction
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	txRepo := &SQLRepository{db: r.bind(tx), dialect: r.dialect, stocks: r.stocks}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	for _, fn := range txRepo.committed {
+		fn()
+	}
+	return nil
+}
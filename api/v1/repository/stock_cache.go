@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"order-matching/api/v1/models"
+	"sync"
+	"time"
+)
+
+// stockCacheTTL bounds how stale cached stocks can get when they are changed
+// by another process
+const stockCacheTTL = 30 * time.Second
+
+// stockCache keeps the stocks table in process so that symbol lookups on
+// hot paths such as order entry do not cost a query each. The whole table is
+// loaded at once, so a lookup costs at most one query per TTL.
+type stockCache struct {
+	mu       sync.RWMutex
+	stocks   map[models.StockSymbol]models.Stock
+	loadedAt time.Time
+}
+
+// newStockCache creates an empty stock cache
+func newStockCache() *stockCache {
+	return &stockCache{}
+}
+
+// get returns a copy of the cached stock, reloading the cache through db
+// when it has expired
+func (c *stockCache) get(db models.DBTX, symbol models.StockSymbol) (*models.Stock, error) {
+	c.mu.RLock()
+	fresh := c.stocks != nil && time.Since(c.loadedAt) < stockCacheTTL
+	stock, ok := c.stocks[symbol]
+	c.mu.RUnlock()
+
+	if !fresh {
+		if err := c.load(db); err != nil {
+			return nil, err
+		}
+		c.mu.RLock()
+		stock, ok = c.stocks[symbol]
+		c.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &stock, nil
+}
+
+// load replaces the cache with the current contents of the stocks table
+func (c *stockCache) load(db models.DBTX) error {
+	stocks, err := models.GetAllStocks(db)
+	if err != nil {
+		return err
+	}
+
+	bySymbol := make(map[models.StockSymbol]models.Stock, len(stocks))
+	for _, stock := range stocks {
+		bySymbol[stock.Symbol] = stock
+	}
+
+	c.mu.Lock()
+	c.stocks = bySymbol
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// invalidate forces the next lookup to reload the cache
+func (c *stockCache) invalidate() {
+	c.mu.Lock()
+	c.stocks = nil
+	c.mu.Unlock()
+}
+
+// put replaces a cached stock. It is a no-op while the cache is unloaded.
+func (c *stockCache) put(stock models.Stock) {
+	c.mu.Lock()
+	if c.stocks != nil {
+		c.stocks[stock.Symbol] = stock
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,264 @@
+package rfq
+
+import (
+	"errors"
+	"fmt"
+	"order-matching/api/v1/config"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotOpen is returned for a step on an RFQ that was accepted,
+	// cancelled or has expired
+	ErrNotOpen = errors.New("RFQ is no longer open")
+
+	// ErrOwnRFQ is returned when a market maker quotes on its own RFQ
+	ErrOwnRFQ = errors.New("cannot quote on your own RFQ")
+
+	// ErrAlreadyQuoted is returned for a second quote of a market maker on
+	// the same RFQ
+	ErrAlreadyQuoted = errors.New("RFQ already quoted")
+
+	// ErrQuoteNotFound is returned when accepting a quote the RFQ does not
+	// have
+	ErrQuoteNotFound = errors.New("quote not found")
+
+	// ErrSideNotQuoted is returned when accepting a side the quote does not
+	// price
+	ErrSideNotQuoted = errors.New("quote does not price that side")
+)
+
+// Desk runs the request-for-quote workflow. A requester opens an RFQ for a
+// size of a stock, market makers answer with firm two-way quotes until it
+// expires, and until then the requester may accept one quote. Accepting
+// executes the whole size off the book at the quoted price, as a trade
+// flagged negotiated between two filled orders that never enter the book;
+// it does not move the stock's price, statistics or candles. Every step is
+// recorded in the RFQ's history.
+type Desk struct {
+	repo repository.Repository
+	cfg  *config.RFQConfig
+	mu   sync.Mutex // Serializes the steps of all RFQs
+}
+
+// NewDesk creates a desk storing RFQs in repo
+func NewDesk(repo repository.Repository, cfg *config.RFQConfig) *Desk {
+	return &Desk{repo: repo, cfg: cfg}
+}
+
+// Create opens an RFQ, expiring after the configured timeout
+func (d *Desk) Create(rfq *models.RFQ) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.repo.Transact(func(tx repository.Repository) error {
+		rfq.Status = models.RFQOpen
+		rfq.ExpiresAt = time.Now().Add(d.cfg.Timeout)
+		if err := tx.CreateRFQ(rfq); err != nil {
+			return fmt.Errorf("failed to create RFQ: %v", err)
+		}
+		return record(tx, &models.RFQEvent{RFQID: rfq.ID, Type: models.RFQEventCreated, UserID: rfq.UserID})
+	})
+}
+
+// Quote adds the firm quote of a market maker to an open RFQ
+func (d *Desk) Quote(id uint, quote *models.RFQQuote) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.repo.Transact(func(tx repository.Repository) error {
+		rfq, err := loadOpen(tx, id)
+		if err != nil {
+			return err
+		}
+		if rfq.UserID == quote.UserID {
+			return ErrOwnRFQ
+		}
+		for _, q := range rfq.Quotes {
+			if q.UserID == quote.UserID {
+				return ErrAlreadyQuoted
+			}
+		}
+
+		quote.RFQID = id
+		if err := tx.CreateRFQQuote(quote); err != nil {
+			return fmt.Errorf("failed to create quote: %v", err)
+		}
+		return record(tx, &models.RFQEvent{RFQID: id, Type: models.RFQEventQuoted, UserID: quote.UserID, QuoteID: quote.ID})
+	})
+}
+
+// Accept executes a quote of an open RFQ, the requester taking the given
+// side: a buyer pays the quote's ask and a seller hits its bid
+func (d *Desk) Accept(id, quoteID uint, side models.OrderType) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.repo.Transact(func(tx repository.Repository) error {
+		rfq, err := loadOpen(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var quote *models.RFQQuote
+		for i := range rfq.Quotes {
+			if rfq.Quotes[i].ID == quoteID {
+				quote = &rfq.Quotes[i]
+			}
+		}
+		if quote == nil {
+			return ErrQuoteNotFound
+		}
+		price := quote.AskPrice
+		if side == models.OrderTypeSell {
+			price = quote.BidPrice
+		}
+		if price == 0 {
+			return ErrSideNotQuoted
+		}
+
+		// The requester and the market maker each get a filled order
+		buy := filledOrder(rfq, models.OrderTypeBuy, price, rfq.UserID)
+		sell := filledOrder(rfq, models.OrderTypeSell, price, quote.UserID)
+		if side == models.OrderTypeSell {
+			buy.UserID, sell.UserID = quote.UserID, rfq.UserID
+		}
+		for _, order := range []*models.Order{buy, sell} {
+			if err := tx.CreateOrder(order); err != nil {
+				return fmt.Errorf("failed to create order: %v", err)
+			}
+		}
+
+		trade := &models.Trade{
+			BuyOrderID:  buy.ID,
+			SellOrderID: sell.ID,
+			StockSymbol: rfq.StockSymbol,
+			Quantity:    rfq.Quantity,
+			Price:       price,
+			Negotiated:  true,
+		}
+		if err := tx.CreateTrade(trade); err != nil {
+			return fmt.Errorf("failed to create trade: %v", err)
+		}
+
+		rfq.Status = models.RFQAccepted
+		rfq.AcceptedQuoteID = quote.ID
+		rfq.TradeID = trade.ID
+		if err := tx.UpdateRFQ(rfq); err != nil {
+			return fmt.Errorf("failed to update RFQ: %v", err)
+		}
+		return record(tx, &models.RFQEvent{
+			RFQID:   id,
+			Type:    models.RFQEventAccepted,
+			UserID:  rfq.UserID,
+			QuoteID: quote.ID,
+			TradeID: trade.ID,
+		})
+	})
+}
+
+// Cancel withdraws an open RFQ
+func (d *Desk) Cancel(id uint) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.repo.Transact(func(tx repository.Repository) error {
+		rfq, err := loadOpen(tx, id)
+		if err != nil {
+			return err
+		}
+		rfq.Status = models.RFQCancelled
+		if err := tx.UpdateRFQ(rfq); err != nil {
+			return fmt.Errorf("failed to update RFQ: %v", err)
+		}
+		return record(tx, &models.RFQEvent{RFQID: id, Type: models.RFQEventCancelled, UserID: rfq.UserID})
+	})
+}
+
+// ExpireDue expires the open RFQs past their expiry as of now and returns
+// how many expired
+func (d *Desk) ExpireDue(now time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	expired := 0
+	err := d.repo.Transact(func(tx repository.Repository) error {
+		expired = 0
+		rfqs, err := tx.GetOpenRFQs()
+		if err != nil {
+			return fmt.Errorf("failed to get open RFQs: %v", err)
+		}
+		for i := range rfqs {
+			if now.Before(rfqs[i].ExpiresAt) {
+				continue
+			}
+			rfqs[i].Status = models.RFQExpired
+			if err := tx.UpdateRFQ(&rfqs[i]); err != nil {
+				return fmt.Errorf("failed to expire RFQ %d: %v", rfqs[i].ID, err)
+			}
+			if err := record(tx, &models.RFQEvent{RFQID: rfqs[i].ID, Type: models.RFQEventExpired}); err != nil {
+				return err
+			}
+			expired++
+		}
+		return nil
+	})
+	return expired, err
+}
+
+// RunExpiry expires RFQs every second until stop is closed
+func (d *Desk) RunExpiry(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if _, err := d.ExpireDue(now); err != nil {
+				logger.Error(err, "Failed to expire RFQs")
+			}
+		}
+	}
+}
+
+// loadOpen loads an RFQ with its quotes within tx and checks that it is
+// open and not past its expiry
+func loadOpen(tx repository.Repository, id uint) (*models.RFQ, error) {
+	rfq, err := tx.GetRFQByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RFQ: %v", err)
+	}
+	if rfq.Status != models.RFQOpen || !time.Now().Before(rfq.ExpiresAt) {
+		return nil, ErrNotOpen
+	}
+	return rfq, nil
+}
+
+// record adds an event to the history of an RFQ
+func record(tx repository.Repository, event *models.RFQEvent) error {
+	if err := tx.CreateRFQEvent(event); err != nil {
+		return fmt.Errorf("failed to record RFQ event: %v", err)
+	}
+	return nil
+}
+
+// filledOrder returns an order of a user filling the whole size of an RFQ
+// at price
+func filledOrder(rfq *models.RFQ, side models.OrderType, price float64, userID uint) *models.Order {
+	return &models.Order{
+		Type:           side,
+		Category:       models.OrderCategoryLimit,
+		StockSymbol:    rfq.StockSymbol,
+		Quantity:       rfq.Quantity,
+		FilledQuantity: rfq.Quantity,
+		Price:          price,
+		Status:         models.OrderStatusMatched,
+		UserID:         userID,
+	}
+}
@@ -0,0 +1,101 @@
+package rfq
+
+import (
+	"order-matching/api/v1/config"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	order_matcher "order-matching/api/v1/services"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// marketData is what a trade on the book moves for COGNT
+type marketData struct {
+	candles map[models.CandleInterval][]models.Candle
+	stock   models.Stock
+	ticker  models.Ticker
+}
+
+// snapshot returns the market data of COGNT as served by matcher
+func snapshot(t *testing.T, repo repository.Repository, matcher *order_matcher.OrderMatcher, now time.Time) marketData {
+	t.Helper()
+	data := marketData{candles: make(map[models.CandleInterval][]models.Candle)}
+	for _, interval := range models.CandleIntervals {
+		candles, err := repo.ListCandles(models.CandleFilter{Symbol: "COGNT", Interval: interval, Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.candles[interval] = candles
+	}
+	stock, err := repo.GetStockBySymbol("COGNT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.stock = *stock
+	data.ticker = matcher.Tickers().Get("COGNT", now)
+	return data
+}
+
+func TestAcceptedRFQLeavesMarketDataAlone(t *testing.T) {
+	repo := repository.NewMemory(models.Stock{Symbol: "COGNT", CurrentPrice: 100})
+	matcher := order_matcher.NewOrderMatcher(repo)
+	orders := []*models.Order{
+		{Type: models.OrderTypeSell, Quantity: 10, Price: 101, UserID: 1},
+		{Type: models.OrderTypeBuy, Quantity: 4, Price: 101, UserID: 2},
+		{Type: models.OrderTypeBuy, Quantity: 5, Price: 99, UserID: 2},
+	}
+	for _, order := range orders {
+		order.Category, order.StockSymbol, order.Status = models.OrderCategoryLimit, "COGNT", models.OrderStatusPending
+	}
+	for _, err := range matcher.ProcessOrders(orders) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now().Add(time.Minute)
+	before := snapshot(t, repo, matcher, now)
+	if before.ticker.LastPrice != 101 || len(before.candles[models.CandleInterval1m]) != 1 {
+		t.Fatalf("market data of the book trade = %+v", before)
+	}
+
+	desk := NewDesk(repo, &config.RFQConfig{Timeout: time.Minute})
+	rfq := &models.RFQ{UserID: 1, StockSymbol: "COGNT", Quantity: 1000}
+	if err := desk.Create(rfq); err != nil {
+		t.Fatal(err)
+	}
+	quote := &models.RFQQuote{UserID: 2, BidPrice: 80, AskPrice: 120}
+	if err := desk.Quote(rfq.ID, quote); err != nil {
+		t.Fatal(err)
+	}
+	if err := desk.Accept(rfq.ID, quote.ID, models.OrderTypeBuy); err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := repo.GetRFQByID(rfq.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trade, err := repo.GetTradeByID(accepted.TradeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trade.Negotiated || trade.Price != 120 || trade.Quantity != 1000 {
+		t.Fatalf("negotiated trade = %+v, want 1000 at 120", trade)
+	}
+
+	if live := snapshot(t, repo, matcher, now); !reflect.DeepEqual(live, before) {
+		t.Fatalf("market data after the RFQ = %+v, want %+v", live, before)
+	}
+
+	// Rebuilt from the stored trades, as after a restart
+	if _, err := matcher.BackfillCandles("COGNT"); err != nil {
+		t.Fatal(err)
+	}
+	restarted := order_matcher.NewOrderMatcher(repo)
+	if err := restarted.LoadBook(); err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := snapshot(t, repo, restarted, now); !reflect.DeepEqual(rebuilt, before) {
+		t.Fatalf("rebuilt market data = %+v, want %+v", rebuilt, before)
+	}
+}
@@ -1,25 +1,104 @@
 package routes
 
 import (
+	"net/http"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/config"
+	"order-matching/api/v1/controllers/audit"
+	"order-matching/api/v1/controllers/heartbeat"
 	"order-matching/api/v1/controllers/orders"
+	"order-matching/api/v1/controllers/rfqs"
+	"order-matching/api/v1/controllers/stocks"
+	"order-matching/api/v1/controllers/tickers"
 	"order-matching/api/v1/controllers/trades"
+	"order-matching/api/v1/idempotency"
+	"order-matching/api/v1/ratelimit"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/rfq"
+	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/sessions"
 
 	"github.com/gorilla/mux"
 )
 
 // SetupRoutes configures all the routes for the application
-func SetupRoutes(router *mux.Router) {
+func SetupRoutes(router *mux.Router, repo repository.Repository, matcher *order_matcher.OrderMatcher, limiter *ratelimit.Limiter, manager *sessions.Manager, desk *rfq.Desk) {
+	orderHandler := orders.NewHandler(repo, matcher)
+	tradeHandler := trades.NewHandler(repo, matcher)
+	stockHandler := stocks.NewHandler(repo, matcher)
+	tickerHandler := tickers.NewHandler(repo, matcher)
+	auditHandler := audit.NewHandler(repo)
+	heartbeatHandler := heartbeat.NewHandler(manager)
+	rfqHandler := rfqs.NewHandler(repo, desk)
+
 	// API routes
 	api := router.PathPrefix("/api/v1").Subrouter()
 
+	// Routes requiring an API key, each guarded by the permission its
+	// operation needs. Trading and read routes are also rate limited by
+	// request class once authorized, so denied requests spend no tokens,
+	// and mutating routes honor the Idempotency-Key header. Keys are only
+	// claimed once a request passed its checks, so that rejections are not
+	// replayed.
+	private := api.NewRoute().Subrouter()
+	private.Use(auth.Middleware(repo))
+	authz := auth.NewAuthorizer(repo)
+	idempotent := idempotency.Middleware(repo)
+	require := func(perm auth.Permission, handler http.HandlerFunc) http.Handler {
+		return authz.Require(perm, idempotent(handler).ServeHTTP)
+	}
+	guard := func(class string, perm auth.Permission, handler http.HandlerFunc) http.Handler {
+		return authz.Require(perm, limiter.Limit(class, idempotent(handler)).ServeHTTP)
+	}
+
 	// Orders routes
-	api.HandleFunc("/orders", orders.CreateOrder).Methods("POST")
-	api.HandleFunc("/orders", orders.GetAllOrders).Methods("GET")
-	api.HandleFunc("/orders/{id:[0-9]+}", orders.GetOrder).Methods("GET")
-	api.HandleFunc("/orders/{id:[0-9]+}/cancel", orders.CancelOrder).Methods("POST")
-	api.HandleFunc("/orders/stock/{symbol}", orders.GetOrdersByStock).Methods("GET")
+	private.Handle("/orders", guard(config.ClassOrders, auth.PermTrade, orderHandler.CreateOrder)).Methods("POST")
+	private.Handle("/orders/batch", authz.Require(auth.PermTrade, limiter.LimitN(config.ClassOrders, orders.BatchSize, idempotent(http.HandlerFunc(orderHandler.CreateOrders))).ServeHTTP)).Methods("POST")
+	private.Handle("/orders/cancel-all", guard(config.ClassCancels, auth.PermTrade, orderHandler.CancelAllOrders)).Methods("POST")
+	private.Handle("/users/{user_id:[0-9]+}/orders/cancel-all", guard(config.ClassCancels, auth.PermOperate, orderHandler.CancelUserOrders)).Methods("POST")
+	private.Handle("/orders", guard(config.ClassReads, auth.PermRead, orderHandler.GetAllOrders)).Methods("GET")
+	private.Handle("/orders/{id:[0-9]+}", guard(config.ClassReads, auth.PermRead, orderHandler.GetOrder)).Methods("GET")
+	private.Handle("/orders/{id:[0-9]+}/cancel", guard(config.ClassCancels, auth.PermTrade, orderHandler.CancelOrder)).Methods("POST")
+	private.Handle("/orders/client/{client_order_id}", guard(config.ClassReads, auth.PermRead, orderHandler.GetOrderByClientID)).Methods("GET")
+	private.Handle("/orders/client/{client_order_id}/cancel", guard(config.ClassCancels, auth.PermTrade, orderHandler.CancelOrderByClientID)).Methods("POST")
+	private.Handle("/orders/stock/{symbol}", guard(config.ClassReads, auth.PermRead, orderHandler.GetOrdersByStock)).Methods("GET")
+	private.Handle("/order-groups", guard(config.ClassOrders, auth.PermTrade, orderHandler.CreateOrderGroup)).Methods("POST")
+	private.Handle("/order-groups/{id:[0-9]+}", guard(config.ClassReads, auth.PermRead, orderHandler.GetOrderGroup)).Methods("GET")
+	private.Handle("/order-groups/{id:[0-9]+}/cancel", guard(config.ClassCancels, auth.PermTrade, orderHandler.CancelOrderGroup)).Methods("POST")
+
+	// RFQ routes; market makers quote on the open RFQs of others
+	private.Handle("/rfqs", guard(config.ClassOrders, auth.PermTrade, rfqHandler.CreateRFQ)).Methods("POST")
+	private.Handle("/rfqs", guard(config.ClassReads, auth.PermRead, rfqHandler.GetRFQs)).Methods("GET")
+	private.Handle("/rfqs/open", guard(config.ClassReads, auth.PermQuote, rfqHandler.GetOpenRFQs)).Methods("GET")
+	private.Handle("/rfqs/{id:[0-9]+}", guard(config.ClassReads, auth.PermRead, rfqHandler.GetRFQ)).Methods("GET")
+	private.Handle("/rfqs/{id:[0-9]+}/history", guard(config.ClassReads, auth.PermRead, rfqHandler.GetRFQHistory)).Methods("GET")
+	private.Handle("/rfqs/{id:[0-9]+}/quotes", guard(config.ClassOrders, auth.PermQuote, rfqHandler.QuoteRFQ)).Methods("POST")
+	private.Handle("/rfqs/{id:[0-9]+}/accept", guard(config.ClassOrders, auth.PermTrade, rfqHandler.AcceptRFQ)).Methods("POST")
+	private.Handle("/rfqs/{id:[0-9]+}/cancel", guard(config.ClassCancels, auth.PermTrade, rfqHandler.CancelRFQ)).Methods("POST")
+
+	// Dead-man's switch routes
+	private.Handle("/heartbeat", guard(config.ClassReads, auth.PermTrade, heartbeatHandler.Heartbeat)).Methods("POST")
+	private.Handle("/heartbeat", guard(config.ClassReads, auth.PermTrade, heartbeatHandler.Disarm)).Methods("DELETE")
 
 	// Trades routes
-	api.HandleFunc("/trades", trades.GetAllTrades).Methods("GET")
-	api.HandleFunc("/trades/{id:[0-9]+}", trades.GetTradeByID).Methods("GET")
+	private.Handle("/trades", guard(config.ClassReads, auth.PermRead, tradeHandler.GetAllTrades)).Methods("GET")
+	private.Handle("/trades/{id:[0-9]+}", guard(config.ClassReads, auth.PermRead, tradeHandler.GetTradeByID)).Methods("GET")
+	private.Handle("/trades/{id:[0-9]+}/correction", guard(config.ClassReads, auth.PermRead, tradeHandler.GetTradeCorrection)).Methods("GET")
+	private.Handle("/trades/{id:[0-9]+}/bust", require(auth.PermOperate, tradeHandler.BustTrade)).Methods("POST")
+	private.Handle("/trades/{id:[0-9]+}/correct", require(auth.PermOperate, tradeHandler.CorrectTrade)).Methods("POST")
+
+	// Stocks routes; reference and market data are public
+	api.HandleFunc("/stocks", stockHandler.GetAllStocks).Methods("GET")
+	private.Handle("/stocks", require(auth.PermOperate, stockHandler.CreateStock)).Methods("POST")
+	api.HandleFunc("/stocks/{symbol}", stockHandler.GetStock).Methods("GET")
+	private.Handle("/stocks/{symbol}", require(auth.PermOperate, stockHandler.UpdateStock)).Methods("PUT")
+	private.Handle("/stocks/{symbol}/delist", require(auth.PermOperate, stockHandler.DelistStock)).Methods("POST")
+	api.HandleFunc("/stocks/{symbol}/candles", stockHandler.GetCandles).Methods("GET")
+
+	// Tickers routes
+	api.HandleFunc("/tickers", tickerHandler.GetAllTickers).Methods("GET")
+	api.HandleFunc("/tickers/{symbol}", tickerHandler.GetTicker).Methods("GET")
+
+	// Audit routes
+	private.Handle("/audit", guard(config.ClassReads, auth.PermAdminister, auditHandler.GetAuditLog)).Methods("GET")
 }
@@ -4,13 +4,27 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"order-matching/api/v1/config"
 	"order-matching/api/v1/database"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/ratelimit"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/rfq"
 	"order-matching/api/v1/routes"
 	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/sessions"
 
 	"github.com/gorilla/mux"
 )
 
+var (
+	// stop is closed by Close to end the background jobs
+	stop = make(chan struct{})
+
+	// manager holds the pending cancellations of disconnected clients
+	manager *sessions.Manager
+)
+
 // Initialize sets up the application
 func Initialize() (*mux.Router, error) {
 	// Initialize database
@@ -18,21 +32,67 @@ func Initialize() (*mux.Router, error) {
 		return nil, fmt.Errorf("failed to initialize database: %v", err)
 	}
 
-	// Set DB in order matcher
-	matcher := order_matcher.GetOrderMatcher()
-	matcher.SetDB(database.GetDB())
+	// Create repository and order matcher
+	repo := repository.NewSQL(database.GetDB(), models.Dialect(database.GetDriver()))
+	matchingConfig, err := config.LoadMatchingConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load matching config: %v", err)
+	}
+	matcher := order_matcher.NewOrderMatcher(repo)
+	matcher.Configure(matchingConfig)
+	if err := matcher.LoadBook(); err != nil {
+		return nil, fmt.Errorf("failed to load order book: %v", err)
+	}
+
+	// Roll stocks into a new trading session every day
+	go matcher.RunSessionRoll(stop)
+
+	// Create the rate limiter, counting fills for the message-to-trade ratio
+	rateLimits, err := config.LoadRateLimitConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate limit config: %v", err)
+	}
+	monitor := ratelimit.NewMonitor(rateLimits)
+	matcher.Subscribe(monitor.Apply)
 
+	// Cancel the orders of clients that disconnect or stop heartbeating
+	sessionConfig, err := config.LoadSessionConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session config: %v", err)
+	}
+	manager = sessions.NewManager(matcher, sessionConfig)
+	matcher.Subscribe(manager.Apply)
+
+	// Run requests for quote, expiring them in the background
+	rfqConfig, err := config.LoadRFQConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load RFQ config: %v", err)
+	}
+	desk := rfq.NewDesk(repo, rfqConfig)
+	go desk.RunExpiry(stop)
+
+	return NewRouter(repo, matcher, ratelimit.NewLimiter(rateLimits, monitor), manager, desk), nil
+}
+
+// NewRouter builds the HTTP router on top of the given repository and
+// matcher without touching the database package, so handlers can be served
+// from any Repository implementation
+func NewRouter(repo repository.Repository, matcher *order_matcher.OrderMatcher, limiter *ratelimit.Limiter, manager *sessions.Manager, desk *rfq.Desk) *mux.Router {
 	// Initialize router
 	router := mux.NewRouter()
 
 	// Setup routes
-	routes.SetupRoutes(router)
+	routes.SetupRoutes(router, repo, matcher, limiter, manager, desk)
 
-	return router, nil
+	return router
 }
 
 // Close cleans up resources
 func Close() {
+	close(stop)
+	if manager != nil {
+		manager.Close()
+	}
 	database.Close()
 }
 
@@ -0,0 +1,136 @@
+package order_matcher
+
+import "order-matching/api/v1/models"
+
+// allocate splits quantity among the resting orders, which must be sorted
+// by price-time priority, following the allocation algorithm of their
+// stock. Price levels are filled best first; the algorithm decides how a
+// level that cannot be filled entirely is shared. It returns the quantity
+// allocated to each order.
+func allocate(stock *models.Stock, quantity uint, orders []models.Order) []uint {
+	fills := make([]uint, len(orders))
+	for start := 0; start < len(orders) && quantity > 0; {
+		// Find the end of the price level
+		end := start + 1
+		for end < len(orders) && orders[end].Price == orders[start].Price {
+			end++
+		}
+
+		level, levelFills := orders[start:end], fills[start:end]
+		switch stock.Allocation {
+		case models.AllocationProRata:
+			quantity -= allocateProRata(quantity, level, levelFills, stock.MinAllocation)
+		case models.AllocationTopOrderProRata:
+			// Only the best price level has a top order
+			if start == 0 {
+				levelFills[0] = min(quantity, remaining(&level[0]))
+				quantity -= levelFills[0]
+				level, levelFills = level[1:], levelFills[1:]
+			}
+			quantity -= allocateProRata(quantity, level, levelFills, stock.MinAllocation)
+		default:
+			quantity -= allocateFIFO(quantity, level, levelFills)
+		}
+		start = end
+	}
+	return fills
+}
+
+// allocateFIFO fills the orders of a level in time priority and returns the
+// quantity allocated
+func allocateFIFO(quantity uint, level []models.Order, fills []uint) uint {
+	var allocated uint
+	for i := range level {
+		if allocated == quantity {
+			break
+		}
+		fill := min(quantity-allocated, remaining(&level[i])-fills[i])
+		fills[i] += fill
+		allocated += fill
+	}
+	return allocated
+}
+
+// allocateProRata shares quantity among the orders of a level in proportion
+// to their remaining quantity and returns the quantity allocated. Shares
+// are rounded down and dropped when below minAllocation; what is left over
+// goes to the orders in time priority, which also breaks ties.
+func allocateProRata(quantity uint, level []models.Order, fills []uint, minAllocation uint) uint {
+	var total uint64
+	for i := range level {
+		total += uint64(remaining(&level[i]))
+	}
+	if total == 0 {
+		return 0
+	}
+	if uint64(quantity) >= total {
+		return allocateFIFO(quantity, level, fills)
+	}
+
+	var allocated uint
+	for i := range level {
+		share := uint(uint64(quantity) * uint64(remaining(&level[i])) / total)
+		if share < minAllocation {
+			continue
+		}
+		fills[i] += share
+		allocated += share
+	}
+	return allocated + allocateFIFO(quantity-allocated, level, fills)
+}
+
+// allocateConstrained allocates the remaining quantity of order among the
+// resting orders like allocate, honouring the fill constraints of both
+// sides. A resting order that would be filled below its minimum is skipped,
+// keeping its place in the book, and the quantity is allocated again among
+// the others. If the order itself cannot fill its own minimum nothing is
+// allocated.
+func allocateConstrained(stock *models.Stock, order *models.Order, orders []models.Order) []uint {
+	candidates := orders
+	indexes := make([]int, len(orders))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	for {
+		fills := allocate(stock, remaining(order), candidates)
+
+		var kept []models.Order
+		var keptIndexes []int
+		for i := range candidates {
+			if fills[i] > 0 && fills[i] < minFill(&candidates[i]) {
+				continue
+			}
+			kept = append(kept, candidates[i])
+			keptIndexes = append(keptIndexes, indexes[i])
+		}
+		if len(kept) < len(candidates) {
+			candidates, indexes = kept, keptIndexes
+			continue
+		}
+
+		result := make([]uint, len(orders))
+		var total uint
+		for i, fill := range fills {
+			result[indexes[i]] = fill
+			total += fill
+		}
+		if total < minFill(order) {
+			return make([]uint, len(orders))
+		}
+		return result
+	}
+}
+
+// minFill returns the smallest quantity an execution of the order may fill
+func minFill(order *models.Order) uint {
+	if order.AllOrNone {
+		return remaining(order)
+	}
+	return min(order.MinQuantity, remaining(order))
+}
+
+// remaining returns the open quantity of an order
+func remaining(order *models.Order) uint {
+	return order.Quantity - order.FilledQuantity
+}
@@ -0,0 +1,106 @@
+package order_matcher
+
+import (
+	"order-matching/api/v1/models"
+	"reflect"
+	"testing"
+)
+
+// resting returns resting orders of the given remaining quantities at price,
+// in time priority
+func resting(price float64, quantities ...uint) []models.Order {
+	orders := make([]models.Order, len(quantities))
+	for i, quantity := range quantities {
+		orders[i] = models.Order{Quantity: quantity, Price: price}
+	}
+	return orders
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name          string
+		allocation    models.AllocationAlgorithm
+		minAllocation uint
+		quantity      uint
+		orders        []models.Order
+		want          []uint
+	}{
+		{"fifo", models.AllocationFIFO, 0, 150, resting(10, 100, 200, 700), []uint{100, 50, 0}},
+		{"fifo across levels", models.AllocationFIFO, 0, 250, append(resting(10, 100, 100), resting(11, 100)...), []uint{100, 100, 50}},
+		{"pro-rata", models.AllocationProRata, 0, 100, resting(10, 100, 200, 700), []uint{10, 20, 70}},
+		{"pro-rata with minimum", models.AllocationProRata, 50, 100, resting(10, 100, 200, 700), []uint{30, 0, 70}},
+		{"pro-rata remainder in time priority", models.AllocationProRata, 0, 10, resting(10, 100, 100, 100), []uint{4, 3, 3}},
+		{"pro-rata fills better level first", models.AllocationProRata, 0, 150, append(resting(10, 100), resting(11, 100, 300)...), []uint{100, 13, 37}},
+		{"pro-rata covering the level", models.AllocationProRata, 0, 1200, resting(10, 100, 200, 700), []uint{100, 200, 700}},
+		{"top order pro-rata", models.AllocationTopOrderProRata, 0, 100, resting(10, 100, 200, 700), []uint{100, 0, 0}},
+		{"top order pro-rata shares the rest", models.AllocationTopOrderProRata, 0, 400, resting(10, 100, 200, 700), []uint{100, 67, 233}},
+		{"top order only on best level", models.AllocationTopOrderProRata, 0, 150, append(resting(10, 100), resting(11, 100, 300)...), []uint{100, 13, 37}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stock := &models.Stock{Allocation: tt.allocation, MinAllocation: tt.minAllocation}
+			if got := allocate(stock, tt.quantity, tt.orders); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("allocate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateConstrainedSkipsOrdersBelowTheirMinimum(t *testing.T) {
+	stock := &models.Stock{Allocation: models.AllocationFIFO}
+	orders := resting(10, 100, 50, 50)
+	orders[0].AllOrNone = true
+	incoming := &models.Order{Quantity: 60}
+
+	if got, want := allocateConstrained(stock, incoming, orders), []uint{0, 50, 10}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("allocateConstrained = %v, want %v", got, want)
+	}
+
+	incoming = &models.Order{Quantity: 210, AllOrNone: true}
+	if got, want := allocateConstrained(stock, incoming, orders), []uint{0, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("allocateConstrained short of the incoming order = %v, want %v", got, want)
+	}
+}
+
+func TestSkippedOrdersKeepTheirPriority(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint func(order *models.Order)
+		skip, fill uint // Quantities bought while skipped, then after
+	}{
+		{"all or none", func(o *models.Order) { o.AllOrNone = true }, 60, 100},
+		{"minimum quantity", func(o *models.Order) { o.MinQuantity = 40 }, 30, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, repo := newTestMatcher(t)
+			constrained := limit(models.OrderTypeSell, 100, 10)
+			tt.constraint(constrained)
+			plain := limit(models.OrderTypeSell, 100, 10)
+			place(t, m, constrained, plain)
+
+			place(t, m, limit(models.OrderTypeBuy, tt.skip, 10))
+			if o := reload(t, repo, constrained); o.FilledQuantity != 0 {
+				t.Fatalf("constrained order filled %d, want 0", o.FilledQuantity)
+			}
+			if o := reload(t, repo, plain); o.FilledQuantity != tt.skip {
+				t.Fatalf("next order filled %d, want %d", o.FilledQuantity, tt.skip)
+			}
+
+			// A later order behind both does not overtake the skipped one
+			later := limit(models.OrderTypeSell, 100, 10)
+			place(t, m, later)
+			if len(m.SellOrders) != 3 || m.SellOrders[0].ID != constrained.ID {
+				t.Fatalf("book = %+v, want the constrained order first", m.SellOrders)
+			}
+
+			place(t, m, limit(models.OrderTypeBuy, tt.fill, 10))
+			if o := reload(t, repo, constrained); o.FilledQuantity != tt.fill {
+				t.Errorf("constrained order filled %d, want %d", o.FilledQuantity, tt.fill)
+			}
+			if o := reload(t, repo, later); o.FilledQuantity != 0 {
+				t.Errorf("later order filled %d, want 0", o.FilledQuantity)
+			}
+		})
+	}
+}
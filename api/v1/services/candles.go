@@ -0,0 +1,154 @@
+package order_matcher
+
+import (
+	"database/sql"
+	"fmt"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"time"
+)
+
+// recordCandles adds the trades of a stock to its candles of every interval
+// within the transaction tx. Trades are bucketed by their execution time, as
+// when the candles are rebuilt, and must be in execution order.
+func recordCandles(tx repository.Repository, symbol models.StockSymbol, trades []*models.Trade) error {
+	for _, interval := range models.CandleIntervals {
+		var candle *models.Candle
+		for _, trade := range trades {
+			open := interval.OpenTime(trade.ExecutedAt)
+			if candle != nil && !candle.OpenTime.Equal(open) {
+				if err := tx.SaveCandle(candle); err != nil {
+					return fmt.Errorf("failed to save %s candle: %v", interval, err)
+				}
+				candle = nil
+			}
+			if candle == nil {
+				stored, err := tx.GetCandle(symbol, interval, open)
+				if err != nil && err != sql.ErrNoRows {
+					return fmt.Errorf("failed to get %s candle: %v", interval, err)
+				}
+				candle = stored
+			}
+
+			if candle == nil {
+				candle = models.NewCandle(symbol, interval, trade.ExecutedAt, trade.Price, trade.Quantity)
+			} else {
+				candle.Add(trade.Price, trade.Quantity)
+			}
+		}
+
+		if candle != nil {
+			if err := tx.SaveCandle(candle); err != nil {
+				return fmt.Errorf("failed to save %s candle: %v", interval, err)
+			}
+		}
+	}
+	return nil
+}
+
+// BackfillCandles rebuilds the candles of a stock from its trades and
+// returns the number of trades aggregated. Matching is paused meanwhile so
+// that no trade is missed or counted twice.
+func (m *OrderMatcher) BackfillCandles(symbol models.StockSymbol) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	err := m.repo.Transact(func(tx repository.Repository) error {
+		var err error
+		count, err = rebuildCandles(tx, symbol)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// rebuildCandles replaces the candles of a stock with candles aggregated
+// from its market trades within the transaction tx and returns the number
+// of trades aggregated
+func rebuildCandles(tx repository.Repository, symbol models.StockSymbol) (int, error) {
+	if err := tx.DeleteCandles(symbol); err != nil {
+		return 0, fmt.Errorf("failed to delete candles: %v", err)
+	}
+
+	// Trades are read oldest first, so each interval only needs its latest
+	// candle, which is saved once the next one opens
+	count := 0
+	open := make(map[models.CandleInterval]*models.Candle)
+	err := eachMarketTrade(tx, models.TradeFilter{Symbol: symbol}, func(trade *models.Trade) error {
+		for _, interval := range models.CandleIntervals {
+			candle := open[interval]
+			if candle != nil && candle.OpenTime.Equal(interval.OpenTime(trade.ExecutedAt)) {
+				candle.Add(trade.Price, trade.Quantity)
+				continue
+			}
+			if candle != nil {
+				if err := tx.SaveCandle(candle); err != nil {
+					return fmt.Errorf("failed to save %s candle: %v", interval, err)
+				}
+			}
+			open[interval] = models.NewCandle(symbol, interval, trade.ExecutedAt, trade.Price, trade.Quantity)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for interval, candle := range open {
+		if err := tx.SaveCandle(candle); err != nil {
+			return 0, fmt.Errorf("failed to save %s candle: %v", interval, err)
+		}
+	}
+	return count, nil
+}
+
+// rebuildCandlesAt recomputes the candles of a stock containing the time at
+// from its market trades within the transaction tx, deleting those left
+// without trades. Candles are aligned to UTC, so the widest one holds all
+// the others and its trades are read once.
+func rebuildCandlesAt(tx repository.Repository, symbol models.StockSymbol, at time.Time) error {
+	widest := models.CandleIntervals[0]
+	for _, interval := range models.CandleIntervals {
+		if interval.Duration() > widest.Duration() {
+			widest = interval
+		}
+	}
+
+	candles := make(map[models.CandleInterval]*models.Candle)
+	from := widest.OpenTime(at)
+	filter := models.TradeFilter{Symbol: symbol, From: from, To: from.Add(widest.Duration())}
+	err := eachMarketTrade(tx, filter, func(trade *models.Trade) error {
+		for _, interval := range models.CandleIntervals {
+			if !interval.OpenTime(trade.ExecutedAt).Equal(interval.OpenTime(at)) {
+				continue
+			}
+			if candle := candles[interval]; candle != nil {
+				candle.Add(trade.Price, trade.Quantity)
+			} else {
+				candles[interval] = models.NewCandle(symbol, interval, trade.ExecutedAt, trade.Price, trade.Quantity)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, interval := range models.CandleIntervals {
+		candle, ok := candles[interval]
+		if !ok {
+			if err := tx.DeleteCandle(symbol, interval, interval.OpenTime(at)); err != nil {
+				return fmt.Errorf("failed to delete %s candle: %v", interval, err)
+			}
+			continue
+		}
+		if err := tx.SaveCandle(candle); err != nil {
+			return fmt.Errorf("failed to save %s candle: %v", interval, err)
+		}
+	}
+	return nil
+}
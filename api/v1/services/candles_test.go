@@ -0,0 +1,69 @@
+package order_matcher
+
+import (
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// candles returns the candles of COGNT by interval
+func candles(t *testing.T, repo repository.Repository) map[models.CandleInterval][]models.Candle {
+	t.Helper()
+	all := make(map[models.CandleInterval][]models.Candle)
+	for _, interval := range models.CandleIntervals {
+		list, err := repo.ListCandles(models.CandleFilter{Symbol: "COGNT", Interval: interval, Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		all[interval] = list
+	}
+	return all
+}
+
+func TestLiveCandlesMatchRebuiltCandles(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	sell := limit(models.OrderTypeSell, 10, 10)
+	buy := limit(models.OrderTypeBuy, 10, 10)
+	place(t, m, sell, buy)
+
+	// One batch of executions straddling the end of a second
+	boundary := time.Date(2026, 3, 4, 12, 0, 1, 0, time.UTC)
+	var batch []*models.Trade
+	for i, at := range []time.Time{boundary.Add(-time.Microsecond), boundary} {
+		trade := &models.Trade{BuyOrderID: buy.ID, SellOrderID: sell.ID, StockSymbol: "COGNT", Quantity: uint(i + 1), Price: float64(20 + i), ExecutedAt: at}
+		if err := repo.CreateTrade(trade); err != nil {
+			t.Fatal(err)
+		}
+		batch = append(batch, trade)
+	}
+	if err := recordCandles(repo, "COGNT", batch); err != nil {
+		t.Fatal(err)
+	}
+	live := candles(t, repo)
+
+	var seconds int
+	for _, candle := range live[models.CandleInterval1s] {
+		if candle.OpenTime.Before(boundary.Add(time.Second)) && !candle.OpenTime.Before(boundary.Add(-time.Second)) {
+			seconds++
+		}
+	}
+	if seconds != 2 {
+		t.Fatalf("1s candles around the boundary = %d, want 2: %+v", seconds, live[models.CandleInterval1s])
+	}
+
+	if _, err := m.BackfillCandles("COGNT"); err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := candles(t, repo); !reflect.DeepEqual(rebuilt, live) {
+		t.Fatalf("rebuilt candles = %+v, want %+v", rebuilt, live)
+	}
+
+	if err := rebuildCandlesAt(repo, "COGNT", boundary); err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := candles(t, repo); !reflect.DeepEqual(rebuilt, live) {
+		t.Fatalf("candles rebuilt at the boundary = %+v, want %+v", rebuilt, live)
+	}
+}
@@ -0,0 +1,181 @@
+package order_matcher
+
+import (
+	"errors"
+	"fmt"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+	"time"
+)
+
+var (
+	// ErrTradeCorrected is returned for the bust or correction of a trade
+	// that was already busted or corrected
+	ErrTradeCorrected = errors.New("trade has already been busted or corrected")
+
+	// ErrCorrectionOverfills is returned for a correction that would fill
+	// an order beyond its quantity
+	ErrCorrectionOverfills = errors.New("correction would fill an order beyond its quantity")
+)
+
+// CorrectTrade busts or corrects a trade. The correction must have its
+// trade, type, reason and operator set, and for a correction the corrected
+// price and quantity; its ID and NewTradeID are filled in.
+//
+// The trade itself is left as executed and linked to the correction. A
+// correction books a replacement trade at the corrected price and quantity.
+// The fills of both orders move by the difference in quantity and their
+// status follows: an open order keeps resting with its restored quantity.
+// The two orders of a trade cross each other, so at most one of them may be
+// reopened once filled. The order that rested first is reopened and matched
+// again like an arriving order, unless the other is still open; the
+// restored quantity of any other filled order is cancelled. The session
+// statistics, tickers and the candles containing the trade are recomputed
+// without it. Corrections do not trigger stop orders. There are no balances
+// or positions to reverse.
+func (m *OrderMatcher) CorrectTrade(correction *models.TradeCorrection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var trade *models.Trade
+	var orders []models.Order
+	var reopened *models.Order
+	var repriced matchResult
+	err := m.transact(func(tx repository.Repository) error {
+		orders, reopened, repriced = nil, nil, matchResult{}
+
+		var err error
+		if trade, err = tx.GetTradeByID(correction.TradeID); err != nil {
+			return fmt.Errorf("failed to get trade: %v", err)
+		}
+		if trade.CorrectionID != 0 {
+			return ErrTradeCorrected
+		}
+
+		// Restore the fills of both orders, the one that rested first last
+		buy, err := tx.GetOrderByID(trade.BuyOrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order: %v", err)
+		}
+		sell, err := tx.GetOrderByID(trade.SellOrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order: %v", err)
+		}
+		maker, taker := buy, sell
+		if sell.ID < buy.ID {
+			maker, taker = sell, buy
+		}
+		for _, order := range []*models.Order{taker, maker} {
+			filled := order.FilledQuantity - trade.Quantity + correction.Quantity
+			if filled > order.Quantity {
+				return ErrCorrectionOverfills
+			}
+			reopen := order == maker && !trade.Negotiated && !isOpen(taker)
+			status := restoredStatus(order, filled, reopen)
+			if order.Status == models.OrderStatusMatched && status != models.OrderStatusMatched && status != models.OrderStatusCancelled {
+				reopened = order
+			}
+			order.Status = status
+			order.FilledQuantity = filled
+			if err := tx.UpdateOrder(order); err != nil {
+				return fmt.Errorf("failed to update order: %v", err)
+			}
+			orders = append(orders, *order)
+		}
+
+		// The reopened order enters the book through the matcher after
+		// the correction
+		for _, order := range orders {
+			if reopened == nil || order.ID != reopened.ID {
+				m.updateBook(order)
+			}
+		}
+		if err := m.repricePegs(tx, trade.StockSymbol, &repriced); err != nil {
+			return err
+		}
+
+		if correction.Type == models.TradeCorrect {
+			replacement := &models.Trade{Price: correction.Price, Quantity: correction.Quantity}
+			if err := tx.CreateCorrectedTrade(replacement, trade.ID); err != nil {
+				return fmt.Errorf("failed to create corrected trade: %v", err)
+			}
+			correction.NewTradeID = replacement.ID
+		}
+		if err := tx.CreateTradeCorrection(correction); err != nil {
+			return fmt.Errorf("failed to record correction: %v", err)
+		}
+
+		// Negotiated trades never reached the market data
+		if trade.Negotiated {
+			return nil
+		}
+		stock, err := tx.GetStockBySymbol(trade.StockSymbol)
+		if err != nil {
+			return fmt.Errorf("failed to get stock: %v", err)
+		}
+		if stock.InSession(trade.ExecutedAt) {
+			if err := replaySession(tx, stock); err != nil {
+				return err
+			}
+		}
+		return rebuildCandlesAt(tx, trade.StockSymbol, trade.ExecutedAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		m.publishOrder(order)
+	}
+	m.bookChanged(trade.StockSymbol, &repriced)
+	m.settleGroups(orders...)
+	m.publishCorrection(*trade, *correction)
+
+	if !trade.Negotiated {
+		if err := m.reloadTicker(trade.StockSymbol); err != nil {
+			logger.Error(err, fmt.Sprintf("Failed to reload the ticker of %s", trade.StockSymbol))
+		}
+	}
+
+	if reopened != nil {
+		if err := m.process(reopened, false); err != nil && err != ErrPostOnlyRejected {
+			logger.Error(err, fmt.Sprintf("Failed to match reopened order %d", reopened.ID))
+		}
+	}
+	return nil
+}
+
+// reloadTicker reloads the trades of a stock's ticker from the repository.
+// The caller must hold m.mu.
+func (m *OrderMatcher) reloadTicker(symbol models.StockSymbol) error {
+	var trades []models.Trade
+	filter := models.TradeFilter{Symbol: symbol, From: time.Now().Add(-tickerWindow)}
+	err := eachMarketTrade(m.repo, filter, func(trade *models.Trade) error {
+		trades = append(trades, *trade)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.tickers.resetTrades(symbol, trades)
+	return nil
+}
+
+// restoredStatus returns the status of an order whose filled quantity
+// changes to filled through a correction. A filled order is reopened if
+// reopen is set and cancelled otherwise; market orders never rest.
+func restoredStatus(order *models.Order, filled uint, reopen bool) models.OrderStatus {
+	switch {
+	case order.Status == models.OrderStatusCancelled || order.Status == models.OrderStatusTriggered:
+		return order.Status
+	case filled == order.Quantity:
+		return models.OrderStatusMatched
+	case order.Status == models.OrderStatusMatched && (!reopen || order.Category == models.OrderCategoryMarket):
+		return models.OrderStatusCancelled
+	case filled == 0:
+		return models.OrderStatusPending
+	}
+	return models.OrderStatusPartiallyFilled
+}
@@ -0,0 +1,83 @@
+package order_matcher
+
+import (
+	"order-matching/api/v1/models"
+	"testing"
+	"time"
+)
+
+// bust busts a trade, failing the test on error
+func bust(t *testing.T, m *OrderMatcher, trade models.Trade) {
+	t.Helper()
+	correction := &models.TradeCorrection{TradeID: trade.ID, Type: models.TradeBust, Reason: "test", UserID: 1}
+	if err := m.CorrectTrade(correction); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBustReopensTheRestingOrder(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	maker := limit(models.OrderTypeSell, 10, 10)
+	taker := limit(models.OrderTypeBuy, 10, 10)
+	place(t, m, maker, taker)
+	bust(t, m, trades(t, repo)[0])
+
+	if o := reload(t, repo, maker); o.Status != models.OrderStatusPending || o.FilledQuantity != 0 {
+		t.Errorf("maker = %s with %d filled, want PENDING with 0", o.Status, o.FilledQuantity)
+	}
+	if o := reload(t, repo, taker); o.Status != models.OrderStatusCancelled {
+		t.Errorf("taker = %s, want CANCELLED", o.Status)
+	}
+
+	// The reopened order trades again
+	buy := limit(models.OrderTypeBuy, 10, 10)
+	place(t, m, buy)
+	if o := reload(t, repo, buy); o.FilledQuantity != 10 {
+		t.Errorf("new buy filled %d, want 10", o.FilledQuantity)
+	}
+}
+
+func TestBustKeepsTheOpenOrderOnly(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	maker := limit(models.OrderTypeSell, 10, 10)
+	taker := limit(models.OrderTypeBuy, 15, 10)
+	place(t, m, maker, taker)
+	bust(t, m, trades(t, repo)[0])
+
+	if o := reload(t, repo, maker); o.Status != models.OrderStatusCancelled {
+		t.Errorf("maker = %s, want CANCELLED", o.Status)
+	}
+	if o := reload(t, repo, taker); o.Status != models.OrderStatusPending || o.FilledQuantity != 0 {
+		t.Errorf("taker = %s with %d filled, want PENDING with 0", o.Status, o.FilledQuantity)
+	}
+	if n := len(trades(t, repo)); n != 1 {
+		t.Errorf("%d trades, want 1", n)
+	}
+}
+
+func TestBustRebuildsOnlyTheCandlesOfTheTrade(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	earlier := models.NewCandle("COGNT", models.CandleInterval1d, time.Now().AddDate(0, 0, -2), 5, 1)
+	if err := repo.SaveCandle(earlier); err != nil {
+		t.Fatal(err)
+	}
+	place(t, m, limit(models.OrderTypeSell, 10, 10), limit(models.OrderTypeBuy, 10, 10))
+	bust(t, m, trades(t, repo)[0])
+
+	candles, err := repo.ListCandles(models.CandleFilter{Symbol: "COGNT", Interval: models.CandleInterval1d, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 1 || !candles[0].OpenTime.Equal(earlier.OpenTime) {
+		t.Fatalf("1d candles = %+v, want only the earlier one", candles)
+	}
+	for _, interval := range models.CandleIntervals {
+		candles, err := repo.ListCandles(models.CandleFilter{Symbol: "COGNT", Interval: interval, From: earlier.OpenTime.Add(time.Hour), Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(candles) != 0 {
+			t.Errorf("%s candles of the busted trade were kept: %+v", interval, candles)
+		}
+	}
+}
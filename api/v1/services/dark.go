@@ -0,0 +1,125 @@
+package order_matcher
+
+import (
+	"fmt"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+	"time"
+)
+
+// Hidden orders rest in a dark book per stock that is never displayed: they
+// stay out of the in-memory book, the tickers and the public order lists.
+// They only execute at the midpoint of the lit best bid and offer, the one
+// midpoint pegs follow, and only while both sides of the lit book are
+// quoted. The
+// price of a hidden order is the worst midpoint it accepts. Hidden orders
+// match each other in time priority when one arrives and again whenever the
+// lit book moves the midpoint, and arriving lit orders take from them first
+// when HiddenMatchLit is set.
+
+// matchDark matches order against the hidden orders of the other side at
+// the midpoint within the transaction tx. The caller must hold m.mu.
+func (m *OrderMatcher) matchDark(tx repository.Repository, order *models.Order, result *matchResult, now time.Time) error {
+	mid := m.midpoint(m.bestPrices(order.StockSymbol))
+	if mid == 0 {
+		return nil
+	}
+	if order.Category != models.OrderCategoryMarket {
+		if (order.Type == models.OrderTypeBuy && order.Price < mid) || (order.Type == models.OrderTypeSell && order.Price > mid) {
+			return nil
+		}
+	}
+
+	darkOrders, err := tx.GetDarkOrders(order, mid)
+	if err != nil {
+		return fmt.Errorf("failed to get dark orders: %v", err)
+	}
+
+	// All executions are at the midpoint, so the dark book is allocated as
+	// a single level in time priority
+	level := make([]models.Order, len(darkOrders))
+	for i, o := range darkOrders {
+		level[i] = o
+		level[i].Price = mid
+	}
+	fills := allocateConstrained(&models.Stock{Allocation: models.AllocationFIFO}, order, level)
+
+	for i, darkOrder := range darkOrders {
+		if fills[i] == 0 {
+			continue
+		}
+		trade := &models.Trade{Quantity: fills[i], Price: mid, Hidden: true, ExecutedAt: now}
+		if err := execute(tx, order, darkOrder, trade, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crossDark matches the hidden orders of a stock against each other once
+// the lit book moved its midpoint, as resting hidden orders may cross at
+// the new one. Hidden buys take from the hidden sells in time priority.
+// The caller must hold m.mu.
+func (m *OrderMatcher) crossDark(symbol models.StockSymbol) {
+	mid := m.midpoint(m.bestPrices(symbol))
+	if mid == m.darkMids[symbol] {
+		return
+	}
+	m.darkMids[symbol] = mid
+	if mid == 0 {
+		return
+	}
+
+	now := models.ExecutionTime()
+	var result matchResult
+	var takers []models.Order
+	err := m.repo.Transact(func(tx repository.Repository) error {
+		result, takers = matchResult{}, nil
+
+		buys, err := tx.GetDarkOrders(&models.Order{Type: models.OrderTypeSell, StockSymbol: symbol}, mid)
+		if err != nil {
+			return fmt.Errorf("failed to get dark orders: %v", err)
+		}
+		for i := range buys {
+			trades := len(result.trades)
+			if err := m.matchDark(tx, &buys[i], &result, now); err != nil {
+				return err
+			}
+			if len(result.trades) == trades {
+				continue
+			}
+			if err := tx.UpdateOrder(&buys[i]); err != nil {
+				return fmt.Errorf("failed to update order: %v", err)
+			}
+			takers = append(takers, buys[i])
+		}
+
+		if len(result.trades) == 0 {
+			return nil
+		}
+		if err := recordTrades(tx, symbol, result.trades, now); err != nil {
+			return err
+		}
+		return recordCandles(tx, symbol, result.trades)
+	})
+	if err != nil {
+		// The next change of the midpoint retries
+		logger.Error(err, fmt.Sprintf("Failed to cross the dark book of %s", symbol))
+		delete(m.darkMids, symbol)
+		return
+	}
+	if len(result.trades) == 0 {
+		return
+	}
+
+	for _, trade := range result.trades {
+		m.publishTrade(*trade)
+	}
+	updated := append(result.updated, takers...)
+	for _, order := range updated {
+		m.publishOrder(order)
+	}
+	m.settleGroups(updated...)
+	m.runStops(symbol, result.trades)
+}
@@ -0,0 +1,60 @@
+package order_matcher
+
+import (
+	"order-matching/api/v1/models"
+	"testing"
+)
+
+func TestHiddenOrdersCrossWhenTheMidpointMoves(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	buy := limit(models.OrderTypeBuy, 10, 11)
+	buy.Hidden = true
+	sell := limit(models.OrderTypeSell, 10, 9)
+	sell.Hidden = true
+
+	// Without a lit midpoint the hidden orders rest
+	place(t, m, buy, sell)
+	if got := trades(t, repo); len(got) != 0 {
+		t.Fatalf("trades without midpoint = %+v", got)
+	}
+
+	// Quoting both sides of the lit book crosses them at the midpoint
+	bid, ask := limit(models.OrderTypeBuy, 5, 10), limit(models.OrderTypeSell, 5, 11)
+	place(t, m, bid, ask)
+	got := trades(t, repo)
+	if len(got) != 1 || got[0].Quantity != 10 || got[0].Price != 10.5 || !got[0].Hidden {
+		t.Fatalf("trades = %+v, want a hidden 10 @ 10.5", got)
+	}
+	for _, order := range []*models.Order{buy, sell} {
+		if o := reload(t, repo, order); o.Status != models.OrderStatusMatched {
+			t.Errorf("hidden order %d is %s, want MATCHED", o.ID, o.Status)
+		}
+	}
+	for _, order := range []*models.Order{bid, ask} {
+		if o := reload(t, repo, order); o.FilledQuantity != 0 {
+			t.Errorf("lit order %d filled %d, want 0", o.ID, o.FilledQuantity)
+		}
+	}
+}
+
+func TestHiddenOrdersAndMidpointPegsShareTheLitMidpoint(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	peg := &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryPegged, PegType: models.PegMidpoint, StockSymbol: "COGNT", Quantity: 10, Status: models.OrderStatusPending, UserID: 1}
+	place(t, m, limit(models.OrderTypeBuy, 5, 10), limit(models.OrderTypeSell, 5, 10.03), peg)
+
+	// The peg rests at the midpoint rounded away from the asks and leads the
+	// bids without moving the midpoint
+	if o := reload(t, repo, peg); o.Price != 10.01 {
+		t.Fatalf("peg price = %v, want 10.01", o.Price)
+	}
+
+	buy := limit(models.OrderTypeBuy, 10, 10.02)
+	buy.Hidden = true
+	sell := limit(models.OrderTypeSell, 10, 10.01)
+	sell.Hidden = true
+	place(t, m, buy, sell)
+	got := trades(t, repo)
+	if len(got) != 1 || got[0].Price != 10.015 || !got[0].Hidden {
+		t.Fatalf("trades = %+v, want a hidden trade at 10.015", got)
+	}
+}
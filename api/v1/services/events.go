@@ -0,0 +1,76 @@
+package order_matcher
+
+import "order-matching/api/v1/models"
+
+// EventType identifies the kind of a matcher event
+type EventType string
+
+const (
+	// EventOrderUpdated is published when an order is accepted, filled or
+	// cancelled
+	EventOrderUpdated EventType = "ORDER_UPDATED"
+
+	// EventTrade is published for every execution
+	EventTrade EventType = "TRADE"
+
+	// EventTradeCorrected is published when an operator busts or corrects
+	// a trade
+	EventTradeCorrected EventType = "TRADE_CORRECTED"
+)
+
+// Event describes a committed change made by the matcher. Order is set for
+// order events and Trade, with its buy and sell orders, for trade events.
+// Correction events carry the corrected trade as it was executed and the
+// Correction. All are copies owned by the listener.
+type Event struct {
+	Type       EventType
+	Order      *models.Order
+	Trade      *models.Trade
+	Correction *models.TradeCorrection
+}
+
+// Listener receives matcher events. Listeners are called synchronously, in
+// commit order, while the matcher is locked, so they must not block or call
+// back into the matcher.
+type Listener func(event Event)
+
+// Subscribe registers a listener for the events of every later change
+func (m *OrderMatcher) Subscribe(listener Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.listeners = append(m.listeners, listener)
+}
+
+// publishOrder notifies the listeners of an order's new state
+func (m *OrderMatcher) publishOrder(order models.Order) {
+	order.Stock = nil
+	for _, listener := range m.listeners {
+		o := order
+		listener(Event{Type: EventOrderUpdated, Order: &o})
+	}
+}
+
+// publishTrade notifies the listeners of an execution
+func (m *OrderMatcher) publishTrade(trade models.Trade) {
+	trade.Stock = nil
+	for _, listener := range m.listeners {
+		t := trade
+		if trade.BuyOrder != nil && trade.SellOrder != nil {
+			buy, sell := *trade.BuyOrder, *trade.SellOrder
+			buy.Stock, sell.Stock = nil, nil
+			t.BuyOrder, t.SellOrder = &buy, &sell
+		}
+		listener(Event{Type: EventTrade, Trade: &t})
+	}
+}
+
+// publishCorrection notifies the listeners of the bust or correction of a
+// trade
+func (m *OrderMatcher) publishCorrection(trade models.Trade, correction models.TradeCorrection) {
+	trade.BuyOrder, trade.SellOrder, trade.Stock = nil, nil, nil
+	for _, listener := range m.listeners {
+		t, c := trade, correction
+		listener(Event{Type: EventTradeCorrected, Trade: &t, Correction: &c})
+	}
+}
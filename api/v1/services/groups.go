@@ -0,0 +1,260 @@
+package order_matcher
+
+import (
+	"fmt"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+)
+
+// Order groups are settled whenever one of their orders changes. The legs
+// of an OCO group, and the exits of a bracket, cancel each other: the first
+// leg to fill or trigger completes the group and cancels the open legs,
+// and a leg cancelled first cancels the group. A bracket entry activates
+// the exits once it is no longer open, sized to its filled quantity; an
+// entry cancelled without fills cancels the group.
+
+// ProcessGroup creates an order group and its orders and matches the
+// orders, all in one transaction. OCO groups are given their two legs and
+// bracket groups their entry order.
+func (m *OrderMatcher) ProcessGroup(group *models.OrderGroup, orders []*models.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]matchResult, len(orders))
+	var repriced matchResult
+	err := m.transact(func(tx repository.Repository) error {
+		repriced = matchResult{}
+		group.Status = models.OrderGroupActive
+		if err := tx.CreateOrderGroup(group); err != nil {
+			return fmt.Errorf("failed to create order group: %v", err)
+		}
+		for i, order := range orders {
+			results[i] = matchResult{}
+			order.GroupID = group.ID
+			if err := tx.CreateOrder(order); err != nil {
+				return fmt.Errorf("failed to create order: %v", err)
+			}
+			if err := m.matchOrder(tx, order, &results[i]); err != nil {
+				return err
+			}
+			m.stage(order, &results[i])
+		}
+		return m.repricePegs(tx, group.StockSymbol, &repriced)
+	})
+	if err != nil {
+		return err
+	}
+
+	var changed []models.Order
+	var trades []*models.Trade
+	for i, order := range orders {
+		m.apply(order, &results[i])
+		changed = append(append(changed, results[i].updated...), *order)
+		trades = append(trades, results[i].trades...)
+	}
+	m.bookChanged(group.StockSymbol, &repriced)
+	m.settleGroups(changed...)
+	m.runStops(group.StockSymbol, trades)
+	return nil
+}
+
+// CancelGroup cancels the open orders of a group and the group itself in a
+// single transaction
+func (m *OrderMatcher) CancelGroup(group *models.OrderGroup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cancelled []models.Order
+	var repriced matchResult
+	err := m.transact(func(tx repository.Repository) error {
+		cancelled, repriced = nil, matchResult{}
+		current, err := tx.GetOrderGroupByID(group.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get order group: %v", err)
+		}
+		*group = *current
+		if group.Status != models.OrderGroupActive {
+			return nil
+		}
+
+		for _, order := range group.Orders {
+			if !isOpen(&order) {
+				continue
+			}
+			order.Status = models.OrderStatusCancelled
+			if err := tx.UpdateOrder(&order); err != nil {
+				return fmt.Errorf("failed to cancel order: %v", err)
+			}
+			m.updateBook(order)
+			cancelled = append(cancelled, order)
+		}
+		group.Status = models.OrderGroupCancelled
+		if err := tx.UpdateOrderGroup(group); err != nil {
+			return err
+		}
+		return m.repricePegs(tx, group.StockSymbol, &repriced)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Notify listeners
+	for _, order := range cancelled {
+		m.publishOrder(order)
+	}
+	m.bookChanged(group.StockSymbol, &repriced)
+	return nil
+}
+
+// settleGroups settles the groups of orders that changed. The caller must
+// hold m.mu.
+func (m *OrderMatcher) settleGroups(orders ...models.Order) {
+	settled := make(map[uint]bool)
+	for _, order := range orders {
+		if order.GroupID == 0 || settled[order.GroupID] {
+			continue
+		}
+		settled[order.GroupID] = true
+		if err := m.settleGroup(order.GroupID); err != nil {
+			logger.Error(err, fmt.Sprintf("Failed to settle order group %d", order.GroupID))
+		}
+	}
+}
+
+// settleGroup cancels the legs of a group made obsolete by the fill,
+// trigger or cancellation of another and activates the exits of a bracket
+// whose entry is done. The caller must hold m.mu.
+func (m *OrderMatcher) settleGroup(id uint) error {
+	var group *models.OrderGroup
+	var cancelled []models.Order
+	var exits []*models.Order
+	var results []matchResult
+	var repriced matchResult
+	err := m.transact(func(tx repository.Repository) error {
+		cancelled, exits, results, repriced = nil, nil, nil, matchResult{}
+
+		var err error
+		if group, err = tx.GetOrderGroupByID(id); err != nil {
+			return fmt.Errorf("failed to get order group: %v", err)
+		}
+		if group.Status != models.OrderGroupActive || len(group.Orders) == 0 {
+			return nil
+		}
+
+		legs := group.Orders
+		if group.Type == models.OrderGroupBracket {
+			entry := legs[0]
+			legs = legs[1:]
+			if len(legs) == 0 {
+				if isOpen(&entry) {
+					return nil
+				}
+				if entry.FilledQuantity == 0 {
+					group.Status = models.OrderGroupCancelled
+					return tx.UpdateOrderGroup(group)
+				}
+
+				exits = bracketExits(group, &entry)
+				results = make([]matchResult, len(exits))
+				for i, exit := range exits {
+					if err := tx.CreateOrder(exit); err != nil {
+						return fmt.Errorf("failed to create exit order: %v", err)
+					}
+					if err := m.matchOrder(tx, exit, &results[i]); err != nil {
+						return err
+					}
+					m.stage(exit, &results[i])
+				}
+				return m.repricePegs(tx, group.StockSymbol, &repriced)
+			}
+		}
+
+		// The first leg to fill or trigger wins over a cancelled one
+		var winner *models.Order
+		for i := range legs {
+			if legs[i].FilledQuantity > 0 || legs[i].Status == models.OrderStatusTriggered {
+				winner = &legs[i]
+				break
+			}
+			if legs[i].Status == models.OrderStatusCancelled {
+				group.Status = models.OrderGroupCancelled
+			}
+		}
+		if winner != nil {
+			group.Status = models.OrderGroupCompleted
+		}
+		if group.Status == models.OrderGroupActive {
+			return nil
+		}
+
+		for _, leg := range legs {
+			if !isOpen(&leg) || (winner != nil && leg.ID == winner.ID) {
+				continue
+			}
+			leg.Status = models.OrderStatusCancelled
+			if err := tx.UpdateOrder(&leg); err != nil {
+				return fmt.Errorf("failed to cancel order: %v", err)
+			}
+			m.updateBook(leg)
+			cancelled = append(cancelled, leg)
+		}
+		if err := tx.UpdateOrderGroup(group); err != nil {
+			return err
+		}
+		return m.repricePegs(tx, group.StockSymbol, &repriced)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, order := range cancelled {
+		m.publishOrder(order)
+	}
+
+	var changed []models.Order
+	var trades []*models.Trade
+	for i, exit := range exits {
+		m.apply(exit, &results[i])
+		changed = append(append(changed, results[i].updated...), *exit)
+		trades = append(trades, results[i].trades...)
+	}
+	if len(cancelled) > 0 || len(exits) > 0 {
+		m.bookChanged(group.StockSymbol, &repriced)
+	}
+	m.settleGroups(changed...)
+	m.runStops(group.StockSymbol, trades)
+	return nil
+}
+
+// bracketExits returns the exit orders of a bracket for the filled quantity
+// of its entry: a take-profit limit order and a stop order entering a
+// market order
+func bracketExits(group *models.OrderGroup, entry *models.Order) []*models.Order {
+	side := models.OrderTypeSell
+	if entry.Type == models.OrderTypeSell {
+		side = models.OrderTypeBuy
+	}
+
+	takeProfit := &models.Order{
+		Type:        side,
+		Category:    models.OrderCategoryLimit,
+		StockSymbol: group.StockSymbol,
+		Quantity:    entry.FilledQuantity,
+		Price:       group.TakeProfitPrice,
+		Status:      models.OrderStatusPending,
+		UserID:      group.UserID,
+		GroupID:     group.ID,
+	}
+	stop := &models.Order{
+		Type:        side,
+		Category:    models.OrderCategoryStop,
+		StockSymbol: group.StockSymbol,
+		Quantity:    entry.FilledQuantity,
+		StopPrice:   group.StopPrice,
+		Status:      models.OrderStatusPending,
+		UserID:      group.UserID,
+		GroupID:     group.ID,
+	}
+	return []*models.Order{takeProfit, stop}
+}
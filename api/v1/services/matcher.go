@@ -1,39 +1,120 @@
 package order_matcher
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"order-matching/api/v1/config"
 	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"sort"
 	"sync"
+	"time"
 )
 
 // OrderMatcher handles the order matching logic
 type OrderMatcher struct {
 	mu         sync.Mutex
-	db         *sql.DB
-	BuyOrders  []models.Order // Sorted by price (desc) and time (asc)
-	SellOrders []models.Order // Sorted by price (asc) and time (asc)
+	repo       repository.Repository
+	BuyOrders  []models.Order                 // Sorted by price (desc) and time (asc)
+	SellOrders []models.Order                 // Sorted by price (asc) and time (asc)
+	pegs       map[uint]models.Order          // Open pegged orders, priced or held
+	stops      map[uint]models.Order          // Stop orders waiting for their trigger
+	darkMids   map[models.StockSymbol]float64 // Midpoints the dark books were crossed at
+	undo       []func()                       // Restores the book changed by the running transaction
+	listeners  []Listener
+	tickers    *Tickers
+	cfg        *config.MatchingConfig
 }
 
-var (
-	instance *OrderMatcher
-	once     sync.Once
-)
+// ErrPostOnlyRejected is returned for a post-only order that would have
+// matched on arrival and was cancelled instead
+var ErrPostOnlyRejected = errors.New("post-only order would match immediately")
 
-// GetOrderMatcher returns the singleton instance of OrderMatcher
-func GetOrderMatcher() *OrderMatcher {
-	once.Do(func() {
-		instance = &OrderMatcher{
-			BuyOrders:  make([]models.Order, 0),
-			SellOrders: make([]models.Order, 0),
-		}
-	})
-	return instance
+// ErrOrderNotOpen is returned for the cancellation of an order that is no
+// longer pending or partially filled
+var ErrOrderNotOpen = errors.New("order is no longer open")
+
+// matchResult collects the changes made by a match, which are applied to
+// the in-memory state once its transaction commits
+type matchResult struct {
+	trades   []*models.Trade
+	updated  []models.Order // Resting orders filled by the match
+	rejected bool           // The order was cancelled instead of matched
+}
+
+// NewOrderMatcher creates an order matcher that persists through repo
+func NewOrderMatcher(repo repository.Repository) *OrderMatcher {
+	m := &OrderMatcher{
+		repo:       repo,
+		BuyOrders:  make([]models.Order, 0),
+		SellOrders: make([]models.Order, 0),
+		pegs:       make(map[uint]models.Order),
+		stops:      make(map[uint]models.Order),
+		darkMids:   make(map[models.StockSymbol]float64),
+		tickers:    NewTickers(),
+		cfg:        config.DefaultMatchingConfig(),
+	}
+	m.listeners = append(m.listeners, m.tickers.Apply)
+	return m
+}
+
+// Configure replaces the matching configuration. It must be called before
+// any order is processed.
+func (m *OrderMatcher) Configure(cfg *config.MatchingConfig) {
+	m.cfg = cfg
+}
+
+// Config returns the matching configuration
+func (m *OrderMatcher) Config() *config.MatchingConfig {
+	return m.cfg
+}
+
+// Tickers returns the tickers kept current by the matcher
+func (m *OrderMatcher) Tickers() *Tickers {
+	return m.tickers
 }
 
-// SetDB sets the database connection for the order matcher
-func (m *OrderMatcher) SetDB(db *sql.DB) {
-	m.db = db
+// LoadBook fills the in-memory book with the open orders and the tickers
+// with the trades of the last 24 hours. It is meant to run once at startup,
+// before any order is processed.
+func (m *OrderMatcher) LoadBook() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	categories := []models.OrderCategory{
+		models.OrderCategoryLimit, models.OrderCategoryPegged,
+		models.OrderCategoryStop, models.OrderCategoryTrailingStop,
+	}
+	for _, category := range categories {
+		for _, status := range []models.OrderStatus{models.OrderStatusPending, models.OrderStatusPartiallyFilled} {
+			filter := models.OrderFilter{
+				Status:   status,
+				Category: category,
+				Page:     models.Page{Limit: models.MaxPageLimit, Sort: models.SortOldest},
+			}
+			for {
+				page, err := m.repo.ListOrders(filter)
+				if err != nil {
+					return fmt.Errorf("failed to get open orders: %v", err)
+				}
+				for _, order := range page.Orders {
+					m.updateBook(order)
+					m.tickers.Apply(Event{Type: EventOrderUpdated, Order: &order})
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				filter.After = page.NextCursor
+			}
+		}
+	}
+
+	filter := models.TradeFilter{From: time.Now().Add(-tickerWindow)}
+	return eachMarketTrade(m.repo, filter, func(trade *models.Trade) error {
+		m.tickers.Apply(Event{Type: EventTrade, Trade: trade})
+		return nil
+	})
 }
 
 // ProcessOrder processes a new order and attempts to match it
@@ -41,165 +122,423 @@ func (m *OrderMatcher) ProcessOrder(order *models.Order) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Begin transaction
-	tx, err := m.db.Begin()
+	return m.process(order, false)
+}
+
+// ProcessOrders creates and matches new orders in sequence under a single
+// hold of the matcher. Each order is created and matched in its own
+// transaction, so a failed order does not affect the others. It returns the
+// error of each order, nil for the orders that were processed.
+func (m *OrderMatcher) ProcessOrders(orders []*models.Order) []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	errs := make([]error, len(orders))
+	for i, order := range orders {
+		errs[i] = m.process(order, true)
+	}
+	return errs
+}
+
+// process matches an order, first creating it if create is set, and
+// applies the result once committed. The caller must hold m.mu.
+func (m *OrderMatcher) process(order *models.Order, create bool) error {
+	var result, repriced matchResult
+	err := m.transact(func(tx repository.Repository) error {
+		result, repriced = matchResult{}, matchResult{}
+		if create {
+			if err := tx.CreateOrder(order); err != nil {
+				return fmt.Errorf("failed to create order: %v", err)
+			}
+		}
+		if err := m.matchOrder(tx, order, &result); err != nil {
+			return err
+		}
+		m.stage(order, &result)
+		return m.repricePegs(tx, order.StockSymbol, &repriced)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
+		return err
 	}
-	defer tx.Rollback()
 
-	// Process order based on type
-	var matchingOrders []models.Order
-	if order.Type == models.OrderTypeBuy {
-		matchingOrders, err = m.getMatchingSellOrders(tx, order)
-	} else {
-		matchingOrders, err = m.getMatchingBuyOrders(tx, order)
+	m.apply(order, &result)
+	m.bookChanged(order.StockSymbol, &repriced)
+	m.settleGroups(append(result.updated, *order)...)
+	m.runStops(order.StockSymbol, result.trades)
+
+	// A repriced peg may have traded with the order once it rested
+	for _, updated := range repriced.updated {
+		if updated.ID == order.ID {
+			*order = updated
+		}
+	}
+
+	if result.rejected {
+		return ErrPostOnlyRejected
+	}
+	return nil
+}
+
+// apply notifies listeners of the committed changes of a match, which
+// stage already applied to the book. The caller must hold m.mu.
+func (m *OrderMatcher) apply(order *models.Order, result *matchResult) {
+	for _, trade := range result.trades {
+		m.publishTrade(*trade)
 	}
+	for _, matched := range result.updated {
+		m.publishOrder(matched)
+	}
+	m.publishOrder(*order)
+}
+
+// bookChanged applies the committed repricing of the pegged orders of a
+// stock, then crosses the dark book if the midpoint moved. The caller must
+// hold m.mu.
+func (m *OrderMatcher) bookChanged(symbol models.StockSymbol, repriced *matchResult) {
+	for _, trade := range repriced.trades {
+		m.publishTrade(*trade)
+	}
+	for _, order := range repriced.updated {
+		m.publishOrder(order)
+	}
+	m.settleGroups(repriced.updated...)
+	m.runStops(symbol, repriced.trades)
+	m.crossDark(symbol)
+}
+
+// transact runs fn in a transaction of the repository. The changes fn
+// makes to the book through updateBook are undone if the transaction
+// fails, so the book can follow the database within it. The caller must
+// hold m.mu.
+func (m *OrderMatcher) transact(fn func(tx repository.Repository) error) error {
+	m.undo = []func(){}
+	defer func() { m.undo = nil }()
+
+	err := m.repo.Transact(fn)
 	if err != nil {
-		return fmt.Errorf("failed to get matching orders: %v", err)
+		undo := m.undo
+		m.undo = nil
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+	return err
+}
+
+// stage applies a match of order to the book within a transaction
+func (m *OrderMatcher) stage(order *models.Order, result *matchResult) {
+	for _, matched := range result.updated {
+		m.updateBook(matched)
+	}
+	m.updateBook(*order)
+}
+
+// matchOrder matches order against the book within the transaction tx
+func (m *OrderMatcher) matchOrder(tx repository.Repository, order *models.Order, result *matchResult) error {
+	now := models.ExecutionTime()
+
+	// Stop orders wait outside the book for their trigger. A trailing stop
+	// starts trailing from the last trade price of its stock.
+	if isStop(order) {
+		if order.Category == models.OrderCategoryTrailingStop {
+			stock, err := tx.GetStockBySymbol(order.StockSymbol)
+			if err != nil {
+				return fmt.Errorf("failed to get stock: %v", err)
+			}
+			m.trail(order, stock.CurrentPrice)
+		}
+		if err := tx.UpdateOrder(order); err != nil {
+			return fmt.Errorf("failed to update stop order: %v", err)
+		}
+		return nil
+	}
+
+	// A pegged order is priced from the book on arrival and matched as a
+	// limit order. If the book cannot price it yet it is held.
+	if order.Category == models.OrderCategoryPegged {
+		bid, ask := m.bestPrices(order.StockSymbol)
+		order.Price = m.pegPrice(order, bid, ask)
+		if order.Price == 0 {
+			if err := tx.UpdateOrder(order); err != nil {
+				return fmt.Errorf("failed to hold pegged order: %v", err)
+			}
+			return nil
+		}
+	}
+
+	// Hidden orders only match in the dark book. Lit orders may take from
+	// it before the lit book if the configuration allows.
+	if order.Hidden || (m.cfg.HiddenMatchLit && !order.PostOnly) {
+		if err := m.matchDark(tx, order, result, now); err != nil {
+			return err
+		}
+	}
+
+	// Process order based on type
+	var matchingOrders []models.Order
+	if !order.Hidden && isOpen(order) {
+		var err error
+		if matchingOrders, err = tx.GetMatchingOrders(order); err != nil {
+			return fmt.Errorf("failed to get matching orders: %v", err)
+		}
 	}
 
 	// For market orders with no matches, cancel immediately
-	if order.Category == models.OrderCategoryMarket && len(matchingOrders) == 0 {
+	if order.Category == models.OrderCategoryMarket && len(matchingOrders) == 0 && len(result.trades) == 0 {
 		order.Status = models.OrderStatusCancelled
-		if err := m.updateOrder(tx, order); err != nil {
+		if err := tx.UpdateOrder(order); err != nil {
 			return fmt.Errorf("failed to cancel market order: %v", err)
 		}
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %v", err)
-		}
 		return nil
 	}
 
-	// Match orders
-	for _, matchingOrder := range matchingOrders {
-		if order.FilledQuantity >= order.Quantity {
-			break
+	// A post-only order that would take liquidity is rejected or moved one
+	// tick behind the opposite best price, where it cannot match
+	if order.PostOnly && len(matchingOrders) > 0 {
+		price := m.postOnlyPrice(order, matchingOrders[0].Price)
+		if m.cfg.PostOnlyAction == config.PostOnlyReject || price <= 0 {
+			order.Status = models.OrderStatusCancelled
+			if err := tx.UpdateOrder(order); err != nil {
+				return fmt.Errorf("failed to reject post-only order: %v", err)
+			}
+			result.rejected = true
+			return nil
 		}
+		order.Price = price
+		matchingOrders = nil
+	}
+
+	// Split the order among the resting orders by the stock's allocation,
+	// within the minimum and all-or-none constraints of the orders
+	stock, err := tx.GetStockBySymbol(order.StockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to get stock: %v", err)
+	}
+	fills := allocateConstrained(stock, order, matchingOrders)
 
-		// Calculate trade quantity
-		remainingQuantity := order.Quantity - order.FilledQuantity
-		matchingRemaining := matchingOrder.Quantity - matchingOrder.FilledQuantity
-		tradeQuantity := min(remainingQuantity, matchingRemaining)
+	// Match orders
+	for i, matchingOrder := range matchingOrders {
+		if fills[i] == 0 {
+			continue
+		}
 
 		// Determine trade price:
 		// - For limit/limit matches: use the resting order's price
 		// - For market/limit matches: use the limit order's price
+		// Pegged orders are priced like limit orders.
 		var tradePrice float64
-		if order.Category == models.OrderCategoryLimit && matchingOrder.Category == models.OrderCategoryLimit {
+		if order.Category != models.OrderCategoryMarket && matchingOrder.Category != models.OrderCategoryMarket {
 			// Both are limit orders, use the resting (matching) order's price
 			tradePrice = matchingOrder.Price
 		} else {
 			// At least one is a market order, use the limit order's price
-			if order.Category == models.OrderCategoryLimit {
+			if order.Category != models.OrderCategoryMarket {
 				tradePrice = order.Price
 			} else {
 				tradePrice = matchingOrder.Price
 			}
 		}
 
-		// Create trade
-		var buyOrderID, sellOrderID uint
-		if order.Type == models.OrderTypeBuy {
-			buyOrderID = order.ID
-			sellOrderID = matchingOrder.ID
-		} else {
-			buyOrderID = matchingOrder.ID
-			sellOrderID = order.ID
-		}
-
-		trade := &models.Trade{
-			BuyOrderID:  buyOrderID,
-			SellOrderID: sellOrderID,
-			StockSymbol: order.StockSymbol,
-			Quantity:    tradeQuantity,
-			Price:       tradePrice,
-		}
-
-		// Create trade record
-		_, err = tx.Exec(`
-			INSERT INTO trades (buy_order_id, sell_order_id, stock_symbol,
-							quantity, price, executed_at)
-			VALUES (?, ?, ?, ?, ?, NOW())`,
-			trade.BuyOrderID, trade.SellOrderID, trade.StockSymbol,
-			trade.Quantity, trade.Price)
-		if err != nil {
-			return fmt.Errorf("failed to create trade: %v", err)
-		}
-
-		// Update matching order
-		matchingOrder.FilledQuantity += tradeQuantity
-		if matchingOrder.FilledQuantity >= matchingOrder.Quantity {
-			matchingOrder.Status = models.OrderStatusMatched
-		} else {
-			matchingOrder.Status = models.OrderStatusPartiallyFilled
+		trade := &models.Trade{Quantity: fills[i], Price: tradePrice, ExecutedAt: now}
+		if err := execute(tx, order, matchingOrder, trade, result); err != nil {
+			return err
 		}
+	}
 
-		// Update matching order in database
-		if err := m.updateOrder(tx, &matchingOrder); err != nil {
-			return fmt.Errorf("failed to update matching order: %v", err)
+	// Update stock statistics and candles with the executions
+	if len(result.trades) > 0 {
+		if err := recordTrades(tx, order.StockSymbol, result.trades, now); err != nil {
+			return err
 		}
-
-		// Update current order
-		order.FilledQuantity += tradeQuantity
-		if order.FilledQuantity >= order.Quantity {
-			order.Status = models.OrderStatusMatched
-		} else {
-			order.Status = models.OrderStatusPartiallyFilled
+		if err := recordCandles(tx, order.StockSymbol, result.trades); err != nil {
+			return err
 		}
 	}
 
-	// Handle remaining quantity for market orders
+	// Cancel remaining quantity for market orders
 	if order.Category == models.OrderCategoryMarket && order.FilledQuantity < order.Quantity {
 		order.Status = models.OrderStatusCancelled
-	} else if order.FilledQuantity < order.Quantity {
-		// Only add limit orders to the order book
-		if order.Type == models.OrderTypeBuy {
-			m.BuyOrders = append(m.BuyOrders, *order)
-		} else {
-			m.SellOrders = append(m.SellOrders, *order)
-		}
 	}
 
 	// Update order in database
-	if err := m.updateOrder(tx, order); err != nil {
+	if err := tx.UpdateOrder(order); err != nil {
 		return fmt.Errorf("failed to update order: %v", err)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+	return nil
+}
+
+// execute records a trade of order against a resting order, filling both.
+// The trade must have its quantity, price and flags set. The trade and the
+// updated resting order are added to result.
+func execute(tx repository.Repository, order *models.Order, resting models.Order, trade *models.Trade, result *matchResult) error {
+	// Create trade
+	if order.Type == models.OrderTypeBuy {
+		trade.BuyOrderID = order.ID
+		trade.SellOrderID = resting.ID
+	} else {
+		trade.BuyOrderID = resting.ID
+		trade.SellOrderID = order.ID
+	}
+	trade.StockSymbol = order.StockSymbol
+
+	// Create trade record
+	if err := tx.CreateTrade(trade); err != nil {
+		return fmt.Errorf("failed to create trade: %v", err)
+	}
+	result.trades = append(result.trades, trade)
+
+	// Update matching order
+	resting.FilledQuantity += trade.Quantity
+	if resting.FilledQuantity >= resting.Quantity {
+		resting.Status = models.OrderStatusMatched
+	} else {
+		resting.Status = models.OrderStatusPartiallyFilled
+	}
+
+	// Update matching order in database
+	if err := tx.UpdateOrder(&resting); err != nil {
+		return fmt.Errorf("failed to update matching order: %v", err)
+	}
+	result.updated = append(result.updated, resting)
+
+	// Update current order
+	order.FilledQuantity += trade.Quantity
+	if order.FilledQuantity >= order.Quantity {
+		order.Status = models.OrderStatusMatched
+	} else {
+		order.Status = models.OrderStatusPartiallyFilled
 	}
 
+	// Attach the orders as of this execution for event listeners
+	taker, maker := *order, resting
+	if order.Type == models.OrderTypeBuy {
+		trade.BuyOrder, trade.SellOrder = &taker, &maker
+	} else {
+		trade.BuyOrder, trade.SellOrder = &maker, &taker
+	}
 	return nil
 }
 
-// CancelOrder cancels a pending order
+// CancelOrder cancels an open order. The order is reloaded under the lock
+// so that fills since it was read are kept, and ErrOrderNotOpen is returned
+// if it is no longer pending or partially filled. On return order holds
+// its current state.
 func (m *OrderMatcher) CancelOrder(order *models.Order) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Begin transaction
-	tx, err := m.db.Begin()
+	current, err := m.repo.GetOrderByID(order.ID)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
+		return fmt.Errorf("failed to get order: %v", err)
+	}
+	*order = *current
+	if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusPartiallyFilled {
+		return ErrOrderNotOpen
 	}
-	defer tx.Rollback()
 
 	// Update order status
 	order.Status = models.OrderStatusCancelled
 
-	// Update in database
-	if err := m.updateOrder(tx, order); err != nil {
-		return fmt.Errorf("failed to update order: %v", err)
+	// Update in database and remove from the order book
+	var repriced matchResult
+	err = m.transact(func(tx repository.Repository) error {
+		repriced = matchResult{}
+		if err := tx.UpdateOrder(order); err != nil {
+			return fmt.Errorf("failed to update order: %v", err)
+		}
+		m.updateBook(*order)
+		return m.repricePegs(tx, order.StockSymbol, &repriced)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Remove from appropriate order list
-	var orders *[]models.Order
+	// Notify listeners
+	m.publishOrder(*order)
+	m.bookChanged(order.StockSymbol, &repriced)
+	m.settleGroups(*order)
+
+	return nil
+}
+
+// CancelOrders cancels every open order matching the filter in a single
+// transaction and returns the cancelled orders. Zero-valued filter fields
+// match any order; the status and paging fields are ignored.
+func (m *OrderMatcher) CancelOrders(filter models.OrderFilter) ([]models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cancelled []models.Order
+	repriced := make(map[models.StockSymbol]*matchResult)
+	err := m.transact(func(tx repository.Repository) error {
+		cancelled = nil
+		repriced = make(map[models.StockSymbol]*matchResult)
+		for _, status := range []models.OrderStatus{models.OrderStatusPending, models.OrderStatusPartiallyFilled} {
+			filter.Status = status
+			filter.Page = models.Page{Limit: models.MaxPageLimit, Sort: models.SortOldest}
+			for {
+				page, err := tx.ListOrders(filter)
+				if err != nil {
+					return fmt.Errorf("failed to get open orders: %v", err)
+				}
+				cancelled = append(cancelled, page.Orders...)
+				if page.NextCursor == "" {
+					break
+				}
+				filter.After = page.NextCursor
+			}
+		}
+
+		// Remove the orders from the order book
+		for i := range cancelled {
+			cancelled[i].Status = models.OrderStatusCancelled
+			if err := tx.UpdateOrder(&cancelled[i]); err != nil {
+				return fmt.Errorf("failed to cancel order: %v", err)
+			}
+			m.updateBook(cancelled[i])
+			repriced[cancelled[i].StockSymbol] = &matchResult{}
+		}
+		for symbol, result := range repriced {
+			if err := m.repricePegs(tx, symbol, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify listeners
+	for _, order := range cancelled {
+		m.publishOrder(order)
+	}
+	for symbol, result := range repriced {
+		m.bookChanged(symbol, result)
+	}
+	m.settleGroups(cancelled...)
+
+	return cancelled, nil
+}
+
+// updateBook mirrors the state of an order in the in-memory book: resting
+// orders are kept in priority order and all others are removed
+func (m *OrderMatcher) updateBook(order models.Order) {
+	if m.undo != nil {
+		m.undo = append(m.undo, m.restorer(order))
+	}
+
+	switch {
+	case order.Category == models.OrderCategoryPegged:
+		track(m.pegs, order)
+	case isStop(&order):
+		track(m.stops, order)
+	}
+
+	orders := &m.SellOrders
 	if order.Type == models.OrderTypeBuy {
 		orders = &m.BuyOrders
-	} else {
-		orders = &m.SellOrders
 	}
 
 	for i, o := range *orders {
@@ -209,113 +548,126 @@ func (m *OrderMatcher) CancelOrder(order *models.Order) error {
 		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+	if !isResting(&order) {
+		return
 	}
 
-	return nil
+	// Priority times break price ties, and ids increase with time
+	book := *orders
+	i := sort.Search(len(book), func(i int) bool {
+		if book[i].Price != order.Price {
+			return (order.Type == models.OrderTypeBuy) == (order.Price > book[i].Price)
+		}
+		if !order.PriorityAt.Equal(book[i].PriorityAt) {
+			return order.PriorityAt.Before(book[i].PriorityAt)
+		}
+		return order.ID < book[i].ID
+	})
+	book = append(book, models.Order{})
+	copy(book[i+1:], book[i:])
+	book[i] = order
+	*orders = book
 }
 
-// Helper functions
-
-func (m *OrderMatcher) getMatchingSellOrders(tx *sql.Tx, buyOrder *models.Order) ([]models.Order, error) {
-	var query string
-	var args []interface{}
+// restorer returns a function restoring the book entry of an order as it
+// is now, removing the order if it has none
+func (m *OrderMatcher) restorer(order models.Order) func() {
+	previous, ok := m.pegs[order.ID]
+	if !ok {
+		previous, ok = m.stops[order.ID]
+	}
+	books := [][]models.Order{m.BuyOrders, m.SellOrders}
+	for i := 0; i < len(books) && !ok; i++ {
+		for _, o := range books[i] {
+			if o.ID == order.ID {
+				previous, ok = o, true
+				break
+			}
+		}
+	}
+	if !ok {
+		previous = order
+		previous.Status = models.OrderStatusCancelled
+	}
+	return func() { m.updateBook(previous) }
+}
 
-	// Base query with common conditions
-	query = `
-		SELECT id, type, category, stock_symbol, quantity, filled_quantity,
-		       price, status, user_id, created_at, updated_at
-		FROM orders
-		WHERE type = 'SELL'
-		  AND stock_symbol = ?
-		  AND status IN ('PENDING', 'PARTIALLY_FILLED')`
-	args = append(args, buyOrder.StockSymbol)
+// Helper functions
 
-	// Add price condition for limit orders
-	if buyOrder.Category == models.OrderCategoryLimit {
-		query += ` AND price <= ?`
-		args = append(args, buyOrder.Price)
+// postOnlyPrice returns the price one tick behind the opposite best price
+func (m *OrderMatcher) postOnlyPrice(order *models.Order, oppositeBest float64) float64 {
+	price := oppositeBest + m.cfg.TickSize
+	if order.Type == models.OrderTypeBuy {
+		price = oppositeBest - m.cfg.TickSize
 	}
+	return m.roundToTick(price)
+}
 
-	// Add order by clause for price-time priority
-	query += ` ORDER BY price ASC, created_at ASC`
+// roundToTick rounds a price to the nearest tick. The result is rounded
+// again to drop the binary error of multiplying by the tick size.
+func (m *OrderMatcher) roundToTick(price float64) float64 {
+	price = math.Round(price/m.cfg.TickSize) * m.cfg.TickSize
+	return math.Round(price*1e8) / 1e8
+}
 
-	// Execute query
-	rows, err := tx.Query(query, args...)
-	if err != nil {
-		return nil, err
+// isResting reports whether the order rests in the book. Pegged orders
+// rest once the book could price them; hidden orders never do.
+func isResting(order *models.Order) bool {
+	if order.Hidden {
+		return false
 	}
-	defer rows.Close()
-
-	var orders []models.Order
-	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(
-			&order.ID, &order.Type, &order.Category, &order.StockSymbol,
-			&order.Quantity, &order.FilledQuantity, &order.Price,
-			&order.Status, &order.UserID, &order.CreatedAt, &order.UpdatedAt)
-		if err != nil {
-			return nil, err
+	switch order.Category {
+	case models.OrderCategoryLimit:
+	case models.OrderCategoryPegged:
+		if order.Price <= 0 {
+			return false
 		}
-		orders = append(orders, order)
+	default:
+		return false
 	}
-	return orders, nil
+	return isOpen(order)
 }
 
-func (m *OrderMatcher) getMatchingBuyOrders(tx *sql.Tx, sellOrder *models.Order) ([]models.Order, error) {
-	var query string
-	var args []interface{}
-
-	// Base query with common conditions
-	query = `
-		SELECT id, type, category, stock_symbol, quantity, filled_quantity,
-		       price, status, user_id, created_at, updated_at
-		FROM orders
-		WHERE type = 'BUY'
-		  AND stock_symbol = ?
-		  AND status IN ('PENDING', 'PARTIALLY_FILLED')`
-	args = append(args, sellOrder.StockSymbol)
-
-	// Add price condition for limit orders
-	if sellOrder.Category == models.OrderCategoryLimit {
-		query += ` AND price >= ?`
-		args = append(args, sellOrder.Price)
-	}
-
-	// Add order by clause for price-time priority
-	query += ` ORDER BY price DESC, created_at ASC`
-
-	// Execute query
-	rows, err := tx.Query(query, args...)
-	if err != nil {
-		return nil, err
+// track keeps an order in orders while it is open
+func track(orders map[uint]models.Order, order models.Order) {
+	if isOpen(&order) {
+		orders[order.ID] = order
+	} else {
+		delete(orders, order.ID)
 	}
-	defer rows.Close()
+}
 
-	var orders []models.Order
-	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(
-			&order.ID, &order.Type, &order.Category, &order.StockSymbol,
-			&order.Quantity, &order.FilledQuantity, &order.Price,
-			&order.Status, &order.UserID, &order.CreatedAt, &order.UpdatedAt)
+// eachMarketTrade calls fn with the trades matching the filter, oldest
+// first, that count towards market data: trades negotiated off the book
+// and trades busted or replaced by a correction are skipped. The paging
+// fields of the filter are ignored.
+func eachMarketTrade(repo repository.Repository, filter models.TradeFilter, fn func(trade *models.Trade) error) error {
+	filter.Page = models.Page{Limit: models.MaxPageLimit, Sort: models.SortOldest}
+	for {
+		page, err := repo.ListTrades(filter)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("failed to get trades: %v", err)
 		}
-		orders = append(orders, order)
+		for i := range page.Trades {
+			trade := &page.Trades[i]
+			if trade.Negotiated || trade.CorrectionID != 0 {
+				continue
+			}
+			if err := fn(trade); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.After = page.NextCursor
 	}
-	return orders, nil
 }
 
-func (m *OrderMatcher) updateOrder(tx *sql.Tx, order *models.Order) error {
-	_, err := tx.Exec(`
-		UPDATE orders 
-		SET filled_quantity = ?, status = ?, updated_at = NOW()
-		WHERE id = ?`,
-		order.FilledQuantity, order.Status, order.ID)
-	return err
+// isOpen reports whether the order has quantity left to fill
+func isOpen(order *models.Order) bool {
+	return order.FilledQuantity < order.Quantity &&
+		(order.Status == models.OrderStatusPending || order.Status == models.OrderStatusPartiallyFilled)
 }
 
 func min(a, b uint) uint {
@@ -0,0 +1,212 @@
+package order_matcher
+
+import (
+	"errors"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"reflect"
+	"testing"
+)
+
+// newTestMatcher creates a matcher over an in-memory repository listing the
+// given stocks, COGNT by default
+func newTestMatcher(t *testing.T, stocks ...models.Stock) (*OrderMatcher, *repository.MemoryRepository) {
+	t.Helper()
+	if len(stocks) == 0 {
+		stocks = []models.Stock{{Symbol: "COGNT", CurrentPrice: 100}}
+	}
+	repo := repository.NewMemory(stocks...)
+	return NewOrderMatcher(repo), repo
+}
+
+// limit returns a pending limit order of COGNT
+func limit(side models.OrderType, quantity uint, price float64) *models.Order {
+	return &models.Order{
+		Type:        side,
+		Category:    models.OrderCategoryLimit,
+		StockSymbol: "COGNT",
+		Quantity:    quantity,
+		Price:       price,
+		Status:      models.OrderStatusPending,
+		UserID:      1,
+	}
+}
+
+// place creates and matches orders in sequence, failing the test on error
+func place(t *testing.T, m *OrderMatcher, orders ...*models.Order) {
+	t.Helper()
+	for i, err := range m.ProcessOrders(orders) {
+		if err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+	}
+}
+
+// reload returns the stored state of an order
+func reload(t *testing.T, repo repository.Repository, order *models.Order) *models.Order {
+	t.Helper()
+	stored, err := repo.GetOrderByID(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// trades returns the trades of the repository, oldest first
+func trades(t *testing.T, repo repository.Repository) []models.Trade {
+	t.Helper()
+	page, err := repo.ListTrades(models.TradeFilter{Page: models.Page{Limit: models.MaxPageLimit, Sort: models.SortOldest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.Trades
+}
+
+func TestLimitOrderMatchesAtRestingPrice(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	sell := limit(models.OrderTypeSell, 100, 10)
+	buy := limit(models.OrderTypeBuy, 60, 11)
+	place(t, m, sell, buy)
+
+	got := trades(t, repo)
+	if len(got) != 1 || got[0].Quantity != 60 || got[0].Price != 10 {
+		t.Fatalf("trades = %+v, want 60 @ 10", got)
+	}
+	if s := reload(t, repo, sell); s.FilledQuantity != 60 || s.Status != models.OrderStatusPartiallyFilled {
+		t.Errorf("sell = %d %s, want 60 PARTIALLY_FILLED", s.FilledQuantity, s.Status)
+	}
+	if b := reload(t, repo, buy); b.Status != models.OrderStatusMatched {
+		t.Errorf("buy status = %s, want MATCHED", b.Status)
+	}
+	if len(m.SellOrders) != 1 || len(m.BuyOrders) != 0 {
+		t.Errorf("book = %d bids, %d asks, want 0 and 1", len(m.BuyOrders), len(m.SellOrders))
+	}
+
+	stock, err := repo.GetStockBySymbol("COGNT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stock.CurrentPrice != 10 || stock.Volume != 60 {
+		t.Errorf("stock = %v x %d, want 10 x 60", stock.CurrentPrice, stock.Volume)
+	}
+}
+
+func TestLimitOrdersMatchInPriceTimePriority(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	first := limit(models.OrderTypeSell, 10, 10)
+	better := limit(models.OrderTypeSell, 10, 9)
+	second := limit(models.OrderTypeSell, 10, 10)
+	place(t, m, first, better, second)
+	place(t, m, limit(models.OrderTypeBuy, 15, 10))
+
+	if o := reload(t, repo, better); o.FilledQuantity != 10 {
+		t.Errorf("best price filled %d, want 10", o.FilledQuantity)
+	}
+	if o := reload(t, repo, first); o.FilledQuantity != 5 {
+		t.Errorf("first at 10 filled %d, want 5", o.FilledQuantity)
+	}
+	if o := reload(t, repo, second); o.FilledQuantity != 0 {
+		t.Errorf("second at 10 filled %d, want 0", o.FilledQuantity)
+	}
+}
+
+func TestMarketOrderWithoutMatchIsCancelled(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	market := &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryMarket, StockSymbol: "COGNT", Quantity: 10, Status: models.OrderStatusPending}
+	place(t, m, market)
+
+	if o := reload(t, repo, market); o.Status != models.OrderStatusCancelled {
+		t.Fatalf("status = %s, want CANCELLED", o.Status)
+	}
+	if len(trades(t, repo)) != 0 {
+		t.Fatal("unexpected trades")
+	}
+}
+
+func TestCancelOrderLeavesBook(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	buy := limit(models.OrderTypeBuy, 10, 10)
+	place(t, m, buy)
+
+	if err := m.CancelOrder(buy); err != nil {
+		t.Fatal(err)
+	}
+	if o := reload(t, repo, buy); o.Status != models.OrderStatusCancelled {
+		t.Fatalf("status = %s, want CANCELLED", o.Status)
+	}
+	if len(m.BuyOrders) != 0 {
+		t.Fatalf("book still holds %d bids", len(m.BuyOrders))
+	}
+
+	// A later sell finds nothing to match
+	place(t, m, limit(models.OrderTypeSell, 10, 10))
+	if len(trades(t, repo)) != 0 {
+		t.Fatal("cancelled order was matched")
+	}
+}
+
+func TestCancelOrderReloadsOrder(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	sell := limit(models.OrderTypeSell, 10, 10)
+	place(t, m, sell)
+	stale := *sell
+	place(t, m, limit(models.OrderTypeBuy, 4, 10))
+
+	// The fill since the order was read is kept
+	if err := m.CancelOrder(&stale); err != nil {
+		t.Fatal(err)
+	}
+	if o := reload(t, repo, sell); o.Status != models.OrderStatusCancelled || o.FilledQuantity != 4 {
+		t.Fatalf("order = %s filled %d, want CANCELLED filled 4", o.Status, o.FilledQuantity)
+	}
+
+	// Orders that are no longer open are left alone
+	buy := limit(models.OrderTypeBuy, 5, 10)
+	place(t, m, limit(models.OrderTypeSell, 5, 10), buy)
+	for _, order := range []*models.Order{buy, &stale} {
+		if err := m.CancelOrder(order); err != ErrOrderNotOpen {
+			t.Errorf("cancel of %s order: err = %v, want ErrOrderNotOpen", order.Status, err)
+		}
+	}
+	if o := reload(t, repo, buy); o.Status != models.OrderStatusMatched {
+		t.Fatalf("status = %s, want MATCHED", o.Status)
+	}
+}
+
+func TestFailedTransactionRestoresBook(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	pegged := peg(models.PegPrimary, 0)
+	buy, sell := limit(models.OrderTypeBuy, 10, 10), limit(models.OrderTypeSell, 10, 11)
+	place(t, m, buy, sell, pegged)
+	bids := append([]models.Order(nil), m.BuyOrders...)
+	asks := append([]models.Order(nil), m.SellOrders...)
+
+	// A cancel, a fill, a new order and a repriced peg, then a failure
+	err := m.transact(func(tx repository.Repository) error {
+		cancelled := *buy
+		cancelled.Status = models.OrderStatusCancelled
+		m.updateBook(cancelled)
+		filled := *sell
+		filled.Status = models.OrderStatusPartiallyFilled
+		filled.FilledQuantity = 5
+		m.updateBook(filled)
+		m.updateBook(*limit(models.OrderTypeBuy, 10, 9))
+		if err := m.repricePegs(tx, "COGNT", &matchResult{}); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("transaction did not fail")
+	}
+
+	if !reflect.DeepEqual(m.BuyOrders, bids) || !reflect.DeepEqual(m.SellOrders, asks) {
+		t.Fatalf("book = %+v / %+v, want %+v / %+v", m.BuyOrders, m.SellOrders, bids, asks)
+	}
+	if o := m.pegs[pegged.ID]; o.Price != 10 {
+		t.Fatalf("tracked peg price = %v, want 10", o.Price)
+	}
+	if o := reload(t, repo, pegged); o.Price != 10 {
+		t.Fatalf("stored peg price = %v, want 10", o.Price)
+	}
+}
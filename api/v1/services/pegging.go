@@ -0,0 +1,176 @@
+package order_matcher
+
+import (
+	"fmt"
+	"math"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"sort"
+)
+
+// Pegged orders are priced from the best bid and ask of the plain limit
+// orders of their stock, so pegs never reference each other. A peg is
+// priced on arrival and may then take liquidity like a limit order. While
+// it rests it is repriced whenever the best bid or ask moves, in the same
+// transaction as the change that moved them. A peg repriced through the
+// opposite side of the book trades there like an arriving order, except a
+// post-only peg, which is kept one tick behind it. A repriced peg gets a
+// new priority time, so it queues behind the orders already resting at its
+// new price. Pegs that cannot be priced are held at price 0, out of the
+// book, until they can.
+
+// bestPrices returns the best bid and ask of the lit book of a stock, 0 for
+// an empty side. Only plain limit orders quote it, so that pegs never move
+// the prices they follow.
+func (m *OrderMatcher) bestPrices(symbol models.StockSymbol) (bid, ask float64) {
+	for _, o := range m.BuyOrders {
+		if o.StockSymbol == symbol && o.Category == models.OrderCategoryLimit {
+			bid = o.Price
+			break
+		}
+	}
+	for _, o := range m.SellOrders {
+		if o.StockSymbol == symbol && o.Category == models.OrderCategoryLimit {
+			ask = o.Price
+			break
+		}
+	}
+	return bid, ask
+}
+
+// midpoint returns the midpoint of the best bid and ask, on the half tick,
+// or 0 if a side is empty. Midpoint pegs and the dark book both follow it.
+func (m *OrderMatcher) midpoint(bid, ask float64) float64 {
+	if bid == 0 || ask == 0 {
+		return 0
+	}
+	mid := math.Round((bid+ask)/m.cfg.TickSize) * m.cfg.TickSize / 2
+	return math.Round(mid*1e8) / 1e8
+}
+
+// pegPrice returns the price of a pegged order given the best bid and ask,
+// or 0 if the book cannot price it
+func (m *OrderMatcher) pegPrice(order *models.Order, bid, ask float64) float64 {
+	same, opposite := bid, ask
+	if order.Type == models.OrderTypeSell {
+		same, opposite = ask, bid
+	}
+
+	var reference float64
+	switch order.PegType {
+	case models.PegPrimary:
+		reference = same
+	case models.PegMarket:
+		reference = opposite
+	case models.PegMidpoint:
+		mid := m.midpoint(bid, ask)
+		if mid == 0 {
+			return 0
+		}
+
+		// A midpoint between ticks is rounded away from the opposite side
+		ticks := mid / m.cfg.TickSize
+		if order.Type == models.OrderTypeBuy {
+			reference = math.Floor(ticks+1e-9) * m.cfg.TickSize
+		} else {
+			reference = math.Ceil(ticks-1e-9) * m.cfg.TickSize
+		}
+	}
+	if reference == 0 {
+		return 0
+	}
+
+	price := m.roundToTick(reference + order.PegOffset)
+	if order.PegCap > 0 {
+		if order.Type == models.OrderTypeBuy {
+			price = math.Min(price, order.PegCap)
+		} else {
+			price = math.Max(price, order.PegCap)
+		}
+	}
+	if price <= 0 {
+		return 0
+	}
+	return price
+}
+
+// oppositeBest returns the best price of the resting orders of a stock on
+// the opposite side of the order, excluding the order itself
+func (m *OrderMatcher) oppositeBest(order *models.Order) float64 {
+	orders := m.BuyOrders
+	if order.Type == models.OrderTypeBuy {
+		orders = m.SellOrders
+	}
+	for _, o := range orders {
+		if o.StockSymbol == order.StockSymbol && o.ID != order.ID {
+			return o.Price
+		}
+	}
+	return 0
+}
+
+// repricePegs reprices the resting and held pegged orders of a stock
+// within the transaction tx, once the book reflects the changes made in it.
+// A peg repriced through the opposite side takes liquidity like an arriving
+// limit order, and the pegs are repriced again after it trades. The
+// repriced pegs, their trades and the orders they filled are collected in
+// result. The caller must hold m.mu and run tx through m.transact.
+func (m *OrderMatcher) repricePegs(tx repository.Repository, symbol models.StockSymbol, result *matchResult) error {
+	for {
+		var pegs []models.Order
+		for _, order := range m.pegs {
+			if order.StockSymbol == symbol {
+				pegs = append(pegs, order)
+			}
+		}
+		sort.Slice(pegs, func(i, j int) bool { return pegs[i].ID < pegs[j].ID })
+
+		traded, err := m.repriceOnce(tx, pegs, result)
+		if err != nil || !traded {
+			return err
+		}
+	}
+}
+
+// repriceOnce reprices pegs from the current book and reports whether a
+// marketable one traded, which moves the book the pegs follow
+func (m *OrderMatcher) repriceOnce(tx repository.Repository, pegs []models.Order, result *matchResult) (bool, error) {
+	for _, order := range pegs {
+		bid, ask := m.bestPrices(order.StockSymbol)
+		price := m.pegPrice(&order, bid, ask)
+		if price == order.Price {
+			continue
+		}
+		order.PriorityAt = models.PriorityTime()
+
+		best := m.oppositeBest(&order)
+		marketable := price > 0 && best > 0 &&
+			((order.Type == models.OrderTypeBuy && price >= best) || (order.Type == models.OrderTypeSell && price <= best))
+		if marketable && !order.PostOnly {
+			var match matchResult
+			if err := m.matchOrder(tx, &order, &match); err != nil {
+				return false, fmt.Errorf("failed to match pegged order %d: %v", order.ID, err)
+			}
+			m.stage(&order, &match)
+			result.trades = append(result.trades, match.trades...)
+			result.updated = append(append(result.updated, match.updated...), order)
+			if len(match.trades) > 0 {
+				return true, nil
+			}
+			continue
+		}
+
+		// A post-only peg is kept one tick behind the opposite side instead,
+		// and held if that leaves it no price
+		if marketable {
+			price = math.Max(m.postOnlyPrice(&order, best), 0)
+		}
+		order.Price = price
+		if err := tx.UpdateOrder(&order); err != nil {
+			return false, fmt.Errorf("failed to reprice pegged order %d: %v", order.ID, err)
+		}
+		m.updateBook(order)
+		result.updated = append(result.updated, order)
+	}
+	return false, nil
+}
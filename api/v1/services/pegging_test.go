@@ -0,0 +1,117 @@
+package order_matcher
+
+import (
+	"order-matching/api/v1/models"
+	"testing"
+)
+
+func TestRepricedPegQueuesBehindRestingOrders(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	peg := &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryPegged, PegType: models.PegPrimary, StockSymbol: "COGNT", Quantity: 10, Status: models.OrderStatusPending, UserID: 1}
+	place(t, m, limit(models.OrderTypeBuy, 10, 10), peg)
+	if o := reload(t, repo, peg); o.Price != 10 {
+		t.Fatalf("peg price = %v, want 10", o.Price)
+	}
+
+	// A better bid moves the peg up behind it
+	better := limit(models.OrderTypeBuy, 10, 11)
+	place(t, m, better)
+	if o := reload(t, repo, peg); o.Price != 11 {
+		t.Fatalf("repriced peg price = %v, want 11", o.Price)
+	}
+
+	place(t, m, limit(models.OrderTypeSell, 10, 11))
+	if o := reload(t, repo, better); o.FilledQuantity != 10 {
+		t.Errorf("order resting at 11 filled %d, want 10", o.FilledQuantity)
+	}
+	if o := reload(t, repo, peg); o.FilledQuantity != 0 {
+		t.Errorf("repriced peg filled %d, want 0", o.FilledQuantity)
+	}
+}
+
+// peg returns a pending pegged buy order of COGNT
+func peg(pegType models.PegType, offset float64) *models.Order {
+	return &models.Order{Type: models.OrderTypeBuy, Category: models.OrderCategoryPegged, PegType: pegType, PegOffset: offset, StockSymbol: "COGNT", Quantity: 10, Status: models.OrderStatusPending, UserID: 1}
+}
+
+func TestMarketableRepricedPegTrades(t *testing.T) {
+	tests := []struct {
+		name  string
+		peg   *models.Order
+		book  []*models.Order // Orders entered before the peg
+		moved *models.Order   // The order repricing the peg
+		price float64
+	}{
+		{
+			name:  "market peg",
+			peg:   peg(models.PegMarket, 0),
+			moved: limit(models.OrderTypeSell, 10, 10),
+			price: 10,
+		},
+		{
+			name:  "market peg at one tick",
+			peg:   peg(models.PegMarket, 0),
+			moved: limit(models.OrderTypeSell, 10, 0.01),
+			price: 0.01,
+		},
+		{
+			name:  "primary peg with positive offset",
+			peg:   peg(models.PegPrimary, 0.1),
+			book:  []*models.Order{limit(models.OrderTypeSell, 10, 10.05)},
+			moved: limit(models.OrderTypeBuy, 10, 10),
+			price: 10.05,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, repo := newTestMatcher(t)
+			place(t, m, append(tt.book, tt.peg)...)
+			if o := reload(t, repo, tt.peg); o.Price != 0 {
+				t.Fatalf("peg price = %v, want held at 0", o.Price)
+			}
+
+			place(t, m, tt.moved)
+			all := trades(t, repo)
+			if len(all) != 1 || all[0].BuyOrderID != tt.peg.ID || all[0].Price != tt.price || all[0].Quantity != 10 {
+				t.Fatalf("trades = %+v, want the peg buying 10 at %v", all, tt.price)
+			}
+			if o := reload(t, repo, tt.peg); o.Status != models.OrderStatusMatched {
+				t.Errorf("peg = %s, want MATCHED", o.Status)
+			}
+			for _, o := range m.BuyOrders {
+				if o.ID == tt.peg.ID {
+					t.Errorf("peg rests in the book after it traded: %+v", o)
+				}
+			}
+			sell := tt.moved
+			if len(tt.book) > 0 {
+				sell = reload(t, repo, tt.book[0])
+			}
+			if sell.Status != models.OrderStatusMatched {
+				t.Errorf("sell at %v = %s, want MATCHED", sell.Price, sell.Status)
+			}
+		})
+	}
+}
+
+func TestRepricedPostOnlyPegStaysBehindOppositeSide(t *testing.T) {
+	m, repo := newTestMatcher(t)
+	behind := peg(models.PegMarket, 0)
+	behind.PostOnly = true
+	place(t, m, behind, limit(models.OrderTypeSell, 10, 10))
+	if o := reload(t, repo, behind); o.Price != 9.99 || o.FilledQuantity != 0 {
+		t.Fatalf("post-only peg = %v filled %d, want 9.99 unfilled", o.Price, o.FilledQuantity)
+	}
+
+	// With no price left one tick behind, the peg is held out of the book
+	held := peg(models.PegMarket, 0)
+	held.PostOnly = true
+	m, repo = newTestMatcher(t)
+	place(t, m, held, limit(models.OrderTypeSell, 10, 0.01))
+	if o := reload(t, repo, held); o.Price != 0 || o.Status != models.OrderStatusPending {
+		t.Fatalf("post-only peg = %s at %v, want PENDING held at 0", o.Status, o.Price)
+	}
+	if len(m.BuyOrders) != 0 || len(trades(t, repo)) != 0 {
+		t.Fatalf("held peg rests in the book %+v or traded", m.BuyOrders)
+	}
+}
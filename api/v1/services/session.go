@@ -0,0 +1,100 @@
+package order_matcher
+
+import (
+	"fmt"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+	"time"
+)
+
+// recordTrades updates the statistics of the stock traded within the
+// transaction tx, first rolling it into the session of now if needed
+func recordTrades(tx repository.Repository, symbol models.StockSymbol, trades []*models.Trade, now time.Time) error {
+	stock, err := tx.GetStockBySymbol(symbol)
+	if err != nil {
+		return fmt.Errorf("failed to load stock: %v", err)
+	}
+
+	stock.RollSession(models.SessionDay(now))
+	for _, trade := range trades {
+		stock.RecordTrade(trade.Price, trade.Quantity)
+	}
+
+	if err := tx.UpdateStockStats(stock); err != nil {
+		return fmt.Errorf("failed to update stock statistics: %v", err)
+	}
+	return nil
+}
+
+// replaySession recomputes the statistics of the current session of a stock
+// from its market trades within the transaction tx
+func replaySession(tx repository.Repository, stock *models.Stock) error {
+	stock.ResetSession()
+	filter := models.TradeFilter{Symbol: stock.Symbol, From: stock.SessionDate}
+	err := eachMarketTrade(tx, filter, func(trade *models.Trade) error {
+		stock.RecordTrade(trade.Price, trade.Quantity)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.UpdateStockStats(stock); err != nil {
+		return fmt.Errorf("failed to update stock statistics: %v", err)
+	}
+	return nil
+}
+
+// RollSessions moves every stock still in a session before the one of now
+// into the current session and returns the number of stocks rolled. Stocks
+// also roll on their first trade of a day, so this only matters for stocks
+// that have not traded yet.
+func (m *OrderMatcher) RollSessions(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	day := models.SessionDay(now)
+	rolled := 0
+	err := m.repo.Transact(func(tx repository.Repository) error {
+		stocks, err := tx.GetAllStocks()
+		if err != nil {
+			return fmt.Errorf("failed to get stocks: %v", err)
+		}
+		for i := range stocks {
+			if !stocks[i].RollSession(day) {
+				continue
+			}
+			if err := tx.UpdateStockStats(&stocks[i]); err != nil {
+				return fmt.Errorf("failed to roll %s: %v", stocks[i].Symbol, err)
+			}
+			rolled++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rolled, nil
+}
+
+// RunSessionRoll rolls the stocks into a new session now and at every
+// following UTC midnight until stop is closed
+func (m *OrderMatcher) RunSessionRoll(stop <-chan struct{}) {
+	for {
+		rolled, err := m.RollSessions(time.Now())
+		if err != nil {
+			logger.Error(err, "Failed to roll trading sessions")
+		} else if rolled > 0 {
+			logger.Info(fmt.Sprintf("Rolled %d stock(s) into a new trading session", rolled))
+		}
+
+		timer := time.NewTimer(time.Until(models.SessionDay(time.Now()).AddDate(0, 0, 1)))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package order_matcher
+
+import (
+	"fmt"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	"order-matching/api/v1/utils/logger"
+	"sort"
+)
+
+// Stop orders wait outside the book until a trade prints at or through
+// their stop price: at or below it for sells, at or above it for buys.
+// The stop is then marked TRIGGERED and enters a child order for its
+// remaining quantity, a limit order at the stop's price or a market order
+// if it has none, with the fill constraints of the stop.
+//
+// The stop price of a trailing stop follows the best last trade price
+// since entry at a fixed or percentage distance and never moves back.
+// Only trades printed after a stop was entered trigger it.
+
+// isStop reports whether the order is a stop or trailing stop order
+func isStop(order *models.Order) bool {
+	return order.Category == models.OrderCategoryStop ||
+		order.Category == models.OrderCategoryTrailingStop
+}
+
+// trail moves the stop price of a trailing stop behind a trade price and
+// reports whether it moved
+func (m *OrderMatcher) trail(order *models.Order, price float64) bool {
+	distance := order.TrailAmount
+	if distance == 0 {
+		distance = price * order.TrailPercent / 100
+	}
+
+	if order.Type == models.OrderTypeSell {
+		stop := m.roundToTick(price - distance)
+		if stop <= order.StopPrice {
+			return false
+		}
+		order.StopPrice = stop
+		return true
+	}
+
+	stop := m.roundToTick(price + distance)
+	if order.StopPrice != 0 && stop >= order.StopPrice {
+		return false
+	}
+	order.StopPrice = stop
+	return true
+}
+
+// triggers reports whether a trade at price triggers the stop order
+func triggers(order *models.Order, price float64) bool {
+	if order.StopPrice <= 0 {
+		return false
+	}
+	if order.Type == models.OrderTypeSell {
+		return price <= order.StopPrice
+	}
+	return price >= order.StopPrice
+}
+
+// runStops trails and triggers the stop orders of a stock with the trades
+// just printed, in order. The trades of the child orders can trigger more
+// stops in turn. The caller must hold m.mu.
+func (m *OrderMatcher) runStops(symbol models.StockSymbol, trades []*models.Trade) {
+	for len(trades) > 0 {
+		var stops []models.Order
+		for _, order := range m.stops {
+			if order.StockSymbol == symbol {
+				stops = append(stops, order)
+			}
+		}
+		if len(stops) == 0 {
+			return
+		}
+		sort.Slice(stops, func(i, j int) bool { return stops[i].ID < stops[j].ID })
+
+		var trailed, triggered []models.Order
+		for _, order := range stops {
+			moved := false
+			fired := false
+			for _, trade := range trades {
+				if order.Category == models.OrderCategoryTrailingStop && m.trail(&order, trade.Price) {
+					moved = true
+				}
+				if triggers(&order, trade.Price) {
+					fired = true
+					break
+				}
+			}
+			if fired {
+				triggered = append(triggered, order)
+			} else if moved {
+				trailed = append(trailed, order)
+			}
+		}
+
+		m.saveStops(trailed)
+
+		trades = nil
+		for _, order := range triggered {
+			childTrades, err := m.trigger(order)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("Failed to trigger stop order %d", order.ID))
+				continue
+			}
+			trades = append(trades, childTrades...)
+		}
+	}
+}
+
+// saveStops persists the new stop prices of trailing stops. The caller
+// must hold m.mu.
+func (m *OrderMatcher) saveStops(orders []models.Order) {
+	if len(orders) == 0 {
+		return
+	}
+
+	err := m.repo.Transact(func(tx repository.Repository) error {
+		for i := range orders {
+			if err := tx.UpdateOrder(&orders[i]); err != nil {
+				return fmt.Errorf("failed to trail stop order %d: %v", orders[i].ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The stops keep trailing from their previous stop prices
+		logger.Error(err, "Failed to trail stop orders")
+		return
+	}
+
+	for _, order := range orders {
+		m.updateBook(order)
+		m.publishOrder(order)
+	}
+}
+
+// trigger marks a stop order triggered and enters its child order in the
+// same transaction. It returns the trades of the child order. The caller
+// must hold m.mu.
+func (m *OrderMatcher) trigger(stop models.Order) ([]*models.Trade, error) {
+	child := &models.Order{
+		Type:          stop.Type,
+		Category:      models.OrderCategoryMarket,
+		StockSymbol:   stop.StockSymbol,
+		Quantity:      stop.Quantity - stop.FilledQuantity,
+		Price:         stop.Price,
+		Status:        models.OrderStatusPending,
+		UserID:        stop.UserID,
+		ParentOrderID: stop.ID,
+		MinQuantity:   stop.MinQuantity,
+		AllOrNone:     stop.AllOrNone,
+	}
+	if stop.Price > 0 {
+		child.Category = models.OrderCategoryLimit
+	}
+
+	var result, repriced matchResult
+	err := m.transact(func(tx repository.Repository) error {
+		result, repriced = matchResult{}, matchResult{}
+		stop.Status = models.OrderStatusTriggered
+		if err := tx.UpdateOrder(&stop); err != nil {
+			return fmt.Errorf("failed to update stop order: %v", err)
+		}
+		m.updateBook(stop)
+		if err := tx.CreateOrder(child); err != nil {
+			return fmt.Errorf("failed to create child order: %v", err)
+		}
+		if err := m.matchOrder(tx, child, &result); err != nil {
+			return err
+		}
+		m.stage(child, &result)
+		return m.repricePegs(tx, stop.StockSymbol, &repriced)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.publishOrder(stop)
+	m.apply(child, &result)
+	m.bookChanged(stop.StockSymbol, &repriced)
+	m.settleGroups(append(result.updated, stop)...)
+	return result.trades, nil
+}
@@ -0,0 +1,212 @@
+package order_matcher
+
+import (
+	"order-matching/api/v1/models"
+	"sync"
+	"time"
+)
+
+// tickerWindow is the period covered by the ticker statistics
+const tickerWindow = 24 * time.Hour
+
+// Tickers keeps the ticker of every stock in memory, updated from matcher
+// events so that serving a ticker never touches the database
+type Tickers struct {
+	mu     sync.RWMutex
+	stocks map[models.StockSymbol]*tickerState
+}
+
+// tickerState is the in-memory market of a stock
+type tickerState struct {
+	orders map[uint]restingOrder
+	bids   map[float64]uint // Open quantity per price level
+	asks   map[float64]uint
+	last   models.Trade
+
+	// minutes aggregates the trades of the window per minute, oldest first
+	minutes []tickerMinute
+}
+
+// restingOrder is an order contributing to a price level
+type restingOrder struct {
+	side     models.OrderType
+	price    float64
+	quantity uint
+}
+
+// tickerMinute aggregates the trades of one minute
+type tickerMinute struct {
+	start    time.Time
+	open     float64
+	high     float64
+	low      float64
+	volume   int64
+	turnover float64
+}
+
+// NewTickers creates an empty ticker store
+func NewTickers() *Tickers {
+	return &Tickers{stocks: make(map[models.StockSymbol]*tickerState)}
+}
+
+// Apply updates the tickers with a matcher event
+func (t *Tickers) Apply(event Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch event.Type {
+	case EventOrderUpdated:
+		t.state(event.Order.StockSymbol).updateOrder(event.Order)
+	case EventTrade:
+		t.state(event.Trade.StockSymbol).addTrade(event.Trade)
+	}
+}
+
+// Get returns the ticker of a stock as of now
+func (t *Tickers) Get(symbol models.StockSymbol, now time.Time) models.Ticker {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	ticker := models.Ticker{Symbol: symbol}
+	if s, ok := t.stocks[symbol]; ok {
+		s.fill(&ticker, now)
+	}
+	return ticker
+}
+
+// state returns the state of a stock, creating it on first use
+func (t *Tickers) state(symbol models.StockSymbol) *tickerState {
+	s, ok := t.stocks[symbol]
+	if !ok {
+		s = &tickerState{
+			orders: make(map[uint]restingOrder),
+			bids:   make(map[float64]uint),
+			asks:   make(map[float64]uint),
+		}
+		t.stocks[symbol] = s
+	}
+	return s
+}
+
+// levels returns the price levels of a side
+func (s *tickerState) levels(side models.OrderType) map[float64]uint {
+	if side == models.OrderTypeBuy {
+		return s.bids
+	}
+	return s.asks
+}
+
+// updateOrder replaces the order's contribution to the price levels
+func (s *tickerState) updateOrder(order *models.Order) {
+	if prev, ok := s.orders[order.ID]; ok {
+		levels := s.levels(prev.side)
+		levels[prev.price] -= prev.quantity
+		if levels[prev.price] == 0 {
+			delete(levels, prev.price)
+		}
+		delete(s.orders, order.ID)
+	}
+
+	if !isResting(order) {
+		return
+	}
+	remaining := order.Quantity - order.FilledQuantity
+	s.orders[order.ID] = restingOrder{side: order.Type, price: order.Price, quantity: remaining}
+	s.levels(order.Type)[order.Price] += remaining
+}
+
+// resetTrades replaces the trades of a stock, such as after a correction.
+// trades must be oldest first.
+func (t *Tickers) resetTrades(symbol models.StockSymbol, trades []models.Trade) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.state(symbol)
+	s.last = models.Trade{}
+	s.minutes = nil
+	for i := range trades {
+		s.addTrade(&trades[i])
+	}
+}
+
+// addTrade records an execution
+func (s *tickerState) addTrade(trade *models.Trade) {
+	s.last = *trade
+
+	start := trade.ExecutedAt.Truncate(time.Minute)
+	n := len(s.minutes)
+	if n == 0 || s.minutes[n-1].start.Before(start) {
+		s.minutes = append(s.minutes, tickerMinute{
+			start: start, open: trade.Price, high: trade.Price, low: trade.Price,
+		})
+		n++
+	}
+
+	m := &s.minutes[n-1]
+	if trade.Price > m.high {
+		m.high = trade.Price
+	}
+	if trade.Price < m.low {
+		m.low = trade.Price
+	}
+	m.volume += int64(trade.Quantity)
+	m.turnover += trade.Price * float64(trade.Quantity)
+
+	s.prune(trade.ExecutedAt)
+}
+
+// prune drops the minutes that have left the window ending at now
+func (s *tickerState) prune(now time.Time) {
+	cutoff := now.Add(-tickerWindow)
+	i := 0
+	for i < len(s.minutes) && s.minutes[i].start.Before(cutoff) {
+		i++
+	}
+	s.minutes = s.minutes[i:]
+}
+
+// fill writes the ticker of the state as of now
+func (s *tickerState) fill(ticker *models.Ticker, now time.Time) {
+	for price, size := range s.bids {
+		if ticker.BestBidSize == 0 || price > ticker.BestBid {
+			ticker.BestBid, ticker.BestBidSize = price, size
+		}
+	}
+	for price, size := range s.asks {
+		if ticker.BestAskSize == 0 || price < ticker.BestAsk {
+			ticker.BestAsk, ticker.BestAskSize = price, size
+		}
+	}
+
+	if s.last.ID == 0 {
+		return
+	}
+	ticker.LastPrice = s.last.Price
+	ticker.LastQuantity = s.last.Quantity
+	ticker.LastTradeAt = s.last.ExecutedAt
+
+	var turnover float64
+	cutoff := now.Add(-tickerWindow)
+	for _, m := range s.minutes {
+		if m.start.Before(cutoff) {
+			continue
+		}
+		if ticker.Volume24h == 0 {
+			ticker.Open24h, ticker.High24h, ticker.Low24h = m.open, m.high, m.low
+		}
+		if m.high > ticker.High24h {
+			ticker.High24h = m.high
+		}
+		if m.low < ticker.Low24h {
+			ticker.Low24h = m.low
+		}
+		ticker.Volume24h += m.volume
+		turnover += m.turnover
+	}
+
+	if ticker.Volume24h > 0 {
+		ticker.VWAP24h = turnover / float64(ticker.Volume24h)
+		ticker.Change24h = ticker.LastPrice - ticker.Open24h
+		ticker.ChangePercent24h = ticker.Change24h / ticker.Open24h * 100
+	}
+}
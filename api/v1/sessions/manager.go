@@ -0,0 +1,249 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"order-matching/api/v1/config"
+	"order-matching/api/v1/models"
+	order_matcher "order-matching/api/v1/services"
+	"order-matching/api/v1/utils/logger"
+	"sync"
+	"time"
+)
+
+// Manager pulls the open orders of clients that go away. Long-lived
+// sessions, such as WebSocket or FIX connections, register with Open and
+// report their orders with Track; once a session opened with
+// cancel-on-disconnect has been disconnected for longer than the grace
+// period without resuming, its open orders are cancelled. REST clients arm
+// a dead-man's switch instead: if they stop sending heartbeats, all their
+// open orders are cancelled.
+type Manager struct {
+	matcher *order_matcher.OrderMatcher
+	cfg     *config.SessionConfig
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	switches map[uint]*deadMansSwitch
+}
+
+// Session is a long-lived connection of a user
+type Session struct {
+	ID                 string
+	UserID             uint
+	CancelOnDisconnect bool
+
+	orders map[uint]struct{} // Open orders entered through the session
+	timer  *time.Timer       // Pending cancellation while disconnected
+}
+
+// deadMansSwitch cancels a user's orders unless refreshed before it fires
+type deadMansSwitch struct {
+	timer     *time.Timer
+	expiresAt time.Time
+}
+
+// NewManager creates a manager cancelling orders through matcher
+func NewManager(matcher *order_matcher.OrderMatcher, cfg *config.SessionConfig) *Manager {
+	return &Manager{
+		matcher:  matcher,
+		cfg:      cfg,
+		sessions: make(map[string]*Session),
+		switches: make(map[uint]*deadMansSwitch),
+	}
+}
+
+// Open registers a new session of a user
+func (m *Manager) Open(userID uint, cancelOnDisconnect bool) (*Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %v", err)
+	}
+
+	s := &Session{
+		ID:                 hex.EncodeToString(id),
+		UserID:             userID,
+		CancelOnDisconnect: cancelOnDisconnect,
+		orders:             make(map[uint]struct{}),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.ID] = s
+	return s, nil
+}
+
+// Track records an order entered through a session
+func (m *Manager) Track(sessionID string, orderID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.sessions[sessionID]; ok {
+		s.orders[orderID] = struct{}{}
+	}
+}
+
+// Disconnect reports that the connection of a session dropped. Sessions
+// with cancel-on-disconnect get their open orders cancelled after the grace
+// period unless resumed; others are forgotten at once.
+func (m *Manager) Disconnect(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok || s.timer != nil {
+		return
+	}
+	if !s.CancelOnDisconnect {
+		delete(m.sessions, sessionID)
+		return
+	}
+	s.timer = time.AfterFunc(m.cfg.CancelGrace, func() { m.expire(sessionID) })
+}
+
+// Resume reattaches a user to a disconnected session within the grace
+// period, keeping its orders
+func (m *Manager) Resume(sessionID string, userID uint) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok || s.UserID != userID {
+		return nil, false
+	}
+	if s.timer != nil {
+		if !s.timer.Stop() {
+			// The session is being cancelled
+			return nil, false
+		}
+		s.timer = nil
+	}
+	return s, true
+}
+
+// MaxHeartbeatTimeout returns the longest timeout of a dead-man's switch
+func (m *Manager) MaxHeartbeatTimeout() time.Duration {
+	return m.cfg.MaxHeartbeatTimeout
+}
+
+// Heartbeat arms or refreshes the dead-man's switch of a user, which
+// cancels all their open orders unless refreshed within timeout. It returns
+// when the switch will fire.
+func (m *Manager) Heartbeat(userID uint, timeout time.Duration) time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if d, ok := m.switches[userID]; ok {
+		d.timer.Stop()
+	}
+	d := &deadMansSwitch{expiresAt: time.Now().Add(timeout)}
+	d.timer = time.AfterFunc(timeout, func() { m.trip(userID, d) })
+	m.switches[userID] = d
+	return d.expiresAt
+}
+
+// Disarm removes the dead-man's switch of a user and reports whether one
+// was armed
+func (m *Manager) Disarm(userID uint) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.switches[userID]
+	if ok {
+		d.timer.Stop()
+		delete(m.switches, userID)
+	}
+	return ok
+}
+
+// Apply forgets the orders of matcher events that are no longer open
+func (m *Manager) Apply(event order_matcher.Event) {
+	if event.Type != order_matcher.EventOrderUpdated {
+		return
+	}
+	if event.Order.Status == models.OrderStatusPending || event.Order.Status == models.OrderStatusPartiallyFilled {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		delete(s.orders, event.Order.ID)
+	}
+}
+
+// Close stops all pending cancellations
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+	}
+	for _, d := range m.switches {
+		d.timer.Stop()
+	}
+}
+
+// expire cancels the open orders of a session whose grace period ended
+func (m *Manager) expire(sessionID string) {
+	m.mu.Lock()
+	s, ok := m.sessions[sessionID]
+	delete(m.sessions, sessionID)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	// CancelOrder reloads each order under the matcher lock, so orders
+	// filled or cancelled meanwhile are skipped
+	cancelled := 0
+	for id := range s.orders {
+		err := m.matcher.CancelOrder(&models.Order{ID: id})
+		if errors.Is(err, order_matcher.ErrOrderNotOpen) {
+			continue
+		}
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Failed to cancel order %d of disconnected session", id))
+			continue
+		}
+		cancelled++
+	}
+
+	logger.LogWithFields(logger.InfoLevel, "Cancelled orders of disconnected session", map[string]interface{}{
+		"session_id": sessionID,
+		"user_id":    s.UserID,
+		"cancelled":  cancelled,
+	})
+}
+
+// trip cancels the open orders of a user whose dead-man's switch d fired
+func (m *Manager) trip(userID uint, d *deadMansSwitch) {
+	m.mu.Lock()
+	current := m.switches[userID] == d
+	if current {
+		delete(m.switches, userID)
+	}
+	m.mu.Unlock()
+
+	// The switch was refreshed or disarmed while firing
+	if !current {
+		return
+	}
+
+	cancelled, err := m.matcher.CancelOrders(models.OrderFilter{UserID: userID})
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Failed to cancel orders of user %d on missed heartbeat", userID))
+		return
+	}
+
+	logger.LogWithFields(logger.InfoLevel, "Dead-man's switch tripped", map[string]interface{}{
+		"user_id":   userID,
+		"cancelled": len(cancelled),
+	})
+}
@@ -0,0 +1,136 @@
+package sessions
+
+import (
+	"order-matching/api/v1/config"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
+	order_matcher "order-matching/api/v1/services"
+	"testing"
+	"time"
+)
+
+const grace = 20 * time.Millisecond
+
+// newTestManager creates a manager over a matcher on an in-memory
+// repository listing COGNT
+func newTestManager(t *testing.T) (*Manager, *order_matcher.OrderMatcher, *repository.MemoryRepository) {
+	t.Helper()
+	repo := repository.NewMemory(models.Stock{Symbol: "COGNT", CurrentPrice: 100})
+	matcher := order_matcher.NewOrderMatcher(repo)
+	m := NewManager(matcher, &config.SessionConfig{CancelGrace: grace, MaxHeartbeatTimeout: time.Minute})
+	matcher.Subscribe(m.Apply)
+	t.Cleanup(m.Close)
+	return m, matcher, repo
+}
+
+// place enters a limit order of COGNT for user 1
+func place(t *testing.T, matcher *order_matcher.OrderMatcher, side models.OrderType, quantity uint, price float64) *models.Order {
+	t.Helper()
+	order := &models.Order{Type: side, Category: models.OrderCategoryLimit, StockSymbol: "COGNT", Quantity: quantity, Price: price, Status: models.OrderStatusPending, UserID: 1}
+	if err := matcher.ProcessOrders([]*models.Order{order})[0]; err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+// status returns the stored status of an order
+func status(t *testing.T, repo repository.Repository, order *models.Order) models.OrderStatus {
+	t.Helper()
+	stored, err := repo.GetOrderByID(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored.Status
+}
+
+// waitForStatus waits up to a second for an order to reach want
+func waitForStatus(t *testing.T, repo repository.Repository, order *models.Order, want models.OrderStatus) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for status(t, repo, order) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("order %d = %s, want %s", order.ID, status(t, repo, order), want)
+		}
+		time.Sleep(grace / 4)
+	}
+}
+
+func TestDisconnectCancelsSessionOrdersAfterGrace(t *testing.T) {
+	m, matcher, repo := newTestManager(t)
+	s, err := m.Open(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filled := place(t, matcher, models.OrderTypeSell, 5, 30)
+	place(t, matcher, models.OrderTypeBuy, 5, 30)
+	partial := place(t, matcher, models.OrderTypeSell, 10, 20)
+	place(t, matcher, models.OrderTypeBuy, 4, 20)
+	open := place(t, matcher, models.OrderTypeBuy, 10, 10)
+	untracked := place(t, matcher, models.OrderTypeBuy, 10, 9)
+	for _, order := range []*models.Order{open, partial, filled} {
+		m.Track(s.ID, order.ID)
+	}
+
+	m.Disconnect(s.ID)
+	if got := status(t, repo, open); got != models.OrderStatusPending {
+		t.Fatalf("order cancelled before the grace period: %s", got)
+	}
+	waitForStatus(t, repo, open, models.OrderStatusCancelled)
+	waitForStatus(t, repo, partial, models.OrderStatusCancelled)
+
+	if got := status(t, repo, filled); got != models.OrderStatusMatched {
+		t.Errorf("filled order = %s, want MATCHED", got)
+	}
+	if got := status(t, repo, untracked); got != models.OrderStatusPending {
+		t.Errorf("order entered outside the session = %s, want PENDING", got)
+	}
+	if _, ok := m.Resume(s.ID, 1); ok {
+		t.Error("expired session resumed")
+	}
+}
+
+func TestResumeKeepsSessionOrders(t *testing.T) {
+	m, matcher, repo := newTestManager(t)
+	s, err := m.Open(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := place(t, matcher, models.OrderTypeBuy, 10, 10)
+	m.Track(s.ID, order.ID)
+
+	m.Disconnect(s.ID)
+	if _, ok := m.Resume(s.ID, 2); ok {
+		t.Fatal("session resumed by another user")
+	}
+	if _, ok := m.Resume(s.ID, 1); !ok {
+		t.Fatal("session not resumed within the grace period")
+	}
+	time.Sleep(3 * grace)
+	if got := status(t, repo, order); got != models.OrderStatusPending {
+		t.Fatalf("order of resumed session = %s, want PENDING", got)
+	}
+
+	// A later disconnect starts a new grace period
+	m.Disconnect(s.ID)
+	waitForStatus(t, repo, order, models.OrderStatusCancelled)
+}
+
+func TestDisconnectWithoutCancelOnDisconnectKeepsOrders(t *testing.T) {
+	m, matcher, repo := newTestManager(t)
+	s, err := m.Open(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := place(t, matcher, models.OrderTypeBuy, 10, 10)
+	m.Track(s.ID, order.ID)
+
+	m.Disconnect(s.ID)
+	time.Sleep(3 * grace)
+	if got := status(t, repo, order); got != models.OrderStatusPending {
+		t.Fatalf("order = %s, want PENDING", got)
+	}
+	if _, ok := m.Resume(s.ID, 1); ok {
+		t.Error("closed session resumed")
+	}
+}
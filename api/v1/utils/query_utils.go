@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"order-matching/api/v1/models"
+	"strconv"
+	"time"
+)
+
+var (
+	// Query-related errors
+	ErrInvalidLimit     = errors.New("limit must be a positive integer")
+	ErrInvalidSortOrder = errors.New("sort must be one of newest, oldest, price_asc, price_desc")
+	ErrInvalidUserID    = errors.New("user_id must be a positive integer")
+	ErrInvalidTime      = errors.New("from and to must be RFC 3339 timestamps or unix seconds")
+	ErrInvalidPriceBand = errors.New("min_price and max_price must be non-negative numbers")
+	ErrSideWithoutUser  = errors.New("side filter on trades requires user_id")
+	ErrInvalidInterval  = errors.New("interval must be one of 1s, 1m, 5m, 1h, 1d")
+)
+
+// ParsePage reads the limit, after and sort query parameters
+func ParsePage(q url.Values) (models.Page, error) {
+	page := models.Page{
+		After: q.Get("after"),
+		Sort:  models.SortOrder(q.Get("sort")),
+	}
+
+	var err error
+	if page.Limit, err = parseLimit(q); err != nil {
+		return page, err
+	}
+
+	if page.Sort != "" && !models.ValidSortOrder(page.Sort) {
+		return page, ErrInvalidSortOrder
+	}
+
+	page.Normalize()
+	return page, nil
+}
+
+// ParseOrderFilter reads the order listing query parameters: symbol,
+// user_id, side, status, category, from, to, min_price, max_price and the
+// page parameters
+func ParseOrderFilter(q url.Values) (models.OrderFilter, error) {
+	var filter models.OrderFilter
+	var err error
+
+	if filter.Page, err = ParsePage(q); err != nil {
+		return filter, err
+	}
+
+	filter.Symbol = models.StockSymbol(q.Get("symbol"))
+	if filter.UserID, err = parseUserID(q); err != nil {
+		return filter, err
+	}
+
+	if v := q.Get("side"); v != "" {
+		filter.Type = models.OrderType(v)
+		if filter.Type != models.OrderTypeBuy && filter.Type != models.OrderTypeSell {
+			return filter, ErrInvalidOrderType
+		}
+	}
+
+	if v := q.Get("status"); v != "" {
+		filter.Status = models.OrderStatus(v)
+		if err := ValidateOrderStatus(filter.Status); err != nil {
+			return filter, err
+		}
+	}
+
+	if v := q.Get("category"); v != "" {
+		filter.Category = models.OrderCategory(v)
+		switch filter.Category {
+		case models.OrderCategoryLimit, models.OrderCategoryMarket, models.OrderCategoryPegged,
+			models.OrderCategoryStop, models.OrderCategoryTrailingStop:
+			// Valid
+		default:
+			return filter, ErrInvalidOrderCategory
+		}
+	}
+
+	if filter.From, filter.To, err = parseTimeRange(q); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice, filter.MaxPrice, err = parsePriceRange(q); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// ParseTradeFilter reads the trade listing query parameters: symbol,
+// user_id, side, from, to, min_price, max_price and the page parameters
+func ParseTradeFilter(q url.Values) (models.TradeFilter, error) {
+	var filter models.TradeFilter
+	var err error
+
+	if filter.Page, err = ParsePage(q); err != nil {
+		return filter, err
+	}
+
+	filter.Symbol = models.StockSymbol(q.Get("symbol"))
+	if filter.UserID, err = parseUserID(q); err != nil {
+		return filter, err
+	}
+
+	if v := q.Get("side"); v != "" {
+		filter.Side = models.OrderType(v)
+		if filter.Side != models.OrderTypeBuy && filter.Side != models.OrderTypeSell {
+			return filter, ErrInvalidOrderType
+		}
+		if filter.UserID == 0 {
+			return filter, ErrSideWithoutUser
+		}
+	}
+
+	if filter.From, filter.To, err = parseTimeRange(q); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice, filter.MaxPrice, err = parsePriceRange(q); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// ParseCandleFilter reads the candle query parameters: interval (default
+// 1m), from, to and limit
+func ParseCandleFilter(symbol models.StockSymbol, q url.Values) (models.CandleFilter, error) {
+	filter := models.CandleFilter{
+		Symbol:   symbol,
+		Interval: models.CandleInterval1m,
+	}
+	var err error
+
+	if v := q.Get("interval"); v != "" {
+		filter.Interval = models.CandleInterval(v)
+		if filter.Interval.Duration() == 0 {
+			return filter, ErrInvalidInterval
+		}
+	}
+
+	if filter.From, filter.To, err = parseTimeRange(q); err != nil {
+		return filter, err
+	}
+
+	if filter.Limit, err = parseLimit(q); err != nil {
+		return filter, err
+	}
+	if filter.Limit == 0 || filter.Limit > models.MaxPageLimit {
+		filter.Limit = models.MaxPageLimit
+	}
+
+	return filter, nil
+}
+
+// ParseAuditFilter reads the audit log query parameters: user_id, denied
+// and limit (default 100, max 1000)
+func ParseAuditFilter(q url.Values) (models.AuditFilter, error) {
+	var filter models.AuditFilter
+	var err error
+
+	if filter.UserID, err = parseUserID(q); err != nil {
+		return filter, err
+	}
+	filter.Denied = q.Get("denied") == "true"
+
+	if filter.Limit, err = parseLimit(q); err != nil {
+		return filter, err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = models.DefaultPageLimit
+	}
+	if filter.Limit > models.MaxPageLimit {
+		filter.Limit = models.MaxPageLimit
+	}
+
+	return filter, nil
+}
+
+// parseLimit reads the optional limit parameter
+func parseLimit(q url.Values) (int, error) {
+	v := q.Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit <= 0 {
+		return 0, ErrInvalidLimit
+	}
+	return limit, nil
+}
+
+// parseUserID reads the optional user_id parameter
+func parseUserID(q url.Values) (uint, error) {
+	v := q.Get("user_id")
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidUserID
+	}
+	return uint(id), nil
+}
+
+// parseTimeRange reads the optional from and to parameters
+func parseTimeRange(q url.Values) (time.Time, time.Time, error) {
+	from, err := parseTime(q.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := parseTime(q.Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return from, to, nil
+}
+
+// parseTime parses an RFC 3339 timestamp or unix seconds
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, ErrInvalidTime
+	}
+	return t, nil
+}
+
+// parsePriceRange reads the optional min_price and max_price parameters
+func parsePriceRange(q url.Values) (float64, float64, error) {
+	var bounds [2]float64
+	for i, key := range []string{"min_price", "max_price"} {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			return 0, 0, ErrInvalidPriceBand
+		}
+		bounds[i] = price
+	}
+	return bounds[0], bounds[1], nil
+}
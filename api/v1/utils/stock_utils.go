@@ -2,20 +2,20 @@ package utils
 
 import (
 	"order-matching/api/v1/models"
+	"regexp"
 )
 
-// GetAllStockSymbols returns all available stock symbols
-func GetAllStockSymbols() []models.StockSymbol {
-	return []models.StockSymbol{
-		models.StockNXTECH,
-		models.StockQNTUM,
-		models.StockCYBEX,
-		models.StockSOLRX,
-		models.StockFUSON,
-		models.StockGENUM,
-		models.StockMEDIX,
-		models.StockAITHN,
-		models.StockNRLNK,
-		models.StockCOGNT,
-	}
+// symbolPattern is the format of a stock symbol
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]{1,10}$`)
+
+// StockLookup finds stocks by symbol. The repositories implement it, which
+// makes the stocks table the registry of tradable symbols.
+type StockLookup interface {
+	GetStockBySymbol(symbol models.StockSymbol) (*models.Stock, error)
+}
+
+// IsListedStock reports whether the symbol belongs to an active stock
+func IsListedStock(stocks StockLookup, symbol models.StockSymbol) bool {
+	stock, err := stocks.GetStockBySymbol(symbol)
+	return err == nil && stock.Status == models.StockStatusActive
 }
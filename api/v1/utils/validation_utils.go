@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"order-matching/api/v1/models"
+	"strings"
 )
 
 var (
@@ -12,34 +13,45 @@ var (
 	ErrInvalidOrderStatus   = errors.New("invalid order status")
 	ErrInvalidPrice         = errors.New("price is required and must be greater than 0 for limit order")
 	ErrInvalidQuantity      = errors.New("quantity must be greater than 0")
+	ErrInvalidMinQuantity   = errors.New("min quantity cannot exceed the order quantity")
+	ErrPostOnlyMarket       = errors.New("post-only orders must be limit or pegged orders")
+	ErrInvalidPegType       = errors.New("peg type must be PRIMARY, MIDPOINT or MARKET for pegged orders and empty otherwise")
+	ErrInvalidPegCap        = errors.New("peg cap cannot be negative")
+	ErrInvalidStopPrice     = errors.New("stop price is required for stop orders and not allowed otherwise")
+	ErrInvalidTrail         = errors.New("trailing stops need either a trail amount or a trail percent below 100")
+	ErrInvalidHidden        = errors.New("hidden orders must be limit orders that are not post-only")
+	ErrHiddenTooSmall       = errors.New("hidden order quantity is below the minimum")
+	ErrInvalidClientOrderID = errors.New("client order id must be at most 64 printable ASCII characters without spaces")
+
+	// Order group errors
+	ErrInvalidGroupType = errors.New("order group type must be OCO or BRACKET")
+	ErrInvalidOCO       = errors.New("an OCO group needs a limit order and a stop order on the same stock and side")
+	ErrInvalidBracket   = errors.New("a bracket needs a limit or market entry and a take-profit price and stop price on either side of it")
+
+	// RFQ errors
+	ErrInvalidQuote = errors.New("a quote needs a bid price, an ask price or both, with the bid below the ask")
 
 	// Stock-related errors
 	ErrInvalidStockSymbol = errors.New("invalid stock symbol")
+	ErrInvalidStockStatus = errors.New("invalid stock status")
 	ErrInvalidStockName   = errors.New("stock name cannot be empty")
 	ErrInvalidStockPrice  = errors.New("price must be greater than 0")
 	ErrInvalidPriceRange  = errors.New("day high cannot be less than day low")
 	ErrInvalidVolume      = errors.New("volume cannot be negative")
 	ErrInvalidMarketCap   = errors.New("market cap must be greater than 0")
 	ErrInvalidSector      = errors.New("sector cannot be empty")
+	ErrInvalidAllocation  = errors.New("invalid allocation algorithm")
 
 	// Trade-related errors
 	ErrInvalidTradeOrders = errors.New("both buy and sell order IDs are required")
 	ErrSameOrderTrade     = errors.New("buy and sell order IDs cannot be the same")
+	ErrInvalidReason      = errors.New("reason is required and must be at most 255 characters")
+	ErrInvalidCorrection  = errors.New("a correction needs a price and quantity greater than 0 that differ from the trade")
 )
 
-// isValidStockSymbol checks if a given stock symbol is valid
-func isValidStockSymbol(symbol models.StockSymbol) bool {
-	validSymbols := GetAllStockSymbols()
-	for _, s := range validSymbols {
-		if s == symbol {
-			return true
-		}
-	}
-	return false
-}
-
-// ValidateOrder performs validation on the order
-func ValidateOrder(order *models.Order) error {
+// ValidateOrder performs validation on the order, requiring its stock to be
+// listed in stocks
+func ValidateOrder(order *models.Order, stocks StockLookup) error {
 	// Validate order type
 	switch order.Type {
 	case models.OrderTypeBuy, models.OrderTypeSell:
@@ -50,19 +62,21 @@ func ValidateOrder(order *models.Order) error {
 
 	// Validate order category
 	switch order.Category {
-	case models.OrderCategoryLimit, models.OrderCategoryMarket:
+	case models.OrderCategoryLimit, models.OrderCategoryMarket, models.OrderCategoryPegged,
+		models.OrderCategoryStop, models.OrderCategoryTrailingStop:
 		// Valid
 	default:
 		return ErrInvalidOrderCategory
 	}
 
 	// Validate stock symbol
-	if !isValidStockSymbol(order.StockSymbol) {
+	if !IsListedStock(stocks, order.StockSymbol) {
 		return ErrInvalidStockSymbol
 	}
 
-	// Validate price for limit orders
-	if order.Category == models.OrderCategoryLimit && order.Price <= 0 {
+	// Validate price for limit orders. Stop orders may have the price of
+	// their limit child.
+	if (order.Category == models.OrderCategoryLimit && order.Price <= 0) || order.Price < 0 {
 		return ErrInvalidPrice
 	}
 
@@ -70,13 +84,129 @@ func ValidateOrder(order *models.Order) error {
 	if order.Quantity <= 0 {
 		return ErrInvalidQuantity
 	}
+	if order.MinQuantity > order.Quantity {
+		return ErrInvalidMinQuantity
+	}
+
+	// Post-only orders must have a price to rest at
+	if order.PostOnly && order.Category != models.OrderCategoryLimit && order.Category != models.OrderCategoryPegged {
+		return ErrPostOnlyMarket
+	}
+
+	// Hidden orders rest in the dark book at a limit price
+	if order.Hidden && (order.Category != models.OrderCategoryLimit || order.PostOnly) {
+		return ErrInvalidHidden
+	}
+
+	// Validate the peg of pegged orders, whose price is set by the book
+	if order.Category == models.OrderCategoryPegged {
+		switch order.PegType {
+		case models.PegPrimary, models.PegMidpoint, models.PegMarket:
+			// Valid
+		default:
+			return ErrInvalidPegType
+		}
+		if order.PegCap < 0 {
+			return ErrInvalidPegCap
+		}
+	} else if order.PegType != "" || order.PegOffset != 0 || order.PegCap != 0 {
+		return ErrInvalidPegType
+	}
+
+	// Validate the trigger of stop orders. The stop price of a trailing
+	// stop is set by the matching engine.
+	switch order.Category {
+	case models.OrderCategoryStop:
+		if order.StopPrice <= 0 {
+			return ErrInvalidStopPrice
+		}
+		if order.TrailAmount != 0 || order.TrailPercent != 0 {
+			return ErrInvalidTrail
+		}
+	case models.OrderCategoryTrailingStop:
+		if order.StopPrice != 0 {
+			return ErrInvalidStopPrice
+		}
+		if order.TrailAmount < 0 || order.TrailPercent < 0 || order.TrailPercent >= 100 ||
+			(order.TrailAmount > 0) == (order.TrailPercent > 0) {
+			return ErrInvalidTrail
+		}
+	default:
+		if order.StopPrice != 0 {
+			return ErrInvalidStopPrice
+		}
+		if order.TrailAmount != 0 || order.TrailPercent != 0 {
+			return ErrInvalidTrail
+		}
+	}
+
+	// Validate the optional client order id
+	if !ValidClientOrderID(order.ClientOrderID) {
+		return ErrInvalidClientOrderID
+	}
 
 	return nil
 }
 
+// ValidateOrderGroup checks that the orders fit the group; each order must
+// have been validated already. OCO groups are given their two legs and
+// bracket groups their entry order.
+func ValidateOrderGroup(group *models.OrderGroup, orders []*models.Order) error {
+	switch group.Type {
+	case models.OrderGroupOCO:
+		if len(orders) != 2 || group.TakeProfitPrice != 0 || group.StopPrice != 0 {
+			return ErrInvalidOCO
+		}
+		limit, stop := orders[0], orders[1]
+		if limit.Category != models.OrderCategoryLimit {
+			limit, stop = stop, limit
+		}
+		if limit.Category != models.OrderCategoryLimit ||
+			(stop.Category != models.OrderCategoryStop && stop.Category != models.OrderCategoryTrailingStop) ||
+			limit.StockSymbol != stop.StockSymbol || limit.Type != stop.Type {
+			return ErrInvalidOCO
+		}
+
+	case models.OrderGroupBracket:
+		if len(orders) != 1 || group.TakeProfitPrice <= 0 || group.StopPrice <= 0 {
+			return ErrInvalidBracket
+		}
+		entry := orders[0]
+		if entry.Category != models.OrderCategoryLimit && entry.Category != models.OrderCategoryMarket {
+			return ErrInvalidBracket
+		}
+
+		// The exits of a long position sell higher to take profit and lower
+		// to stop losses, those of a short position the other way around
+		if entry.Type == models.OrderTypeBuy && group.TakeProfitPrice <= group.StopPrice ||
+			entry.Type == models.OrderTypeSell && group.TakeProfitPrice >= group.StopPrice {
+			return ErrInvalidBracket
+		}
+
+	default:
+		return ErrInvalidGroupType
+	}
+
+	return nil
+}
+
+// ValidClientOrderID reports whether id may be used as a client order id.
+// The empty id means none.
+func ValidClientOrderID(id string) bool {
+	if len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
 // ValidateStock performs validation on the stock data
 func ValidateStock(stock *models.Stock) error {
-	if !isValidStockSymbol(stock.Symbol) {
+	if !symbolPattern.MatchString(string(stock.Symbol)) {
 		return ErrInvalidStockSymbol
 	}
 
@@ -104,12 +234,27 @@ func ValidateStock(stock *models.Stock) error {
 		return ErrInvalidSector
 	}
 
+	switch stock.Status {
+	case models.StockStatusActive, models.StockStatusDelisted:
+		// Valid
+	default:
+		return ErrInvalidStockStatus
+	}
+
+	switch stock.Allocation {
+	case models.AllocationFIFO, models.AllocationProRata, models.AllocationTopOrderProRata:
+		// Valid
+	default:
+		return ErrInvalidAllocation
+	}
+
 	return nil
 }
 
-// ValidateTrade performs validation on the trade
-func ValidateTrade(trade *models.Trade) error {
-	if !isValidStockSymbol(trade.StockSymbol) {
+// ValidateTrade performs validation on the trade, requiring its stock to be
+// listed in stocks
+func ValidateTrade(trade *models.Trade, stocks StockLookup) error {
+	if !IsListedStock(stocks, trade.StockSymbol) {
 		return ErrInvalidStockSymbol
 	}
 
@@ -132,13 +277,61 @@ func ValidateTrade(trade *models.Trade) error {
 	return nil
 }
 
+// ValidateTradeCorrection performs validation on the bust or correction of
+// trade
+func ValidateTradeCorrection(correction *models.TradeCorrection, trade *models.Trade) error {
+	if strings.TrimSpace(correction.Reason) == "" || len(correction.Reason) > 255 {
+		return ErrInvalidReason
+	}
+
+	switch correction.Type {
+	case models.TradeBust:
+	case models.TradeCorrect:
+		if correction.Price <= 0 || correction.Quantity == 0 {
+			return ErrInvalidCorrection
+		}
+		if correction.Price == trade.Price && correction.Quantity == trade.Quantity {
+			return ErrInvalidCorrection
+		}
+	default:
+		return ErrInvalidCorrection
+	}
+
+	return nil
+}
+
+// ValidateRFQ performs validation on a request for quote, requiring its
+// stock to be listed in stocks
+func ValidateRFQ(rfq *models.RFQ, stocks StockLookup) error {
+	if !IsListedStock(stocks, rfq.StockSymbol) {
+		return ErrInvalidStockSymbol
+	}
+	if rfq.Quantity == 0 {
+		return ErrInvalidQuantity
+	}
+	return nil
+}
+
+// ValidateRFQQuote performs validation on a quote, which must quote at
+// least one side without crossing itself
+func ValidateRFQQuote(quote *models.RFQQuote) error {
+	if quote.BidPrice < 0 || quote.AskPrice < 0 || (quote.BidPrice == 0 && quote.AskPrice == 0) {
+		return ErrInvalidQuote
+	}
+	if quote.BidPrice > 0 && quote.AskPrice > 0 && quote.BidPrice >= quote.AskPrice {
+		return ErrInvalidQuote
+	}
+	return nil
+}
+
 // ValidateOrderStatus checks if the order status is valid
 func ValidateOrderStatus(status models.OrderStatus) error {
 	switch status {
 	case models.OrderStatusPending,
 		models.OrderStatusPartiallyFilled,
 		models.OrderStatusMatched,
-		models.OrderStatusCancelled:
+		models.OrderStatusCancelled,
+		models.OrderStatusTriggered:
 		return nil
 	default:
 		return ErrInvalidOrderStatus
@@ -1,14 +1,236 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"order-matching/api/v1/auth"
+	"order-matching/api/v1/database"
+	"order-matching/api/v1/models"
+	"order-matching/api/v1/repository"
 	"order-matching/api/v1/server"
+	order_matcher "order-matching/api/v1/services"
+	"os"
+	"strconv"
+	"strings"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "migrate":
+			err = migrate(os.Args[2:])
+		case "backfill-candles":
+			err = backfillCandles(os.Args[2:])
+		case "create-user":
+			err = createUser(os.Args[2:])
+		case "create-api-key":
+			err = createAPIKey(os.Args[2:])
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	defer server.Close()
 
 	if err := server.Run(":8080"); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// migrate runs the migrate subcommand:
+//
+//	migrate [up]       apply all pending migrations
+//	migrate down [n]   roll back the last n migrations (default 1)
+//	migrate status     list migrations and whether they are applied
+func migrate(args []string) error {
+	if err := database.Connect(); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	migrator, err := database.NewMigrator(database.GetDB(), database.GetDriver())
+	if err != nil {
+		return err
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down or status)", command)
+	}
+
+	return nil
+}
+
+// backfillCandles runs the backfill-candles subcommand, which rebuilds the
+// candles of the given symbols, or of every stock, from the trades table:
+//
+//	backfill-candles [symbol...]
+func backfillCandles(args []string) error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	matcher := order_matcher.NewOrderMatcher(repo)
+
+	var symbols []models.StockSymbol
+	for _, arg := range args {
+		symbols = append(symbols, models.StockSymbol(arg))
+	}
+	if len(symbols) == 0 {
+		stocks, err := repo.GetAllStocks()
+		if err != nil {
+			return err
+		}
+		for _, stock := range stocks {
+			symbols = append(symbols, stock.Symbol)
+		}
+	}
+
+	for _, symbol := range symbols {
+		count, err := matcher.BackfillCandles(symbol)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		fmt.Printf("%s\t%d trade(s)\n", symbol, count)
+	}
+	return nil
+}
+
+// createUser runs the create-user subcommand, which creates a user with a
+// first API key and prints the key. The role defaults to TRADER:
+//
+//	create-user <username> [TRADER|READ_ONLY|MARKET_MAKER|OPERATOR|ADMIN]
+func createUser(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: create-user <username> [role]")
+	}
+	role := models.UserRoleTrader
+	if len(args) == 2 {
+		role = models.UserRole(strings.ToUpper(args[1]))
+		if !auth.ValidRole(role) {
+			return fmt.Errorf("unknown role %q", args[1])
+		}
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	user := &models.User{Username: args[0], Role: role}
+	var key string
+	err = repo.Transact(func(tx repository.Repository) error {
+		if err := tx.CreateUser(user); err != nil {
+			return fmt.Errorf("failed to create user: %v", err)
+		}
+		key, err = issueAPIKey(tx, user, "default")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s user %s (id %d)\nAPI key: %s\n", user.Role, user.Username, user.ID, key)
+	return nil
+}
+
+// createAPIKey runs the create-api-key subcommand, which issues another API
+// key to a user and prints it:
+//
+//	create-api-key <username> [name]
+func createAPIKey(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: create-api-key <username> [name]")
+	}
+	name := "default"
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	user, err := repo.GetUserByUsername(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to find user %s: %v", args[0], err)
+	}
+
+	key, err := issueAPIKey(repo, user, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("API key: %s\n", key)
+	return nil
+}
+
+// issueAPIKey generates and stores a new API key for user
+func issueAPIKey(repo repository.Repository, user *models.User, name string) (string, error) {
+	key, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate API key: %v", err)
+	}
+	if err := repo.CreateAPIKey(&models.APIKey{UserID: user.ID, Name: name, KeyHash: hash}); err != nil {
+		return "", fmt.Errorf("failed to store API key: %v", err)
+	}
+	return key, nil
+}
+
+// openRepository connects to the database and returns a repository on top
+// of it. The caller closes the database.
+func openRepository() (*repository.SQLRepository, error) {
+	if err := database.Connect(); err != nil {
+		return nil, err
+	}
+	return repository.NewSQL(database.GetDB(), models.Dialect(database.GetDriver())), nil
+}